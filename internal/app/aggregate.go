@@ -0,0 +1,416 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// aggregate.go - --aggregate: one min/avg/max/p95 record per time window in headless mode
+package app
+
+import (
+	"encoding/xml"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// aggregateStat replaces every number in an aggregated record.
+type aggregateStat struct {
+	Min float64 `json:"min" yaml:"min" xml:"Min" toon:"min"`
+	Avg float64 `json:"avg" yaml:"avg" xml:"Avg" toon:"avg"`
+	Max float64 `json:"max" yaml:"max" xml:"Max" toon:"max"`
+	P95 float64 `json:"p95" yaml:"p95" xml:"P95" toon:"p95"`
+}
+
+// aggregateStats are the aggregateStat members, in order, by their short names.
+var aggregateStats = []string{"min", "avg", "max", "p95"}
+
+// aggregateLatestFields keep the window's latest value as-is: most describe
+// the machine rather than measure it, and the process list is re-sorted
+// every sample, so its elements can't be lined up by position.
+var aggregateLatestFields = map[string]bool{
+	"schema_version":   true,
+	"system_info":      true,
+	"thunderbolt_info": true,
+	"rdma_status":      true,
+	"network_links":    true,
+	"processes":        true,
+}
+
+// aggregateFill fills dst, a value of the aggregated type, from the same
+// field of every sample in the window.
+type aggregateFill func(dst reflect.Value, srcs []reflect.Value)
+
+// headlessAggregator collects samples into windows aligned to multiples of
+// the window length, so one-minute windows start on the minute.
+type headlessAggregator struct {
+	window  time.Duration
+	fields  *fieldSelection
+	typ     reflect.Type
+	fill    aggregateFill
+	start   time.Time
+	samples []HeadlessOutput
+}
+
+// aggregateWindow is a closed window, ready to print.
+type aggregateWindow struct {
+	start   time.Time
+	doc     any
+	samples []HeadlessOutput
+}
+
+var activeAggregate *headlessAggregator
+
+// setupHeadlessAggregate parses --aggregate. It runs after
+// setupHeadlessFields, since the selected fields are what gets aggregated.
+func setupHeadlessAggregate() {
+	if aggregatePeriod == "" {
+		return
+	}
+	window, err := time.ParseDuration(aggregatePeriod)
+	if err != nil {
+		stderrLogger.Fatalf("invalid --aggregate: %v", err)
+	}
+	if window < time.Duration(updateInterval)*time.Millisecond {
+		stderrLogger.Fatalf("invalid --aggregate: %s is shorter than --interval", window)
+	}
+	activeAggregate = newHeadlessAggregator(window, activeFields)
+}
+
+func newHeadlessAggregator(window time.Duration, fields *fieldSelection) *headlessAggregator {
+	src := reflect.TypeFor[HeadlessOutput]()
+	if fields != nil {
+		src = fields.typ
+	}
+	inner, fill := compileAggregateStruct(src)
+
+	// Window details first, then the aggregated fields. A trimmed --fields
+	// type already carries the XML root name.
+	var root []reflect.StructField
+	if _, ok := src.FieldByName("XMLName"); !ok {
+		root = append(root, reflect.StructField{
+			Name: "XMLName",
+			Type: reflect.TypeFor[xml.Name](),
+			Tag:  `xml:"HeadlessOutput" json:"-" yaml:"-" toon:"-"`,
+		})
+	}
+	prefix := len(root)
+	root = append(root,
+		reflect.StructField{Name: "WindowStart", Type: reflect.TypeFor[string](),
+			Tag: `json:"window_start" yaml:"window_start" xml:"WindowStart" toon:"window_start"`},
+		reflect.StructField{Name: "WindowSeconds", Type: reflect.TypeFor[float64](),
+			Tag: `json:"window_seconds" yaml:"window_seconds" xml:"WindowSeconds" toon:"window_seconds"`},
+		reflect.StructField{Name: "Samples", Type: reflect.TypeFor[int](),
+			Tag: `json:"samples" yaml:"samples" xml:"Samples" toon:"samples"`},
+	)
+	for i := range inner.NumField() {
+		root = append(root, inner.Field(i))
+	}
+
+	a := &headlessAggregator{window: window, fields: fields, typ: reflect.StructOf(root)}
+	a.fill = func(dst reflect.Value, srcs []reflect.Value) {
+		dst.Field(prefix).SetString(a.start.Format(time.RFC3339))
+		dst.Field(prefix + 1).SetFloat(window.Seconds())
+		dst.Field(prefix + 2).SetInt(int64(len(srcs)))
+		v := reflect.New(inner).Elem()
+		fill(v, srcs)
+		for i := range inner.NumField() {
+			dst.Field(prefix + 3 + i).Set(v.Field(i))
+		}
+	}
+	return a
+}
+
+// add puts a sample taken at now into its window. When the sample is the
+// first of a new window, the previous one is closed and returned.
+func (a *headlessAggregator) add(output HeadlessOutput, now time.Time) *aggregateWindow {
+	start := now.Truncate(a.window)
+	var closed *aggregateWindow
+	if len(a.samples) > 0 && !start.Equal(a.start) {
+		closed = a.flush()
+	}
+	if len(a.samples) == 0 {
+		a.start = start
+	}
+	a.samples = append(a.samples, output)
+	return closed
+}
+
+// flush closes the current window, or returns nil if it has no samples.
+func (a *headlessAggregator) flush() *aggregateWindow {
+	if len(a.samples) == 0 {
+		return nil
+	}
+	srcs := make([]reflect.Value, len(a.samples))
+	for i, s := range a.samples {
+		if a.fields != nil {
+			srcs[i] = reflect.ValueOf(a.fields.trim(s)).Elem()
+		} else {
+			srcs[i] = reflect.ValueOf(s)
+		}
+	}
+	doc := reflect.New(a.typ)
+	a.fill(doc.Elem(), srcs)
+	w := &aggregateWindow{start: a.start, doc: doc.Interface(), samples: a.samples}
+	a.samples = nil
+	return w
+}
+
+// project returns a HeadlessOutput holding one statistic of every number in
+// the window, for the formats that print a plain snapshot.
+func (w *aggregateWindow) project(stat string) HeadlessOutput {
+	srcs := make([]reflect.Value, len(w.samples))
+	for i, s := range w.samples {
+		srcs[i] = reflect.ValueOf(s)
+	}
+	var out HeadlessOutput
+	projectAggregate(reflect.ValueOf(&out).Elem(), srcs, stat)
+	return out
+}
+
+func (w *aggregateWindow) record() headlessRecord {
+	return headlessRecord{output: w.project("avg"), doc: w.doc, window: w}
+}
+
+func isAggregateNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func aggregateFieldName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" {
+		return sf.Name
+	}
+	return name
+}
+
+func lastAggregateFill(dst reflect.Value, srcs []reflect.Value) {
+	dst.Set(srcs[len(srcs)-1])
+}
+
+// compileAggregate maps type t to its aggregated form: numbers become
+// aggregateStat, structs are aggregated field by field and lists element by
+// element, lined up by position. Everything else keeps the window's latest
+// value.
+func compileAggregate(t reflect.Type) (reflect.Type, aggregateFill) {
+	switch {
+	case t == reflect.TypeFor[xml.Name]():
+		return t, lastAggregateFill
+	case isAggregateNumber(t.Kind()):
+		return reflect.TypeFor[aggregateStat](), func(dst reflect.Value, srcs []reflect.Value) {
+			dst.Set(reflect.ValueOf(newAggregateStat(aggregateFloats(srcs))))
+		}
+	case t.Kind() == reflect.Slice:
+		inner, fill := compileAggregate(t.Elem())
+		if inner == t.Elem() {
+			return t, lastAggregateFill
+		}
+		sliceType := reflect.SliceOf(inner)
+		return sliceType, func(dst reflect.Value, srcs []reflect.Value) {
+			last := srcs[len(srcs)-1]
+			if last.IsNil() {
+				return
+			}
+			out := reflect.MakeSlice(sliceType, last.Len(), last.Len())
+			for i := range last.Len() {
+				fill(out.Index(i), aggregateIndex(srcs, i))
+			}
+			dst.Set(out)
+		}
+	case t.Kind() == reflect.Pointer && t.Elem().Kind() == reflect.Struct:
+		inner, fill := compileAggregate(t.Elem())
+		return reflect.PointerTo(inner), func(dst reflect.Value, srcs []reflect.Value) {
+			var live []reflect.Value
+			for _, s := range srcs {
+				if !s.IsNil() {
+					live = append(live, s.Elem())
+				}
+			}
+			if len(live) == 0 {
+				return
+			}
+			p := reflect.New(inner)
+			fill(p.Elem(), live)
+			dst.Set(p)
+		}
+	case t.Kind() == reflect.Struct:
+		return compileAggregateStruct(t)
+	}
+	return t, lastAggregateFill
+}
+
+// compileAggregateStruct aggregates each exported field of struct type t,
+// keeping names and tags so every encoder names them as before.
+func compileAggregateStruct(t reflect.Type) (reflect.Type, aggregateFill) {
+	var fields []reflect.StructField
+	var fills []aggregateFill
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		typ, fill := sf.Type, aggregateFill(lastAggregateFill)
+		if !aggregateLatestFields[aggregateFieldName(sf)] {
+			typ, fill = compileAggregate(sf.Type)
+		}
+		out := len(fields)
+		fields = append(fields, reflect.StructField{Name: sf.Name, Type: typ, Tag: sf.Tag})
+		fills = append(fills, func(dst reflect.Value, srcs []reflect.Value) {
+			vals := make([]reflect.Value, len(srcs))
+			for j, s := range srcs {
+				vals[j] = s.Field(i)
+			}
+			fill(dst.Field(out), vals)
+		})
+	}
+	if len(fields) == 0 {
+		return t, lastAggregateFill
+	}
+	return reflect.StructOf(fields), func(dst reflect.Value, srcs []reflect.Value) {
+		for _, fill := range fills {
+			fill(dst, srcs)
+		}
+	}
+}
+
+// projectAggregate sets dst, of the samples' own type, to one statistic of
+// each number, following the same rules as compileAggregate.
+func projectAggregate(dst reflect.Value, srcs []reflect.Value, stat string) {
+	t := dst.Type()
+	switch {
+	case isAggregateNumber(t.Kind()):
+		setAggregateNumber(dst, newAggregateStat(aggregateFloats(srcs)).get(stat))
+	case t.Kind() == reflect.Slice:
+		last := srcs[len(srcs)-1]
+		if last.IsNil() {
+			dst.Set(last)
+			return
+		}
+		out := reflect.MakeSlice(t, last.Len(), last.Len())
+		for i := range last.Len() {
+			projectAggregate(out.Index(i), aggregateIndex(srcs, i), stat)
+		}
+		dst.Set(out)
+	case t.Kind() == reflect.Struct:
+		for i := range t.NumField() {
+			sf := t.Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			vals := make([]reflect.Value, len(srcs))
+			for j, s := range srcs {
+				vals[j] = s.Field(i)
+			}
+			if aggregateLatestFields[aggregateFieldName(sf)] {
+				lastAggregateFill(dst.Field(i), vals)
+			} else {
+				projectAggregate(dst.Field(i), vals, stat)
+			}
+		}
+	default:
+		lastAggregateFill(dst, srcs)
+	}
+}
+
+// aggregateIndex picks element i from every sample long enough to have one.
+func aggregateIndex(srcs []reflect.Value, i int) []reflect.Value {
+	var vals []reflect.Value
+	for _, s := range srcs {
+		if i < s.Len() {
+			vals = append(vals, s.Index(i))
+		}
+	}
+	return vals
+}
+
+func aggregateFloats(srcs []reflect.Value) []float64 {
+	values := make([]float64, len(srcs))
+	for i, v := range srcs {
+		values[i] = templateFloat(v.Interface())
+	}
+	return values
+}
+
+func setAggregateNumber(dst reflect.Value, v float64) {
+	switch dst.Kind() {
+	case reflect.Float32, reflect.Float64:
+		dst.SetFloat(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		dst.SetInt(int64(math.Round(v)))
+	default:
+		dst.SetUint(uint64(math.Round(math.Max(v, 0))))
+	}
+}
+
+// newAggregateStat summarises values the way `mactop check` does, with p95
+// by nearest rank.
+func newAggregateStat(values []float64) aggregateStat {
+	var s aggregateStat
+	if len(values) == 0 {
+		return s
+	}
+	s.Min, _ = aggregateBudget("min", values)
+	s.Avg, _ = aggregateBudget("avg", values)
+	s.Max, _ = aggregateBudget("max", values)
+	s.P95, _ = aggregateBudget("p95", values)
+	return s
+}
+
+func (s aggregateStat) get(stat string) float64 {
+	switch stat {
+	case "min":
+		return s.Min
+	case "max":
+		return s.Max
+	case "p95":
+		return s.P95
+	}
+	return s.Avg
+}
+
+// csvHeader names one column per aggregated value: a dotted JSON path, with
+// .min, .avg, .max and .p95 for numbers. Lists and objects kept as-is are
+// one column of JSON, as with --fields.
+func (a *headlessAggregator) csvHeader() []string {
+	var header []string
+	walkAggregateColumns(reflect.New(a.typ).Elem(), "", func(name string, _ reflect.Value) {
+		header = append(header, name)
+	})
+	return header
+}
+
+func (w *aggregateWindow) csvRecord() []string {
+	var record []string
+	walkAggregateColumns(reflect.ValueOf(w.doc).Elem(), "", func(_ string, v reflect.Value) {
+		record = append(record, formatFieldCell(v.Interface()))
+	})
+	return record
+}
+
+func walkAggregateColumns(v reflect.Value, prefix string, fn func(name string, v reflect.Value)) {
+	t := v.Type()
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		name := aggregateFieldName(sf)
+		if name == "-" || sf.Type == reflect.TypeFor[xml.Name]() {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		fv := v.Field(i)
+		switch {
+		case sf.Type == reflect.TypeFor[aggregateStat]():
+			s := fv.Interface().(aggregateStat)
+			for _, stat := range aggregateStats {
+				fn(name+"."+stat, reflect.ValueOf(s.get(stat)))
+			}
+		case sf.Type.Kind() == reflect.Struct:
+			walkAggregateColumns(fv, name, fn)
+		default:
+			fn(name, fv)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package app
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func aggregateTestSamples() []HeadlessOutput {
+	var samples []HeadlessOutput
+	for i, cpu := range []float64{10, 30, 20, 40} {
+		o := HeadlessOutput{
+			Timestamp:  time.Date(2026, 3, 1, 12, 0, 10*i, 0, time.UTC).Format(time.RFC3339),
+			CPUUsage:   cpu,
+			DisplayFPS: uint32(55 + i),
+			CoreUsages: []float64{cpu, cpu * 2},
+			SystemInfo: SystemInfo{Name: "Apple M4 Max", CoreCount: 16},
+			Fans:       []HeadlessFan{{Name: "Left", RPM: 1000 * (i + 1)}},
+			Processes:  []HeadlessProcess{{PID: 100 + i, Command: "proc"}},
+		}
+		samples = append(samples, o)
+	}
+	return samples
+}
+
+func fillAggregator(t *testing.T, a *headlessAggregator) *aggregateWindow {
+	t.Helper()
+	base := time.Date(2026, 3, 1, 12, 0, 5, 0, time.UTC)
+	for i, s := range aggregateTestSamples() {
+		if w := a.add(s, base.Add(time.Duration(i)*10*time.Second)); w != nil {
+			t.Fatalf("sample %d closed a window early", i)
+		}
+	}
+	w := a.add(HeadlessOutput{CPUUsage: 99}, base.Add(time.Minute))
+	if w == nil {
+		t.Fatal("first sample of the next minute should close the window")
+	}
+	return w
+}
+
+// aggregateTestDoc is the JSON of an aggregated window, for the fields the
+// tests look at.
+type aggregateTestDoc struct {
+	WindowStart   string          `json:"window_start"`
+	WindowSeconds float64         `json:"window_seconds"`
+	Samples       int             `json:"samples"`
+	CPUUsage      aggregateStat   `json:"cpu_usage"`
+	DisplayFPS    aggregateStat   `json:"display_fps"`
+	CoreUsages    []aggregateStat `json:"core_usages"`
+	SystemInfo    SystemInfo      `json:"system_info"`
+	Fans          []struct {
+		Name string        `json:"name"`
+		RPM  aggregateStat `json:"rpm"`
+	} `json:"fans"`
+	Processes []HeadlessProcess `json:"processes"`
+}
+
+func decodeAggregateDoc(t *testing.T, w *aggregateWindow) aggregateTestDoc {
+	t.Helper()
+	data, err := json.Marshal(w.doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc aggregateTestDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestAggregateWindowDoc(t *testing.T) {
+	doc := decodeAggregateDoc(t, fillAggregator(t, newHeadlessAggregator(time.Minute, nil)))
+	if doc.WindowStart != "2026-03-01T12:00:00Z" || doc.WindowSeconds != 60 || doc.Samples != 4 {
+		t.Errorf("window = %s/%v/%d", doc.WindowStart, doc.WindowSeconds, doc.Samples)
+	}
+	if want := (aggregateStat{Min: 10, Avg: 25, Max: 40, P95: 40}); doc.CPUUsage != want {
+		t.Errorf("cpu_usage = %+v, want %+v", doc.CPUUsage, want)
+	}
+	if doc.DisplayFPS.Max != 58 {
+		t.Errorf("display_fps = %+v", doc.DisplayFPS)
+	}
+	if len(doc.CoreUsages) != 2 || doc.CoreUsages[1].Max != 80 {
+		t.Errorf("core_usages = %+v", doc.CoreUsages)
+	}
+	if len(doc.Fans) != 1 || doc.Fans[0].Name != "Left" || doc.Fans[0].RPM.Avg != 2500 {
+		t.Errorf("fans = %+v", doc.Fans)
+	}
+}
+
+func TestAggregateWindowKeepsLatest(t *testing.T) {
+	w := fillAggregator(t, newHeadlessAggregator(time.Minute, nil))
+	doc := decodeAggregateDoc(t, w)
+	if doc.SystemInfo.CoreCount != 16 || doc.SystemInfo.Name != "Apple M4 Max" {
+		t.Errorf("system_info should be kept as-is: %+v", doc.SystemInfo)
+	}
+	if len(doc.Processes) != 1 || doc.Processes[0].PID != 103 {
+		t.Errorf("processes should be the latest list: %+v", doc.Processes)
+	}
+
+	// The root element keeps its usual name in XML.
+	x, err := xml.Marshal(w.doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(x), "<HeadlessOutput><WindowStart>") {
+		t.Errorf("unexpected XML root: %.60s", x)
+	}
+}
+
+func TestAggregateWindowProject(t *testing.T) {
+	w := fillAggregator(t, newHeadlessAggregator(time.Minute, nil))
+	peak := w.project("max")
+	if peak.CPUUsage != 40 || peak.DisplayFPS != 58 || peak.Fans[0].RPM != 4000 || peak.SystemInfo.CoreCount != 16 {
+		t.Errorf("max projection = cpu %v fps %v fan %v cores %v", peak.CPUUsage, peak.DisplayFPS, peak.Fans[0].RPM, peak.SystemInfo.CoreCount)
+	}
+	if avg := w.record().output; avg.CPUUsage != 25 || avg.DisplayFPS != 57 {
+		t.Errorf("record output should be the average, got cpu %v fps %v", avg.CPUUsage, avg.DisplayFPS)
+	}
+
+	lines := string(formatInfluxAggregate(w, "mac"))
+	if !strings.Contains(lines, "cpu,host=mac,stat=p95 usage_percent=40 ") {
+		t.Errorf("missing p95 cpu line:\n%s", lines)
+	}
+	if n := strings.Count(lines, "\nprocess,"); n != 1 {
+		t.Errorf("processes should be written once, got %d lines", n)
+	}
+}
+
+func TestAggregateWithFields(t *testing.T) {
+	f, err := newFieldSelection("cpu_usage,system_info.name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newHeadlessAggregator(time.Minute, f)
+	w := fillAggregator(t, a)
+	data, _ := json.Marshal(w.doc)
+	want := `{"window_start":"2026-03-01T12:00:00Z","window_seconds":60,"samples":4,"cpu_usage":{"min":10,"avg":25,"max":40,"p95":40},"system_info":{"name":"Apple M4 Max"}}`
+	if string(data) != want {
+		t.Errorf("got  %s\nwant %s", data, want)
+	}
+
+	header := a.csvHeader()
+	record := w.csvRecord()
+	if got := strings.Join(header, ","); got != "window_start,window_seconds,samples,cpu_usage.min,cpu_usage.avg,cpu_usage.max,cpu_usage.p95,system_info.name" {
+		t.Errorf("csv header = %s", got)
+	}
+	if got := strings.Join(record, ","); got != "2026-03-01T12:00:00Z,60.00,4,10.00,25.00,40.00,40.00,Apple M4 Max" {
+		t.Errorf("csv record = %s", got)
+	}
+}
+
+func TestAggregateFlush(t *testing.T) {
+	a := newHeadlessAggregator(time.Minute, nil)
+	if a.flush() != nil {
+		t.Error("flushing an empty aggregator should return nil")
+	}
+	a.add(HeadlessOutput{CPUUsage: 5}, time.Now())
+	if w := a.flush(); w == nil || len(w.samples) != 1 {
+		t.Errorf("flush should close the partial window, got %+v", w)
+	}
+	if a.flush() != nil {
+		t.Error("a flushed window should not be printed twice")
+	}
+}
@@ -0,0 +1,615 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// alerts.go - Threshold alerts from the "alerts" config section, shared by the TUI and headless modes
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	alertCommandTimeout = 30 * time.Second
+	alertWebhookTimeout = 10 * time.Second
+)
+
+// alertExpr matches `[process "name"] field op threshold [for duration]`.
+// List elements in the field are picked with [i] or [key=value].
+var alertExpr = regexp.MustCompile(`^\s*(?:process\s+"([^"]+)"\s+)?((?:[A-Za-z0-9_.]|\[[^\]]*\])+)\s*(>=|<=|==|!=|>|<)\s*(.+?)(?:\s+for\s+(\S+))?\s*$`)
+
+// alertThreshold matches a number with an optional unit such as "%", "W" or
+// "ms/s". Units are for readability; values compare in the field's own unit.
+var alertThreshold = regexp.MustCompile(`^(-?[0-9]*\.?[0-9]+)\s*[A-Za-z%°/]*$`)
+
+// alertPathElem matches one element of a field path: a JSON name with an
+// optional [i] index or [key=value] pick.
+var alertPathElem = regexp.MustCompile(`^([A-Za-z0-9_]+)(?:\[([^\]]*)\])?$`)
+
+// alertListKeys names the field that picks an element of a list by value,
+// as in temperatures[group=CPU].max_celsius. Other lists are only indexed.
+var alertListKeys = map[string]string{
+	"temperatures": "group",
+	"fans":         "name",
+	"volumes":      "name",
+	"ethernet":     "name",
+	"buses":        "name",
+	"devices":      "name",
+}
+
+// alertProcessFields maps the per-process names a rule may use to the
+// ProcessMetrics value they read.
+var alertProcessFields = map[string]func(p ProcessMetrics) float64{
+	"cpu":            func(p ProcessMetrics) float64 { return p.CPU },
+	"cpu_percent":    func(p ProcessMetrics) float64 { return p.CPU },
+	"gpu":            func(p ProcessMetrics) float64 { return p.GPU },
+	"gpu_ms_per_sec": func(p ProcessMetrics) float64 { return p.GPU },
+	"memory":         func(p ProcessMetrics) float64 { return p.Memory },
+	"memory_percent": func(p ProcessMetrics) float64 { return p.Memory },
+	"rss_kb":         func(p ProcessMetrics) float64 { return float64(p.RSS) },
+}
+
+// alertRule is a compiled AlertConfig plus its firing state.
+type alertRule struct {
+	name       string
+	expr       string
+	field      string
+	process    string
+	op         string
+	threshold  float64
+	forDur     time.Duration
+	hysteresis float64
+	cooldown   time.Duration
+	actions    []AlertAction
+
+	pendingSince time.Time
+	firing       bool
+	lastFired    time.Time
+}
+
+// alertEngine evaluates every rule against each sample.
+type alertEngine struct {
+	mu    sync.Mutex
+	rules []*alertRule
+}
+
+// alertEvent describes a firing for webhooks and shell actions.
+type alertEvent struct {
+	Name      string  `json:"name"`
+	Rule      string  `json:"rule"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	Host      string  `json:"host"`
+	Timestamp string  `json:"timestamp"`
+}
+
+var activeAlerts *alertEngine
+
+// setupAlerts compiles the configured alert rules, if any.
+func setupAlerts() {
+	if len(currentConfig.Alerts) == 0 {
+		return
+	}
+	e, err := newAlertEngine(currentConfig.Alerts)
+	if err != nil {
+		stderrLogger.Fatalf("invalid alert rule: %v", err)
+	}
+	activeAlerts = e
+}
+
+func newAlertEngine(configs []AlertConfig) (*alertEngine, error) {
+	known := alertKnownFields()
+	e := &alertEngine{}
+	for _, c := range configs {
+		r, err := compileAlert(c, known)
+		if err != nil {
+			return nil, err
+		}
+		e.rules = append(e.rules, r)
+	}
+	return e, nil
+}
+
+func compileAlert(c AlertConfig, known map[string]bool) (*alertRule, error) {
+	m := alertExpr.FindStringSubmatch(c.When)
+	if m == nil {
+		return nil, fmt.Errorf("%q: expected `field op value [for duration]`", c.When)
+	}
+	r := &alertRule{
+		name:       c.Name,
+		expr:       strings.TrimSpace(c.When),
+		process:    m[1],
+		op:         m[3],
+		hysteresis: c.Hysteresis,
+		actions:    c.Actions,
+	}
+	if r.name == "" {
+		r.name = r.expr
+	}
+	if len(r.actions) == 0 {
+		r.actions = []AlertAction{{Type: "banner"}}
+	}
+
+	var err error
+	if r.field, err = resolveAlertField(m[2], r.process != "", known); err != nil {
+		return nil, fmt.Errorf("%q: %w", c.When, err)
+	}
+	if r.threshold, err = parseAlertThreshold(m[4], r.field); err != nil {
+		return nil, fmt.Errorf("%q: %w", c.When, err)
+	}
+	forText := c.For
+	if m[5] != "" {
+		forText = m[5]
+	}
+	if r.forDur, err = parseAlertDuration(forText); err != nil {
+		return nil, fmt.Errorf("%q: for: %w", c.When, err)
+	}
+	if r.cooldown, err = parseAlertDuration(c.Cooldown); err != nil {
+		return nil, fmt.Errorf("%q: cooldown: %w", c.When, err)
+	}
+	for _, a := range r.actions {
+		if err := validateAlertAction(a); err != nil {
+			return nil, fmt.Errorf("%q: %w", c.When, err)
+		}
+	}
+	return r, nil
+}
+
+// resolveAlertField accepts a full dotted path ("soc_metrics.cpu_temp",
+// "fans[0].rpm") or a last path element that names exactly one field
+// outside any list ("cpu_temp").
+func resolveAlertField(name string, process bool, known map[string]bool) (string, error) {
+	if process {
+		if _, ok := alertProcessFields[name]; !ok {
+			return "", fmt.Errorf("unknown process field %q (cpu, gpu, memory, rss_kb)", name)
+		}
+		return name, nil
+	}
+	path, shape, err := parseAlertPath(name)
+	if err != nil {
+		return "", err
+	}
+	if known[shape] {
+		return path, nil
+	}
+	var matches []string
+	for p := range known {
+		if path == shape && !strings.Contains(p, "[]") && strings.HasSuffix(p, "."+name) {
+			matches = append(matches, p)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("unknown field %q", name)
+	case 1:
+		return matches[0], nil
+	}
+	sort.Strings(matches)
+	return "", fmt.Errorf("field %q is ambiguous, use one of %s", name, strings.Join(matches, ", "))
+}
+
+// parseAlertPath checks the list picks in a field path and returns the path
+// as alertFieldValues writes it, and its shape with every pick replaced by
+// []. A numeric element picks by index, so fans.0.rpm is fans[0].rpm.
+func parseAlertPath(name string) (path, shape string, err error) {
+	var elems []string
+	for rest := name; rest != ""; {
+		// Dots inside a [key=value] pick don't end the element.
+		end := len(rest)
+		depth := 0
+		for i, r := range rest {
+			if r == '[' {
+				depth++
+			} else if r == ']' {
+				depth--
+			} else if r == '.' && depth == 0 {
+				end = i
+				break
+			}
+		}
+		elems = append(elems, rest[:end])
+		rest = strings.TrimPrefix(rest[end:], ".")
+	}
+	var pathB, shapeB strings.Builder
+	for i, elem := range elems {
+		if _, err := strconv.Atoi(elem); err == nil && i > 0 && !strings.HasSuffix(elems[i-1], "]") {
+			pathB.WriteString("[" + elem + "]")
+			shapeB.WriteString("[]")
+			continue
+		}
+		m := alertPathElem.FindStringSubmatch(elem)
+		if m == nil {
+			return "", "", fmt.Errorf("invalid path element %q in %q", elem, name)
+		}
+		if i > 0 {
+			pathB.WriteByte('.')
+			shapeB.WriteByte('.')
+		}
+		pathB.WriteString(elem)
+		shapeB.WriteString(m[1])
+		if strings.Contains(elem, "[") {
+			if err := checkAlertPick(m[1], m[2]); err != nil {
+				return "", "", fmt.Errorf("%q: %w", name, err)
+			}
+			shapeB.WriteString("[]")
+		}
+	}
+	return pathB.String(), shapeB.String(), nil
+}
+
+// checkAlertPick checks the [i] or [key=value] after list.
+func checkAlertPick(list, pick string) error {
+	if _, err := strconv.Atoi(pick); err == nil {
+		return nil
+	}
+	key, value, ok := strings.Cut(pick, "=")
+	want := alertListKeys[list]
+	switch {
+	case !ok:
+		return fmt.Errorf("invalid index [%s]", pick)
+	case want == "":
+		return fmt.Errorf("%s elements are picked by index, as in %s[0]", list, list)
+	case key != want || value == "":
+		return fmt.Errorf("%s elements are picked by %s, as in %s[%s=...]", list, want, list, want)
+	}
+	return nil
+}
+
+func parseAlertThreshold(s, field string) (float64, error) {
+	if field == "thermal_state" {
+		if level, err := parseThermalLevel(s); err == nil {
+			return float64(level), nil
+		}
+	}
+	m := alertThreshold.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid threshold %q", s)
+	}
+	return strconv.ParseFloat(m[1], 64)
+}
+
+func parseAlertDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err == nil && d < 0 {
+		err = errors.New("must not be negative")
+	}
+	return d, err
+}
+
+func validateAlertAction(a AlertAction) error {
+	switch a.Type {
+	case "bell", "banner":
+	case "shell":
+		if a.Command == "" {
+			return errors.New("shell action needs a command")
+		}
+	case "webhook":
+		if a.URL == "" {
+			return errors.New("webhook action needs a url")
+		}
+	default:
+		return fmt.Errorf("unknown action %q (bell, banner, shell, webhook)", a.Type)
+	}
+	return nil
+}
+
+// alertFieldValues flattens a snapshot into dotted JSON paths, plus a few
+// derived fields that are awkward to express over the raw values: memory in
+// GB, short names for the power rails and fan_rpm, the fastest fan. Alerts,
+// budgets and history all read these names. The snapshot holds the
+// whole-machine draw in total_power and the remainder after the SoC in
+// system_power. The thermal state is passed as a level since the snapshot's
+// string is localized.
+func alertFieldValues(output HeadlessOutput, thermal thermalStateLevel) map[string]float64 {
+	fields := make(map[string]float64)
+	var tree map[string]any
+	if data, err := json.Marshal(output); err == nil && json.Unmarshal(data, &tree) == nil {
+		flattenAlertFields("", tree, fields)
+	}
+	fields["thermal_state"] = float64(thermal)
+
+	const gb = 1 << 30
+	fields["memory_used_gb"] = float64(output.Memory.Used) / gb
+	fields["swap_used_gb"] = float64(output.Memory.SwapUsed) / gb
+	fields["memory_used_percent"] = 0
+	if output.Memory.Total > 0 {
+		fields["memory_used_percent"] = float64(output.Memory.Used) / float64(output.Memory.Total) * 100
+	}
+
+	m := output.SocMetrics
+	fields["system_w"] = m.TotalPower
+	fields["package_w"] = m.TotalPower - m.SystemPower
+	fields["cpu_w"] = m.CPUPower
+	fields["gpu_w"] = m.GPUPower + m.GPUSRAMPower
+	fields["ane_w"] = m.ANEPower
+	fields["dram_w"] = m.DRAMPower
+	for i, fan := range output.Fans {
+		if i == 0 || float64(fan.RPM) > fields["fan_rpm"] {
+			fields["fan_rpm"] = float64(fan.RPM)
+		}
+	}
+	return fields
+}
+
+// flattenAlertFields writes every number and flag under prefix. List
+// elements appear as list[i], and also as list[key=value] for the lists in
+// alertListKeys; when two elements share a value the first one wins.
+func flattenAlertFields(prefix string, v any, out map[string]float64) {
+	switch x := v.(type) {
+	case map[string]any:
+		for k, child := range x {
+			path := k
+			if prefix != "" {
+				path = prefix + "." + k
+			}
+			flattenAlertFields(path, child, out)
+		}
+	case []any:
+		key := alertListKeys[prefix[strings.LastIndex(prefix, ".")+1:]]
+		seen := make(map[string]bool)
+		for i, child := range x {
+			flattenAlertFields(prefix+"["+strconv.Itoa(i)+"]", child, out)
+			elem, _ := child.(map[string]any)
+			if value, ok := elem[key].(string); ok && value != "" && !seen[value] {
+				seen[value] = true
+				flattenAlertFields(prefix+"["+key+"="+value+"]", child, out)
+			}
+		}
+	case float64:
+		out[prefix] = x
+	case bool:
+		out[prefix] = 0
+		if x {
+			out[prefix] = 1
+		}
+	}
+}
+
+// alertKnownFields is every field shape a rule may name: each number and
+// flag in HeadlessOutput, whether or not a sample includes it, with [] for
+// list picks, plus the derived fields. fan_rpm is the one derived field an
+// empty sample lacks.
+func alertKnownFields() map[string]bool {
+	known := map[string]bool{"fan_rpm": true}
+	for name := range alertFieldValues(HeadlessOutput{}, thermalStateNominal) {
+		known[name] = true
+	}
+	alertTypeFields("", reflect.TypeFor[HeadlessOutput](), known)
+	return known
+}
+
+func alertTypeFields(prefix string, t reflect.Type, known map[string]bool) {
+	switch t.Kind() {
+	case reflect.Pointer:
+		alertTypeFields(prefix, t.Elem(), known)
+	case reflect.Slice:
+		alertTypeFields(prefix+"[]", t.Elem(), known)
+	case reflect.Struct:
+		for i := range t.NumField() {
+			sf := t.Field(i)
+			name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+			if !sf.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = sf.Name
+			}
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			alertTypeFields(name, sf.Type, known)
+		}
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		known[prefix] = true
+	}
+}
+
+// value reads the rule's field from a sample. Process rules sum every
+// process whose command contains the name, so a missing process reads 0.
+func (r *alertRule) value(fields map[string]float64, processes []ProcessMetrics) (float64, bool) {
+	if r.process == "" {
+		v, ok := fields[r.field]
+		return v, ok
+	}
+	get := alertProcessFields[r.field]
+	name := strings.ToLower(r.process)
+	var total float64
+	for _, p := range processes {
+		if strings.Contains(strings.ToLower(p.Command), name) {
+			total += get(p)
+		}
+	}
+	return total, true
+}
+
+func compareAlert(v float64, op string, threshold float64) bool {
+	switch op {
+	case ">":
+		return v > threshold
+	case ">=":
+		return v >= threshold
+	case "<":
+		return v < threshold
+	case "<=":
+		return v <= threshold
+	case "==":
+		return v == threshold
+	}
+	return v != threshold
+}
+
+// cleared reports whether a firing rule's value has moved back past the
+// threshold by at least the hysteresis.
+func (r *alertRule) cleared(v float64) bool {
+	threshold := r.threshold
+	switch r.op {
+	case ">", ">=":
+		threshold -= r.hysteresis
+	case "<", "<=":
+		threshold += r.hysteresis
+	}
+	return !compareAlert(v, r.op, threshold)
+}
+
+// step advances the rule by one observation and reports whether its actions
+// should run now.
+func (r *alertRule) step(v float64, now time.Time) bool {
+	if r.firing {
+		if r.cleared(v) {
+			r.firing = false
+			r.pendingSince = time.Time{}
+		}
+		return false
+	}
+	if !compareAlert(v, r.op, r.threshold) {
+		r.pendingSince = time.Time{}
+		return false
+	}
+	if r.pendingSince.IsZero() {
+		r.pendingSince = now
+	}
+	if now.Sub(r.pendingSince) < r.forDur {
+		return false
+	}
+	r.firing = true
+	if !r.lastFired.IsZero() && now.Sub(r.lastFired) < r.cooldown {
+		return false
+	}
+	r.lastFired = now
+	return true
+}
+
+// checkAlerts evaluates the configured rules against one sample.
+func checkAlerts(output HeadlessOutput, s headlessSample, inTUI bool) {
+	if activeAlerts == nil {
+		return
+	}
+	activeAlerts.evaluate(alertFieldValues(output, s.thermal), s.processes, time.Now(), inTUI)
+}
+
+func (e *alertEngine) evaluate(fields map[string]float64, processes []ProcessMetrics, now time.Time, inTUI bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, r := range e.rules {
+		v, ok := r.value(fields, processes)
+		if !ok || !r.step(v, now) {
+			continue
+		}
+		host, _ := os.Hostname()
+		fireAlert(r, alertEvent{
+			Name:      r.name,
+			Rule:      r.expr,
+			Value:     v,
+			Threshold: r.threshold,
+			Host:      host,
+			Timestamp: now.Format(time.RFC3339),
+		}, inTUI)
+	}
+}
+
+func fireAlert(r *alertRule, ev alertEvent, inTUI bool) {
+	for _, a := range r.actions {
+		switch a.Type {
+		case "bell":
+			// Headless stdout carries data, so ring on stderr there.
+			if inTUI {
+				fmt.Fprint(os.Stdout, "\a")
+			} else {
+				fmt.Fprint(os.Stderr, "\a")
+			}
+		case "banner":
+			// The TUI shows firing alerts in its title bar; see alertBanner.
+			if !inTUI {
+				stderrLogger.Printf("mactop alert: %s (value %.2f, threshold %.2f)\n", ev.Name, ev.Value, ev.Threshold)
+			}
+		case "shell":
+			go runAlertCommand(a.Command, ev)
+		case "webhook":
+			go postAlertWebhook(a.URL, ev)
+		}
+	}
+}
+
+func runAlertCommand(command string, ev alertEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), alertCommandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(),
+		"MACTOP_ALERT_NAME="+ev.Name,
+		"MACTOP_ALERT_RULE="+ev.Rule,
+		"MACTOP_ALERT_VALUE="+strconv.FormatFloat(ev.Value, 'f', -1, 64),
+		"MACTOP_ALERT_THRESHOLD="+strconv.FormatFloat(ev.Threshold, 'f', -1, 64),
+		"MACTOP_ALERT_HOST="+ev.Host,
+	)
+	// Keep the command's output off headless stdout.
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		stderrLogger.Printf("alert %q: command failed: %v\n", ev.Name, err)
+	}
+}
+
+func postAlertWebhook(url string, ev alertEvent) {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	client := &http.Client{Timeout: alertWebhookTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		stderrLogger.Printf("alert %q: webhook failed: %v\n", ev.Name, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		stderrLogger.Printf("alert %q: webhook returned %s\n", ev.Name, resp.Status)
+	}
+}
+
+// alertBanner lists the firing alerts that have a banner action, or "" if none.
+func alertBanner() string {
+	if activeAlerts == nil {
+		return ""
+	}
+	activeAlerts.mu.Lock()
+	defer activeAlerts.mu.Unlock()
+	var names []string
+	for _, r := range activeAlerts.rules {
+		if !r.firing {
+			continue
+		}
+		for _, a := range r.actions {
+			if a.Type == "banner" {
+				names = append(names, r.name)
+				break
+			}
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// updateAlertBanner swaps the version in the main block's top-right title
+// for the firing alerts. Caller must hold renderMutex.
+func updateAlertBanner() {
+	if banner := alertBanner(); banner != "" {
+		mainBlock.TitleRight = " ⚠ " + banner + " "
+		return
+	}
+	mainBlock.TitleRight = " " + version + " "
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompileAlert(t *testing.T) {
+	known := alertKnownFields()
+	tests := []struct {
+		name      string
+		cfg       AlertConfig
+		wantField string
+		wantOp    string
+		wantValue float64
+		wantFor   time.Duration
+		wantErr   bool
+	}{
+		{"Leaf name with inline for", AlertConfig{When: "cpu_temp > 95 for 30s"}, "soc_metrics.cpu_temp", ">", 95, 30 * time.Second, false},
+		{"Thermal level", AlertConfig{When: "thermal_state >= serious"}, "thermal_state", ">=", 2, 0, false},
+		{"Derived field", AlertConfig{When: "swap_used_gb > 8", For: "1m"}, "swap_used_gb", ">", 8, time.Minute, false},
+		{"Power rail", AlertConfig{When: "package_w > 40"}, "package_w", ">", 40, 0, false},
+		{"Fastest fan", AlertConfig{When: "fan_rpm > 5000"}, "fan_rpm", ">", 5000, 0, false},
+		{"Process rule with unit", AlertConfig{When: `process "ollama" gpu > 500ms/s`}, "gpu", ">", 500, 0, false},
+		{"Dotted path", AlertConfig{When: "memory.swap_used != 0"}, "memory.swap_used", "!=", 0, 0, false},
+		{"Field left out when zero", AlertConfig{When: "display_fps < 30"}, "display_fps", "<", 30, 0, false},
+		{"Fan by index", AlertConfig{When: "fans[0].rpm > 5000"}, "fans[0].rpm", ">", 5000, 0, false},
+		{"Temperature group by name", AlertConfig{When: "temperatures[group=CPU P-Core].max_celsius > 90"}, "temperatures[group=CPU P-Core].max_celsius", ">", 90, 0, false},
+		{"Dotted index", AlertConfig{When: "ecpu_usage.1 >= 90"}, "ecpu_usage[1]", ">=", 90, 0, false},
+		{"List without a pick", AlertConfig{When: "fans.rpm > 5000"}, "", "", 0, 0, true},
+		{"Pick by the wrong key", AlertConfig{When: "temperatures[name=GPU].max_celsius > 90"}, "", "", 0, 0, true},
+		{"Unknown field", AlertConfig{When: "warp_factor > 9"}, "", "", 0, 0, true},
+		{"Bad threshold", AlertConfig{When: "cpu_temp > hot"}, "", "", 0, 0, true},
+		{"Bad action", AlertConfig{When: "cpu_temp > 95", Actions: []AlertAction{{Type: "pager"}}}, "", "", 0, 0, true},
+		{"Webhook without url", AlertConfig{When: "cpu_temp > 95", Actions: []AlertAction{{Type: "webhook"}}}, "", "", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := compileAlert(tt.cfg, known)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("compileAlert(%q) error = %v, wantErr %v", tt.cfg.When, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if r.field != tt.wantField || r.op != tt.wantOp || r.threshold != tt.wantValue || r.forDur != tt.wantFor {
+				t.Errorf("compileAlert(%q) = %s %s %v for %v, want %s %s %v for %v",
+					tt.cfg.When, r.field, r.op, r.threshold, r.forDur, tt.wantField, tt.wantOp, tt.wantValue, tt.wantFor)
+			}
+		})
+	}
+}
+
+func TestAlertRuleStep(t *testing.T) {
+	r := &alertRule{op: ">", threshold: 95, forDur: 3 * time.Second, hysteresis: 5, cooldown: time.Minute}
+	start := time.Unix(0, 0)
+	steps := []struct {
+		at       time.Duration
+		value    float64
+		wantFire bool
+		wantOn   bool
+	}{
+		{0, 96, false, false},                // pending
+		{2 * time.Second, 97, false, false},  // not held long enough
+		{3 * time.Second, 97, true, true},    // held for 3s: fire
+		{4 * time.Second, 92, false, true},   // below threshold but within hysteresis
+		{5 * time.Second, 89, false, false},  // cleared
+		{6 * time.Second, 99, false, false},  // pending again
+		{10 * time.Second, 99, false, true},  // firing, but inside cooldown
+		{11 * time.Second, 80, false, false}, // cleared
+		{70 * time.Second, 99, false, false}, // pending
+		{73 * time.Second, 99, true, true},   // cooldown over: fire
+	}
+
+	for _, s := range steps {
+		fired := r.step(s.value, start.Add(s.at))
+		if fired != s.wantFire || r.firing != s.wantOn {
+			t.Errorf("at %v value %v: fired, firing = %v, %v, want %v, %v", s.at, s.value, fired, r.firing, s.wantFire, s.wantOn)
+		}
+	}
+}
+
+func TestAlertProcessValue(t *testing.T) {
+	r := &alertRule{process: "ollama", field: "gpu"}
+	processes := []ProcessMetrics{
+		{Command: "/usr/local/bin/ollama serve", GPU: 200},
+		{Command: "Ollama Helper", GPU: 350},
+		{Command: "WindowServer", GPU: 900},
+	}
+	if v, _ := r.value(nil, processes); v != 550 {
+		t.Errorf("value() = %v, want 550 summed over matching processes", v)
+	}
+	if v, _ := r.value(nil, nil); v != 0 {
+		t.Errorf("value() with no processes = %v, want 0", v)
+	}
+}
+
+func TestAlertFieldValuesLists(t *testing.T) {
+	output := HeadlessOutput{
+		DisplayFPS:   120,
+		ECPUUsage:    []float64{1200, 35},
+		Fans:         []HeadlessFan{{ID: 0, Name: "Left", RPM: 2400}},
+		Temperatures: []HeadlessTempGroup{{Group: "GPU", Max: 71}, {Group: "GPU", Max: 99}},
+	}
+	fields := alertFieldValues(output, thermalStateNominal)
+	for path, want := range map[string]float64{
+		"display_fps":                         120,
+		"ecpu_usage[1]":                       35,
+		"fans[0].rpm":                         2400,
+		"fans[name=Left].rpm":                 2400,
+		"temperatures[1].max_celsius":         99,
+		"temperatures[group=GPU].max_celsius": 71,
+		"fan_rpm":                             2400,
+	} {
+		if got, ok := fields[path]; !ok || got != want {
+			t.Errorf("%s = %v (present %v), want %v", path, got, ok, want)
+		}
+	}
+}
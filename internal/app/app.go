@@ -13,8 +13,6 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/prometheus/client_golang/prometheus"
-
 	"sync"
 
 	"github.com/mattn/go-runewidth"
@@ -63,15 +61,6 @@ func setupUI() {
 	updateHelpText()
 	stderrLogger.Printf("Model: %s\nE-Core Count: %d\nP-Core Count: %d\nS-Core Count: %d\nGPU Core Count: %d", modelName, eCoreCount, pCoreCount, sCoreCount, gpuCoreCount)
 
-	systemInfoGauge.With(prometheus.Labels{
-		"model":          modelName,
-		"core_count":     fmt.Sprintf("%d", eCoreCount+pCoreCount+sCoreCount),
-		"e_core_count":   fmt.Sprintf("%d", eCoreCount),
-		"p_core_count":   fmt.Sprintf("%d", pCoreCount),
-		"s_core_count":   fmt.Sprintf("%d", sCoreCount),
-		"gpu_core_count": fmt.Sprintf("%d", gpuCoreCount),
-	}).Set(1)
-
 	processList = w.NewList()
 	processList.Title = i18n.T("TUI_ProcessList")
 	processList.TextStyle = ui.NewStyle(ui.ColorGreen)
@@ -100,9 +89,11 @@ func setupUI() {
 	tbInfoParagraph.Title = i18n.T("TUI_ThunderboltRDMA")
 	tbInfoParagraph.Text = i18n.T("TUI_LoadingTB")
 	go func() {
-		description := GetThunderboltDescription()
+		info, err := GetFormattedThunderboltInfo()
+		description := describeThunderbolt(info, err)
 		tbInfoMutex.Lock()
 		tbDeviceInfo = description
+		tbBusInfo = info
 		tbInfoMutex.Unlock()
 	}()
 
@@ -255,6 +246,10 @@ func updateModelText() {
 }
 
 func updateIntervalText() {
+	if src, ok := activeBackend.(statusReporter); ok {
+		mainBlock.TitleBottomRight = src.statusText()
+		return
+	}
 	mainBlock.TitleBottomRight = fmt.Sprintf(" -/+ %dms ", updateInterval)
 }
 
@@ -282,13 +277,10 @@ func updateInfoUI() {
 		return
 	}
 
-	if currentConfig.DefaultLayout != LayoutInfo {
+	if currentConfig.DefaultLayout != LayoutInfo && currentConfig.DefaultLayout != LayoutCluster {
 		return
 	}
 
-	infoParagraph.Text = buildInfoText()
-	infoParagraph.BorderRounded = true
-
 	themeColor := "green"
 	if currentConfig.Theme != "" {
 		themeColor = currentConfig.Theme
@@ -298,6 +290,15 @@ func updateInfoUI() {
 	}
 	tc := GetThemeColor(themeColor)
 
+	if currentConfig.DefaultLayout == LayoutCluster {
+		infoParagraph.Title = i18n.T("TUI_Cluster")
+		infoParagraph.Text = buildClusterText(themeColor)
+	} else {
+		infoParagraph.Title = ""
+		infoParagraph.Text = buildInfoText()
+	}
+	infoParagraph.BorderRounded = true
+
 	infoParagraph.BorderStyle.Fg = tc
 	infoParagraph.TitleStyle.Fg = tc
 
@@ -475,11 +476,14 @@ func applyInitialTheme(colorName string, setColor bool) {
 // initializeTheme sets up all theming with priority: CLI flags > theme.json > saved config
 // Each property (foreground, background) is evaluated independently
 func initializeTheme(colorName string, setColor bool, interval int, setInterval bool) {
-	// Interval priority: 1) CLI --interval, 2) saved config, 3) default 1000ms
+	// Interval priority: 1) CLI --interval, 2) recording or remote server, 3) saved config, 4) default 1000ms
 	if setInterval {
 		updateInterval = interval
 		currentConfig.Interval = interval
 		updateIntervalText()
+	} else if ms := sourceIntervalMs(); ms > 0 {
+		updateInterval = ms
+		updateIntervalText()
 	} else if currentConfig.Interval > 0 {
 		updateInterval = currentConfig.Interval
 		updateIntervalText()
@@ -549,6 +553,9 @@ func runAlternateMode() bool {
 		startOverlayWorker()
 		return true
 	}
+	if subcommand != "" && runSubcommand(subcommand) {
+		return true
+	}
 	if headless {
 		runHeadless(headlessCount)
 		return true
@@ -705,7 +712,9 @@ func Run() {
 	}
 	sortReverse = currentConfig.SortReverse
 
-	flag.Parse()
+	var flagArgs []string
+	subcommand, flagArgs = splitSubcommand(os.Args[1:])
+	flag.CommandLine.Parse(flagArgs)
 
 	// Initialize i18n engine with override priorities
 	resolvedLanguage = currentConfig.Language
@@ -728,6 +737,17 @@ func Run() {
 
 	currentUser = os.Getenv("USER")
 
+	setupRecordReplay()
+	defer stopRecording()
+	setupHistory()
+	defer stopHistory()
+	setupAlerts()
+	setupOTLP()
+	setupInflux()
+	defer stopInflux()
+	setupStatsd()
+	setupPrometheusTextfile()
+
 	if runAlternateMode() {
 		return
 	}
@@ -901,7 +921,7 @@ func updateCPUUI(cpuMetrics CPUMetrics) {
 
 	updateMemoryHistory(memoryMetrics)
 	if len(cpuMetrics.CoreUsages) > 0 {
-		finalizeCPUUI(totalUsage, cpuMetrics.CoreUsages, cpuMetrics, memoryMetrics)
+		finalizeCPUUI()
 	}
 }
 
@@ -979,10 +999,7 @@ func updateMemoryHistory(memoryMetrics MemoryMetrics) {
 	}
 }
 
-func finalizeCPUUI(totalUsage float64, coreUsages []float64, cpuMetrics CPUMetrics, memoryMetrics MemoryMetrics) {
-	ecoreAvg, pcoreAvg, scoreAvg := calculateCoreAverages(coreUsages)
-	updateCPUPrometheusMetrics(totalUsage, ecoreAvg, pcoreAvg, scoreAvg, coreUsages, cpuMetrics, memoryMetrics)
-
+func finalizeCPUUI() {
 	// Update gauge colors with dynamic saturation if 1977 theme is active
 	if currentConfig.Theme == "1977" {
 		update1977GaugeColors()
@@ -1076,6 +1093,12 @@ func updatePowerChartText(cpuMetrics CPUMetrics, thermalStr string) {
 			thermalStr,
 			uptimeStr,
 		)
+		energy := sessionEnergy.snapshot()
+		if energy.Cost > 0 {
+			PowerChart.Text += "\n" + fmt.Sprintf(i18n.T("Metrics_PowerChartEnergyCost"), energy.SessionWh, energy.Cost)
+		} else {
+			PowerChart.Text += "\n" + fmt.Sprintf(i18n.T("Metrics_PowerChartEnergy"), energy.SessionWh)
+		}
 	}
 }
 
@@ -1087,82 +1110,6 @@ func updateMemoryGaugeTitle(memoryMetrics MemoryMetrics) {
 	}
 }
 
-func calculateCoreAverages(coreUsages []float64) (ecoreAvg, pcoreAvg, scoreAvg float64) {
-	if cpuCoreWidget.eCoreCount > 0 && len(coreUsages) >= cpuCoreWidget.eCoreCount {
-		for i := 0; i < cpuCoreWidget.eCoreCount; i++ {
-			ecoreAvg += coreUsages[i]
-		}
-		ecoreAvg /= float64(cpuCoreWidget.eCoreCount)
-	}
-	pStart := cpuCoreWidget.eCoreCount
-	pEnd := pStart + cpuCoreWidget.pCoreCount
-	if cpuCoreWidget.pCoreCount > 0 && len(coreUsages) >= pEnd {
-		for i := pStart; i < pEnd; i++ {
-			pcoreAvg += coreUsages[i]
-		}
-		pcoreAvg /= float64(cpuCoreWidget.pCoreCount)
-	}
-	sStart := pEnd
-	sEnd := sStart + cpuCoreWidget.sCoreCount
-	if cpuCoreWidget.sCoreCount > 0 && len(coreUsages) >= sEnd {
-		for i := sStart; i < sEnd; i++ {
-			scoreAvg += coreUsages[i]
-		}
-		scoreAvg /= float64(cpuCoreWidget.sCoreCount)
-	}
-	return ecoreAvg, pcoreAvg, scoreAvg
-}
-
-func updateCPUPrometheusMetrics(totalUsage, ecoreAvg, pcoreAvg, scoreAvg float64, coreUsages []float64, cpuMetrics CPUMetrics, memoryMetrics MemoryMetrics) {
-	thermalStateNum := 0
-	switch getThermalStateLevel() {
-	case thermalStateFair:
-		thermalStateNum = 1
-	case thermalStateSerious:
-		thermalStateNum = 2
-	case thermalStateCritical:
-		thermalStateNum = 3
-	}
-
-	cpuUsage.Set(totalUsage)
-	ecoreUsage.Set(ecoreAvg)
-	pcoreUsage.Set(pcoreAvg)
-	scoreUsage.Set(scoreAvg)
-	powerUsage.With(prometheus.Labels{"component": "cpu"}).Set(cpuMetrics.CPUW)
-	powerUsage.With(prometheus.Labels{"component": "gpu"}).Set(cpuMetrics.GPUW)
-	powerUsage.With(prometheus.Labels{"component": "ane"}).Set(cpuMetrics.ANEW)
-	powerUsage.With(prometheus.Labels{"component": "dram"}).Set(cpuMetrics.DRAMW)
-	powerUsage.With(prometheus.Labels{"component": "gpu_sram"}).Set(cpuMetrics.GPUSRAMW)
-	powerUsage.With(prometheus.Labels{"component": "system"}).Set(cpuMetrics.SystemW)
-	powerUsage.With(prometheus.Labels{"component": "total"}).Set(cpuMetrics.PackageW)
-	socTemp.Set(cpuMetrics.CPUTemp)
-	gpuTemp.Set(cpuMetrics.GPUTemp)
-	thermalState.Set(float64(thermalStateNum))
-
-	// DRAM bandwidth
-	dramBandwidth.With(prometheus.Labels{"direction": "read"}).Set(cpuMetrics.DRAMReadBW)
-	dramBandwidth.With(prometheus.Labels{"direction": "write"}).Set(cpuMetrics.DRAMWriteBW)
-	dramBandwidth.With(prometheus.Labels{"direction": "combined"}).Set(cpuMetrics.DRAMBWCombined)
-
-	memoryUsage.With(prometheus.Labels{"type": "used"}).Set(float64(memoryMetrics.Used) / 1024 / 1024 / 1024)
-	memoryUsage.With(prometheus.Labels{"type": "total"}).Set(float64(memoryMetrics.Total) / 1024 / 1024 / 1024)
-	memoryUsage.With(prometheus.Labels{"type": "swap_used"}).Set(float64(memoryMetrics.SwapUsed) / 1024 / 1024 / 1024)
-	memoryUsage.With(prometheus.Labels{"type": "swap_total"}).Set(float64(memoryMetrics.SwapTotal) / 1024 / 1024 / 1024)
-
-	// Update per-core CPU usage metrics
-	eCoreCount := cpuCoreWidget.eCoreCount
-	pEnd := eCoreCount + cpuCoreWidget.pCoreCount
-	for i, usage := range coreUsages {
-		coreType := "s"
-		if i < eCoreCount {
-			coreType = "e"
-		} else if i < pEnd {
-			coreType = "p"
-		}
-		cpuCoreUsage.With(prometheus.Labels{"core": fmt.Sprintf("%d", i), "type": coreType}).Set(usage)
-	}
-}
-
 func updateGPUUI(gpuMetrics GPUMetrics) {
 	if isCompactLayout() {
 		if gpuMetrics.Temp > 0 {
@@ -1225,13 +1172,6 @@ func updateGPUUI(gpuMetrics GPUMetrics) {
 		gpuHistoryChart.Title = fmt.Sprintf(i18n.T("Metrics_GPUHistoryChart"), avgGPU)
 	}
 
-	if gpuMetrics.ActivePercent > 0 {
-		gpuUsage.Set(gpuMetrics.ActivePercent)
-	} else {
-		gpuUsage.Set(0)
-	}
-	gpuFreqMHz.Set(float64(gpuMetrics.FreqMHz))
-
 	// Update gauge colors with dynamic saturation if 1977 theme is active
 	if currentConfig.Theme == "1977" {
 		update1977GaugeColors()
@@ -1395,15 +1335,6 @@ func updateTBNetUI(tbStats []ThunderboltNetStats) {
 			tbNetSparklineOut.MaxVal = maxValOut * 1.1
 		}
 	}
-
-	// Update Prometheus metrics for Thunderbolt network and RDMA
-	tbNetworkSpeed.With(prometheus.Labels{"direction": "download"}).Set(totalBytesIn)
-	tbNetworkSpeed.With(prometheus.Labels{"direction": "upload"}).Set(totalBytesOut)
-	if rdmaStatus.Available {
-		rdmaAvailable.Set(1)
-	} else {
-		rdmaAvailable.Set(0)
-	}
 }
 
 func parseCommandLineFlags() {
@@ -1412,7 +1343,19 @@ func parseCommandLineFlags() {
 	flag.BoolVar(&headless, "headless", false, "Run in headless mode (no TUI, output JSON to stdout)")
 	flag.BoolVar(&headlessPretty, "pretty", false, "Pretty print output in headless mode")
 	flag.IntVar(&headlessCount, "count", 0, "Number of samples to collect in headless mode (0 = infinite)")
-	flag.StringVar(&headlessFormat, "format", "json", "Output format for headless mode: json, yaml, xml, csv, toon")
+	flag.StringVar(&headlessFields, "fields", "", "Comma-separated paths to keep in headless output (e.g. soc_metrics.cpu_power,processes[0:5].command)")
+	flag.StringVar(&headlessFormat, "format", "json", "Output format for headless mode: json, yaml, xml, csv, toon, influx, template, i3bar, waybar, tmux, sketchybar, table")
+	flag.StringVar(&headlessTemplate, "template", "", "Go template rendered for each sample with --format template")
+	flag.StringVar(&aggregatePeriod, "aggregate", "", "Print one min/avg/max/p95 record per window in headless mode (e.g. 60s)")
+	flag.StringVar(&outputPath, "output", "", "Write headless output to this file instead of stdout")
+	flag.StringVar(&rotateSize, "rotate-size", "", "Start a new --output file once it reaches this size (e.g. 100MB)")
+	flag.StringVar(&rotateEvery, "rotate-every", "", "Start a new --output file every period (e.g. 1h)")
+	flag.StringVar(&rotateCompress, "rotate-compress", "", "Compress rotated --output files: gzip, zstd")
+	flag.IntVar(&rotateKeep, "rotate-keep", 0, "Number of rotated --output files to keep (0 = all)")
+	flag.BoolVar(&flatCSV, "flat", false, "With --format csv, write a column per temperature group, fan, volume, link and top process instead of JSON blobs")
+	flag.IntVar(&flatProcesses, "flat-processes", 5, "Number of top processes given columns by --flat")
+	flag.IntVar(&tableHeaderEvery, "header-every", 0, "Repeat the --format table header every N lines (0 = terminal height)")
+	flag.StringVar(&templateFile, "template-file", "", "Read the --format template template from a file")
 	flag.IntVar(&updateInterval, "interval", 1000, "Update interval in milliseconds")
 	flag.IntVar(&updateInterval, "i", 1000, "Update interval in milliseconds")
 	flag.Bool("d", false, "Dump all available IOReport channels and exit")
@@ -1434,6 +1377,25 @@ func parseCommandLineFlags() {
 	flag.BoolVar(&fanControl, "fan-control", false, "Enable interactive fan speed control (⚠️  writes to SMC)")
 	flag.BoolVar(&dumpTemps, "dump-temps", false, "Diagnostic: dump all raw SMC temperature keys and exit")
 	flag.BoolVar(&dumpDebug, "dump-debug", false, "Diagnostic: dump IOReport/HID/SMC/NVMe debug info and exit")
+	flag.StringVar(&recordPath, "record", "", "Append every collected sample to a recording file")
+	flag.StringVar(&replayPath, "replay", "", "Replay a recording made with --record instead of reading this machine")
+	flag.StringVar(&connectAddr, "connect", "", "Draw the TUI from a remote `mactop serve` instance at host:port")
+	flag.StringVar(&serveListen, "listen", ":7070", "Address for `mactop serve` to listen on")
+	flag.Float64Var(&pricePerKWh, "price-per-kwh", 0, "Electricity price per kWh, to estimate the session's energy cost")
+	flag.StringVar(&promTextfilePath, "prometheus-textfile", "", "Rewrite this .prom file every interval for node_exporter's textfile collector")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "", "Push metrics to an OTLP/HTTP collector (e.g. http://localhost:4318)")
+	flag.StringVar(&influxURL, "influx-url", "", "Batch-POST line protocol to an InfluxDB /api/v2/write endpoint (token from INFLUX_TOKEN)")
+	flag.StringVar(&statsdAddr, "statsd", "", "Send gauges to a StatsD agent over UDP (host:port)")
+	flag.StringVar(&statsdPrefix, "statsd-prefix", "mactop", "Metric name prefix for --statsd")
+	flag.StringVar(&statsdTags, "statsd-tags", "", "Comma-separated DogStatsD tags (e.g. env:prod,team:ml); enables DogStatsD output")
+	flag.StringVar(&budgetPath, "budget", "", "YAML file of power and thermal limits for `mactop check`")
+	flag.BoolVar(&historyEnabled, "history", false, "Keep samples under ~/.mactop/history for `mactop query`")
+	flag.StringVar(&queryMetric, "metric", "", "Comma-separated fields for `mactop query` (e.g. package_w,gpu_temp)")
+	flag.StringVar(&querySince, "since", "24h", "How far back `mactop query` reads (e.g. 90m, 7d)")
+	flag.StringVar(&queryStep, "step", "1m", "Length of each `mactop query` point (e.g. 10s, 1h)")
+	flag.StringVar(&queryAgg, "agg", "avg", "Aggregate for each `mactop query` point: avg, min, max")
+	flag.StringVar(&clusterHosts, "hosts", "", "Comma-separated host:port list of `mactop serve` nodes for `mactop cluster`")
+	flag.StringVar(&demoName, "demo", "", "Show synthetic metrics from a built-in scenario (idle, xcode-build, llm-inference, thermal-throttle, fan-failure) or a YAML timeline")
 	flag.BoolVar(&dumpFPS, "dump-fps", false, "Diagnostic: dump display info and test CGDisplayStream FPS at multiple sizes")
 }
 
@@ -1505,6 +1467,9 @@ func shutdownAndExit(closeDone bool) {
 			}()
 		}
 		shutdownWorkers()
+		stopRecording()
+		stopHistory()
+		stopInflux()
 		ui.Close()
 		os.Exit(0)
 	})
@@ -0,0 +1,124 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// backend.go - Pluggable source of raw host metrics
+package app
+
+import "time"
+
+// Backend supplies the raw samples the collectors turn into CPU, GPU, power,
+// memory, network, disk and process metrics. Each supported OS provides one
+// through newPlatformBackend; everything above this layer is platform-neutral.
+type Backend interface {
+	// Name identifies the backend in logs and diagnostics.
+	Name() string
+	// Init prepares any long-lived handles needed by SampleSoc.
+	Init() error
+	// Close releases whatever Init acquired.
+	Close()
+	// SystemInfo describes the host: model name and core counts.
+	SystemInfo() SystemInfo
+	// SampleSoc blocks for roughly durationMs and returns power, frequency,
+	// residency, temperature and fan readings averaged over that window.
+	SampleSoc(durationMs int) SocMetrics
+	// ThermalState reports the OS-level thermal pressure.
+	ThermalState() thermalStateLevel
+	// CPUUsage returns cumulative per-core tick counters.
+	CPUUsage() ([]CPUUsage, error)
+	// Memory returns physical memory and swap usage.
+	Memory() (NativeMemoryMetrics, error)
+	// Network returns cumulative counters per non-loopback interface.
+	Network() (map[string]NativeNetMetric, error)
+	// Disk returns cumulative I/O counters per physical disk.
+	Disk() (map[string]NativeDiskMetric, error)
+	// Processes returns the process table sorted by CPU usage.
+	Processes(systemGpuPercent float64) ([]ProcessMetrics, error)
+}
+
+var activeBackend Backend = newPlatformBackend()
+
+func initSocMetrics() error {
+	return activeBackend.Init()
+}
+
+func cleanupSocMetrics() {
+	activeBackend.Close()
+}
+
+func sampleSocMetrics(durationMs int) SocMetrics {
+	return activeBackend.SampleSoc(durationMs)
+}
+
+func getThermalStateLevel() thermalStateLevel {
+	return activeBackend.ThermalState()
+}
+
+func GetCPUUsage() ([]CPUUsage, error) {
+	return activeBackend.CPUUsage()
+}
+
+func GetNativeMemoryMetrics() (NativeMemoryMetrics, error) {
+	return activeBackend.Memory()
+}
+
+// GetNativeNetworkMetrics returns network statistics for all interfaces
+func GetNativeNetworkMetrics() (map[string]NativeNetMetric, error) {
+	return activeBackend.Network()
+}
+
+// GetNativeDiskMetrics returns disk I/O statistics
+func GetNativeDiskMetrics() (map[string]NativeDiskMetric, error) {
+	return activeBackend.Disk()
+}
+
+func getProcessList(systemGpuPercent float64) ([]ProcessMetrics, error) {
+	processes, err := activeBackend.Processes(systemGpuPercent)
+	if err == nil {
+		processMeter.attribute(processes, sessionEnergy.latestSample(), time.Now())
+	}
+	return processes, err
+}
+
+// snapshotSource is implemented by backends that play back already-derived
+// samples (recordings) instead of reading counters from this host. The
+// values they carry would otherwise be recomputed from local state.
+type snapshotSource interface {
+	// NetDisk returns network and disk rates as recorded.
+	NetDisk() NetDiskMetrics
+	// Volumes returns the mounted volumes as recorded.
+	Volumes() []VolumeInfo
+	// CoreLabels returns the core layout in BuildCoreLabels form.
+	CoreLabels() ([]string, int, int, int, []int)
+}
+
+// statusReporter is implemented by backends that show their state (playback
+// position, connection) in place of the update interval.
+type statusReporter interface {
+	statusText() string
+}
+
+// readsThisHost reports whether the active backend samples this machine, as
+// opposed to a recording, a demo or a remote server. PIDs and fans shown
+// otherwise are not ours to act on.
+func readsThisHost() bool {
+	_, ok := activeBackend.(snapshotSource)
+	return !ok
+}
+
+// sourceIntervalMs returns the sampling interval fixed by a recording or a
+// remote server, or 0 when the local interval applies.
+func sourceIntervalMs() int {
+	switch b := activeBackend.(type) {
+	case *replayBackend:
+		return int(b.rec.interval(0) / time.Millisecond)
+	case *remoteBackend:
+		return b.intervalMs()
+	}
+	return 0
+}
+
+// getCoreLabels returns the core labels for the active backend.
+func getCoreLabels() ([]string, int, int, int, []int) {
+	if src, ok := activeBackend.(snapshotSource); ok {
+		return src.CoreLabels()
+	}
+	return BuildCoreLabels()
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// backend_darwin.go - IOReport/SMC/Mach backend for Apple Silicon
+package app
+
+// darwinBackend reads IOReport, SMC, Mach host statistics and sysctl.
+type darwinBackend struct{}
+
+func newPlatformBackend() Backend {
+	return darwinBackend{}
+}
+
+func (darwinBackend) Name() string { return "darwin" }
+
+func (darwinBackend) Init() error { return initIOReportMetrics() }
+
+func (darwinBackend) Close() { cleanupIOReportMetrics() }
+
+func (darwinBackend) SystemInfo() SystemInfo { return computeSysctlSOCInfo() }
+
+func (darwinBackend) SampleSoc(durationMs int) SocMetrics {
+	return sampleIOReportMetrics(durationMs)
+}
+
+func (darwinBackend) ThermalState() thermalStateLevel { return getXCPMThermalLevel() }
+
+func (darwinBackend) CPUUsage() ([]CPUUsage, error) { return getHostCPUUsage() }
+
+func (darwinBackend) Memory() (NativeMemoryMetrics, error) { return getMachMemoryMetrics() }
+
+func (darwinBackend) Network() (map[string]NativeNetMetric, error) {
+	return getIfaddrsNetworkMetrics()
+}
+
+func (darwinBackend) Disk() (map[string]NativeDiskMetric, error) { return getIOKitDiskMetrics() }
+
+func (darwinBackend) Processes(systemGpuPercent float64) ([]ProcessMetrics, error) {
+	return getSysctlProcessList(systemGpuPercent)
+}
@@ -0,0 +1,482 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// backend_linux.go - procfs/sysfs backend for Linux hosts
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	procRoot = "/proc"
+	sysRoot  = "/sys"
+
+	// USER_HZ is fixed at 100 on every architecture Linux exposes through procfs.
+	linuxClockTicks = 100
+	diskSectorBytes = 512
+)
+
+// linuxBackend reads /proc for CPU, memory, network, disk and process data,
+// /sys/class/powercap for RAPL energy and /sys/class/hwmon for sensors.
+type linuxBackend struct {
+	once     sync.Once
+	topology linuxCoreTopology
+	rapl     []raplZone
+
+	mu           sync.Mutex
+	thermalLevel thermalStateLevel
+}
+
+func newPlatformBackend() Backend {
+	return &linuxBackend{thermalLevel: thermalStateNominal}
+}
+
+func (b *linuxBackend) discover() {
+	b.once.Do(func() {
+		b.topology = readLinuxCoreTopology()
+		b.rapl = discoverRAPLZones(filepath.Join(sysRoot, "class", "powercap"))
+	})
+}
+
+func (b *linuxBackend) Name() string { return "linux" }
+
+func (b *linuxBackend) Init() error {
+	b.discover()
+	if _, err := os.Stat(filepath.Join(procRoot, "stat")); err != nil {
+		return fmt.Errorf("procfs unavailable: %w", err)
+	}
+	return nil
+}
+
+func (b *linuxBackend) Close() {}
+
+func (b *linuxBackend) SystemInfo() SystemInfo {
+	b.discover()
+	info := readLinuxCPUInfo()
+	info.ECoreCount = len(b.topology.eCPUs)
+	info.PCoreCount = len(b.topology.pCPUs)
+	// Per-core usage and the E/P split are per logical CPU, so CoreCount
+	// is too; on SMT machines that is twice the physical cores.
+	if n := info.ECoreCount + info.PCoreCount; n > 0 {
+		info.CoreCount = n
+	}
+	return info
+}
+
+func (b *linuxBackend) ThermalState() thermalStateLevel {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.thermalLevel
+}
+
+// SampleSoc measures RAPL energy and per-core busy time across a window of
+// durationMs, then attaches cpufreq, DRM GPU and hwmon readings.
+func (b *linuxBackend) SampleSoc(durationMs int) SocMetrics {
+	b.discover()
+
+	energyBefore := readRAPLEnergy(b.rapl)
+	ticksBefore, _ := b.CPUUsage()
+	start := time.Now()
+	time.Sleep(time.Duration(durationMs) * time.Millisecond)
+	elapsed := time.Since(start).Seconds()
+	energyAfter := readRAPLEnergy(b.rapl)
+	ticksAfter, _ := b.CPUUsage()
+
+	var m SocMetrics
+	applyRAPLPower(&m, b.rapl, energyBefore, energyAfter, elapsed)
+
+	busy := cpuBusyPercents(ticksBefore, ticksAfter)
+	m.EClusterActive = averageAt(busy, b.topology.eCPUs)
+	m.PClusterActive = averageAt(busy, b.topology.pCPUs)
+	m.EClusterFreqMHz = averageCPUFreqMHz(b.topology.eCPUs)
+	m.PClusterFreqMHz = averageCPUFreqMHz(b.topology.pCPUs)
+
+	m.GPUActive, m.GPUFreqMHz = readDRMGPU(filepath.Join(sysRoot, "class", "drm"))
+
+	hw := readHwmon(filepath.Join(sysRoot, "class", "hwmon"))
+	m.CPUTemp = hw.cpuTemp
+	m.SocTemp = hw.cpuTemp
+	m.GPUTemp = hw.gpuTemp
+	m.Fans = hw.fans
+	m.TempSensors = hw.temps
+
+	b.mu.Lock()
+	b.thermalLevel = hw.thermalLevel()
+	b.mu.Unlock()
+
+	return m
+}
+
+func (b *linuxBackend) CPUUsage() ([]CPUUsage, error) {
+	data, err := os.ReadFile(filepath.Join(procRoot, "stat"))
+	if err != nil {
+		return nil, fmt.Errorf("read /proc/stat: %w", err)
+	}
+	return parseProcStat(string(data)), nil
+}
+
+func (b *linuxBackend) Memory() (NativeMemoryMetrics, error) {
+	data, err := os.ReadFile(filepath.Join(procRoot, "meminfo"))
+	if err != nil {
+		return NativeMemoryMetrics{}, fmt.Errorf("read /proc/meminfo: %w", err)
+	}
+	return parseMeminfo(string(data)), nil
+}
+
+func (b *linuxBackend) Network() (map[string]NativeNetMetric, error) {
+	data, err := os.ReadFile(filepath.Join(procRoot, "net", "dev"))
+	if err != nil {
+		return nil, fmt.Errorf("read /proc/net/dev: %w", err)
+	}
+	return parseNetDev(string(data)), nil
+}
+
+func (b *linuxBackend) Disk() (map[string]NativeDiskMetric, error) {
+	data, err := os.ReadFile(filepath.Join(procRoot, "diskstats"))
+	if err != nil {
+		return nil, fmt.Errorf("read /proc/diskstats: %w", err)
+	}
+	all := parseDiskstats(string(data))
+	result := make(map[string]NativeDiskMetric, len(all))
+	for name, d := range all {
+		if isPhysicalBlockDevice(name) {
+			result[name] = d
+		}
+	}
+	return result, nil
+}
+
+// isPhysicalBlockDevice keeps whole disks and drops partitions, loop, ram,
+// zram and device-mapper nodes so traffic is not counted twice.
+func isPhysicalBlockDevice(name string) bool {
+	for _, prefix := range []string{"loop", "ram", "zram", "dm-"} {
+		if strings.HasPrefix(name, prefix) {
+			return false
+		}
+	}
+	_, err := os.Stat(filepath.Join(sysRoot, "block", name))
+	return err == nil
+}
+
+func (b *linuxBackend) Processes(systemGpuPercent float64) ([]ProcessMetrics, error) {
+	var pids []int
+	if filterPID > 0 {
+		pids = []int{filterPID}
+	} else {
+		entries, err := os.ReadDir(procRoot)
+		if err != nil {
+			return nil, fmt.Errorf("read /proc: %w", err)
+		}
+		for _, e := range entries {
+			if pid, err := strconv.Atoi(e.Name()); err == nil && pid > 0 {
+				pids = append(pids, pid)
+			}
+		}
+	}
+
+	totalMem := uint64(0)
+	if mem, err := b.Memory(); err == nil {
+		totalMem = mem.Total
+	}
+	pageSize := int64(os.Getpagesize())
+
+	var processes []ProcessMetrics
+	now := time.Now()
+
+	prevProcessTimesMutex.Lock()
+	defer prevProcessTimesMutex.Unlock()
+
+	nextProcessTimes := make(map[int]ProcessTimeState)
+	for _, pid := range pids {
+		pm, ns, ok := readLinuxProcess(pid, now, totalMem, pageSize)
+		if ok {
+			processes = append(processes, pm)
+			nextProcessTimes[pid] = ns
+		}
+	}
+
+	prevProcessTimes = nextProcessTimes
+
+	return finalizeProcessList(processes, now, systemGpuPercent), nil
+}
+
+func readLinuxProcess(pid int, now time.Time, totalMem uint64, pageSize int64) (ProcessMetrics, ProcessTimeState, bool) {
+	dir := filepath.Join(procRoot, strconv.Itoa(pid))
+	data, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return ProcessMetrics{}, ProcessTimeState{}, false
+	}
+	st, err := parsePIDStat(string(data))
+	if err != nil {
+		return ProcessMetrics{}, ProcessTimeState{}, false
+	}
+
+	createSec := int64(st.startTicks / linuxClockTicks)
+	comm := st.comm
+	if prevState, ok := prevProcessTimes[pid]; ok && prevState.CreateSec == createSec && prevState.Command != "" {
+		comm = prevState.Command
+	} else if cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil {
+		comm = commandFromCmdline(cmdline, st.comm)
+	}
+
+	totalTimeNs := (st.utime + st.stime) * uint64(time.Second) / linuxClockTicks
+	cpuPercent := 0.0
+	if prevState, ok := prevProcessTimes[pid]; ok && prevState.CreateSec == createSec {
+		timeDelta := totalTimeNs - prevState.Time
+		wallDelta := now.Sub(prevState.Timestamp).Nanoseconds()
+		if wallDelta > 0 && totalTimeNs > prevState.Time {
+			cpuPercent = (float64(timeDelta) / float64(wallDelta)) * 100.0
+		}
+	}
+
+	rssBytes := st.rssPages * pageSize
+	memPercent := 0.0
+	if totalMem > 0 {
+		memPercent = (float64(rssBytes) / float64(totalMem)) * 100.0
+	}
+
+	user := ""
+	if fi, err := os.Stat(dir); err == nil {
+		if sys, ok := fi.Sys().(*syscall.Stat_t); ok {
+			user = getUsername(sys.Uid)
+		}
+	}
+
+	pm := ProcessMetrics{
+		PID:         pid,
+		PPID:        st.ppid,
+		User:        user,
+		CPU:         cpuPercent,
+		Memory:      memPercent,
+		VSZ:         int64(st.vsize / 1024),
+		RSS:         rssBytes / 1024,
+		Command:     comm,
+		State:       st.state,
+		Time:        formatTime(float64(totalTimeNs) / 1e9),
+		LastUpdated: now,
+	}
+	ns := ProcessTimeState{
+		Time:      totalTimeNs,
+		Timestamp: now,
+		Command:   comm,
+		CreateSec: createSec,
+	}
+	return pm, ns, true
+}
+
+// commandFromCmdline prefers the argv[0] basename when it extends the
+// 15-byte kernel comm, matching the full-name lookup done on macOS.
+func commandFromCmdline(cmdline []byte, comm string) string {
+	argv0 := string(cmdline)
+	if i := strings.IndexByte(argv0, 0); i >= 0 {
+		argv0 = argv0[:i]
+	}
+	base := filepath.Base(argv0)
+	if argv0 != "" && strings.HasPrefix(base, comm) {
+		return base
+	}
+	return comm
+}
+
+// parseProcStat returns per-CPU tick counters indexed by CPU id. Offline
+// CPUs leave zero entries so indices keep matching the kernel numbering.
+func parseProcStat(data string) []CPUUsage {
+	var usage []CPUUsage
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || !strings.HasPrefix(fields[0], "cpu") || fields[0] == "cpu" {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(fields[0], "cpu"))
+		if err != nil || id < 0 {
+			continue
+		}
+		ticks := make([]float64, 8)
+		for i := range ticks {
+			if i+1 < len(fields) {
+				ticks[i], _ = strconv.ParseFloat(fields[i+1], 64)
+			}
+		}
+		for len(usage) <= id {
+			usage = append(usage, CPUUsage{})
+		}
+		// user nice system idle iowait irq softirq steal
+		usage[id] = CPUUsage{
+			User:   ticks[0],
+			Nice:   ticks[1],
+			System: ticks[2] + ticks[5] + ticks[6] + ticks[7],
+			Idle:   ticks[3] + ticks[4],
+		}
+	}
+	return usage
+}
+
+func parseMeminfo(data string) NativeMemoryMetrics {
+	kb := make(map[string]uint64)
+	for _, line := range strings.Split(data, "\n") {
+		key, rest, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[0], 10, 64); err == nil {
+			kb[key] = v * 1024
+		}
+	}
+
+	available, ok := kb["MemAvailable"]
+	if !ok {
+		available = kb["MemFree"] + kb["Buffers"] + kb["Cached"]
+	}
+	total := kb["MemTotal"]
+	if available > total {
+		available = total
+	}
+	swapUsed := uint64(0)
+	if kb["SwapTotal"] > kb["SwapFree"] {
+		swapUsed = kb["SwapTotal"] - kb["SwapFree"]
+	}
+	return NativeMemoryMetrics{
+		Total:     total,
+		Used:      total - available,
+		Available: available,
+		SwapTotal: kb["SwapTotal"],
+		SwapUsed:  swapUsed,
+	}
+}
+
+func parseNetDev(data string) map[string]NativeNetMetric {
+	metrics := make(map[string]NativeNetMetric)
+	for _, line := range strings.Split(data, "\n") {
+		name, rest, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		fields := strings.Fields(rest)
+		if name == "lo" || len(fields) < 10 {
+			continue
+		}
+		v := make([]uint64, 10)
+		for i := range v {
+			v[i], _ = strconv.ParseUint(fields[i], 10, 64)
+		}
+		metrics[name] = NativeNetMetric{
+			Name:        name,
+			BytesRecv:   v[0],
+			PacketsRecv: v[1],
+			BytesSent:   v[8],
+			PacketsSent: v[9],
+		}
+	}
+	return metrics
+}
+
+func parseDiskstats(data string) map[string]NativeDiskMetric {
+	result := make(map[string]NativeDiskMetric)
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 11 {
+			continue
+		}
+		v := make([]uint64, 11)
+		for i := 3; i < 11; i++ {
+			v[i], _ = strconv.ParseUint(fields[i], 10, 64)
+		}
+		name := fields[2]
+		result[name] = NativeDiskMetric{
+			Name:       name,
+			ReadOps:    v[3],
+			ReadBytes:  v[5] * diskSectorBytes,
+			ReadTime:   v[6] * uint64(time.Millisecond),
+			WriteOps:   v[7],
+			WriteBytes: v[9] * diskSectorBytes,
+			WriteTime:  v[10] * uint64(time.Millisecond),
+		}
+	}
+	return result
+}
+
+type pidStat struct {
+	comm       string
+	state      string
+	ppid       int
+	utime      uint64
+	stime      uint64
+	startTicks uint64
+	vsize      uint64
+	rssPages   int64
+}
+
+// parsePIDStat decodes /proc/[pid]/stat. The comm field may itself contain
+// spaces and parentheses, so fields are split after the last ')'.
+func parsePIDStat(data string) (pidStat, error) {
+	open := strings.IndexByte(data, '(')
+	end := strings.LastIndexByte(data, ')')
+	if open < 0 || end < open {
+		return pidStat{}, fmt.Errorf("malformed stat line")
+	}
+	fields := strings.Fields(data[end+1:])
+	// fields[0] is field 3 (state); stat field N lives at fields[N-3].
+	if len(fields) < 22 {
+		return pidStat{}, fmt.Errorf("short stat line: %d fields", len(fields)+2)
+	}
+	st := pidStat{comm: data[open+1 : end], state: fields[0]}
+	st.ppid, _ = strconv.Atoi(fields[1])
+	st.utime, _ = strconv.ParseUint(fields[11], 10, 64)
+	st.stime, _ = strconv.ParseUint(fields[12], 10, 64)
+	st.startTicks, _ = strconv.ParseUint(fields[19], 10, 64)
+	st.vsize, _ = strconv.ParseUint(fields[20], 10, 64)
+	st.rssPages, _ = strconv.ParseInt(fields[21], 10, 64)
+	return st, nil
+}
+
+// readLinuxCPUInfo reads the model name and logical CPU count from
+// /proc/cpuinfo, falling back to the device-tree model on ARM boards.
+func readLinuxCPUInfo() SystemInfo {
+	data, err := os.ReadFile(filepath.Join(procRoot, "cpuinfo"))
+	if err != nil {
+		return SystemInfo{Name: "Linux"}
+	}
+	info := parseCPUInfo(string(data))
+	if info.Name == "" {
+		if model, err := os.ReadFile(filepath.Join(procRoot, "device-tree", "model")); err == nil {
+			info.Name = strings.TrimRight(string(model), "\x00\n")
+		}
+	}
+	if info.Name == "" {
+		info.Name = "Linux"
+	}
+	return info
+}
+
+// parseCPUInfo counts one CPU per processor entry, so SMT siblings count
+// separately.
+func parseCPUInfo(data string) SystemInfo {
+	var info SystemInfo
+	for line := range strings.SplitSeq(data, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch key {
+		case "model name", "Model", "Hardware":
+			if info.Name == "" {
+				info.Name = value
+			}
+		case "processor":
+			info.CoreCount++
+		}
+	}
+	return info
+}
@@ -0,0 +1,204 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseProcStat(t *testing.T) {
+	data := `cpu  400 20 300 1000 50 5 5 0 0 0
+cpu0 100 10 100 500 20 2 3 0 0 0
+cpu2 300 10 200 500 30 3 2 1 0 0
+intr 12345
+`
+	got := parseProcStat(data)
+	want := []CPUUsage{
+		{User: 100, Nice: 10, System: 105, Idle: 520},
+		{},
+		{User: 300, Nice: 10, System: 206, Idle: 530},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseProcStat() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseCPUInfo(t *testing.T) {
+	// Two cores with two threads each.
+	data := `processor	: 0
+model name	: Test CPU
+physical id	: 0
+core id		: 0
+
+processor	: 1
+model name	: Test CPU
+physical id	: 0
+core id		: 1
+
+processor	: 2
+model name	: Test CPU
+physical id	: 0
+core id		: 0
+
+processor	: 3
+model name	: Test CPU
+physical id	: 0
+core id		: 1
+`
+	if got := parseCPUInfo(data); got.Name != "Test CPU" || got.CoreCount != 4 {
+		t.Errorf("parseCPUInfo() = %+v, want Test CPU with 4 logical CPUs", got)
+	}
+}
+
+func TestParseMeminfo(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want NativeMemoryMetrics
+	}{
+		{
+			name: "MemAvailable present",
+			data: "MemTotal:       1000 kB\nMemFree:         100 kB\nMemAvailable:    400 kB\nSwapTotal:       200 kB\nSwapFree:        150 kB\n",
+			want: NativeMemoryMetrics{Total: 1024000, Used: 614400, Available: 409600, SwapTotal: 204800, SwapUsed: 51200},
+		},
+		{
+			name: "Older kernel without MemAvailable",
+			data: "MemTotal: 1000 kB\nMemFree: 100 kB\nBuffers: 50 kB\nCached: 250 kB\n",
+			want: NativeMemoryMetrics{Total: 1024000, Used: 614400, Available: 409600},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseMeminfo(tt.data); got != tt.want {
+				t.Errorf("parseMeminfo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNetDev(t *testing.T) {
+	data := `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo: 5000      50    0    0    0     0          0         0     5000      50    0    0    0     0       0          0
+  eth0: 123456   789    0    0    0     0          0         0    654321    987    0    0    0     0       0          0
+`
+	got := parseNetDev(data)
+	if _, ok := got["lo"]; ok {
+		t.Errorf("expected lo to be excluded")
+	}
+	want := NativeNetMetric{Name: "eth0", BytesRecv: 123456, PacketsRecv: 789, BytesSent: 654321, PacketsSent: 987}
+	if got["eth0"] != want {
+		t.Errorf("parseNetDev()[eth0] = %+v, want %+v", got["eth0"], want)
+	}
+}
+
+func TestParseDiskstats(t *testing.T) {
+	data := " 259       0 nvme0n1 1000 10 8000 400 2000 20 16000 800 0 900 1200\n"
+	got := parseDiskstats(data)["nvme0n1"]
+	want := NativeDiskMetric{
+		Name:       "nvme0n1",
+		ReadOps:    1000,
+		ReadBytes:  8000 * 512,
+		ReadTime:   400_000_000,
+		WriteOps:   2000,
+		WriteBytes: 16000 * 512,
+		WriteTime:  800_000_000,
+	}
+	if got != want {
+		t.Errorf("parseDiskstats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParsePIDStat(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    pidStat
+		wantErr bool
+	}{
+		{
+			name: "Plain comm",
+			data: "42 (bash) S 1 42 42 0 -1 4194560 100 0 0 0 250 50 0 0 20 0 1 0 9000 10485760 300 18446744073709551615",
+			want: pidStat{comm: "bash", state: "S", ppid: 1, utime: 250, stime: 50, startTicks: 9000, vsize: 10485760, rssPages: 300},
+		},
+		{
+			name: "Comm with spaces and parens",
+			data: "7 (Web (Content) 1) R 1 7 7 0 -1 0 0 0 0 0 10 20 0 0 20 0 4 0 500 2048 16 0",
+			want: pidStat{comm: "Web (Content) 1", state: "R", ppid: 1, utime: 10, stime: 20, startTicks: 500, vsize: 2048, rssPages: 16},
+		},
+		{name: "Truncated", data: "1 (init) S 0 1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePIDStat(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePIDStat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parsePIDStat() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCPUList(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []int
+	}{
+		{"0-3", []int{0, 1, 2, 3}},
+		{"0-1,4,6-7\n", []int{0, 1, 4, 6, 7}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := parseCPUList(tt.input); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCPUList(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyRAPLPower(t *testing.T) {
+	zones := []raplZone{
+		{name: "package-0", maxRange: 100_000_000},
+		{name: "core"},
+		{name: "uncore"},
+		{name: "dram"},
+	}
+	// package counter wraps: 90J -> 10J with a 100J range is 20J over 1s.
+	before := []int64{90_000_000, 0, 0, 0}
+	after := []int64{10_000_000, 12_000_000, 3_000_000, 1_000_000}
+
+	var m SocMetrics
+	applyRAPLPower(&m, zones, before, after, 1)
+	if m.CPUPower != 12 || m.GPUPower != 3 || m.DRAMPower != 1 {
+		t.Errorf("component power = cpu %v gpu %v dram %v, want 12 3 1", m.CPUPower, m.GPUPower, m.DRAMPower)
+	}
+	if m.TotalPower != 21 {
+		t.Errorf("TotalPower = %v, want 21", m.TotalPower)
+	}
+}
+
+func TestCommandFromCmdline(t *testing.T) {
+	tests := []struct {
+		name    string
+		cmdline string
+		comm    string
+		want    string
+	}{
+		{"Full name beyond comm limit", "/usr/lib/firefox/firefox-developer-edition\x00--new\x00", "firefox-develop", "firefox-developer-edition"},
+		{"Rewritten argv", "sshd: alice@pts/0\x00", "sshd", "sshd"},
+		{"Kernel thread", "", "kworker/0:1", "kworker/0:1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commandFromCmdline([]byte(tt.cmdline), tt.comm); got != tt.want {
+				t.Errorf("commandFromCmdline() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
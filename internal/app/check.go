@@ -0,0 +1,345 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// check.go - `mactop check --budget`: asserts power and thermal limits over a recording or a command
+package app
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// budgetExpr matches `[aggregate] field [op] threshold`.
+var budgetExpr = regexp.MustCompile(`^\s*(?:(max|min|avg|p[0-9]{1,2}(?:\.[0-9]+)?)\s+)?((?:[A-Za-z0-9_.]|\[[^\]]*\])+)\s*(>=|<=|==|!=|>|<)?\s*(\S.*?)\s*$`)
+
+// budgetSessionFields are measured over the whole session rather than per
+// sample, so they take no aggregate.
+var budgetSessionFields = []string{
+	"wall_seconds", "throttled_seconds",
+	"energy_j", "energy_wh", "package_j", "cpu_j", "gpu_j", "ane_j", "dram_j",
+}
+
+// budgetFile is the YAML read by --budget.
+type budgetFile struct {
+	Budget []string `yaml:"budget"`
+}
+
+// budgetRule is one compiled limit.
+type budgetRule struct {
+	agg       string // "" for session fields
+	field     string
+	op        string
+	threshold float64
+}
+
+// budgetResult is a rule evaluated against a session.
+type budgetResult struct {
+	rule  *budgetRule
+	value float64
+	ok    bool
+	err   error
+}
+
+// budgetSession collects what the rules need from a run of samples: the
+// series of every per-sample field a rule reads, and the session totals.
+type budgetSession struct {
+	series    map[string][]float64
+	seconds   float64
+	throttled float64
+	joules    [6]float64
+}
+
+func loadBudget(path string) ([]*budgetRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f budgetFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(f.Budget) == 0 {
+		return nil, fmt.Errorf("%s: no rules under budget:", path)
+	}
+	known := alertKnownFields()
+	var rules []*budgetRule
+	for _, expr := range f.Budget {
+		r, err := compileBudgetRule(expr, known)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// compileBudgetRule parses one rule. A per-sample field without an
+// aggregate is checked against its max; a missing operator means "at most",
+// or "at least" for min.
+func compileBudgetRule(expr string, known map[string]bool) (*budgetRule, error) {
+	m := budgetExpr.FindStringSubmatch(expr)
+	if m == nil {
+		return nil, fmt.Errorf("%q: expected `[max|min|avg|pNN] field [op] value`", expr)
+	}
+	r := &budgetRule{agg: m[1], op: m[3]}
+	var err error
+	if isBudgetSessionField(m[2]) {
+		if r.agg != "" {
+			return nil, fmt.Errorf("%q: %s is a session total and takes no aggregate", expr, m[2])
+		}
+		r.field = m[2]
+	} else {
+		if r.field, err = resolveAlertField(m[2], false, known); err != nil {
+			return nil, fmt.Errorf("%q: %w", expr, err)
+		}
+		if r.agg == "" {
+			r.agg = "max"
+		}
+		if _, err := budgetPercentile(r.agg); err != nil {
+			return nil, fmt.Errorf("%q: %w", expr, err)
+		}
+	}
+	if r.op == "" {
+		r.op = "<="
+		if r.agg == "min" {
+			r.op = ">="
+		}
+	}
+	if r.threshold, err = parseAlertThreshold(m[4], r.field); err != nil {
+		return nil, fmt.Errorf("%q: %w", expr, err)
+	}
+	return r, nil
+}
+
+func isBudgetSessionField(name string) bool {
+	for _, f := range budgetSessionFields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// budgetPercentile returns the percentile a pNN aggregate asks for, or -1
+// for the other aggregates.
+func budgetPercentile(agg string) (float64, error) {
+	after, ok := strings.CutPrefix(agg, "p")
+	if !ok {
+		return -1, nil
+	}
+	p, err := strconv.ParseFloat(after, 64)
+	if err != nil || p <= 0 || p > 100 {
+		return 0, fmt.Errorf("invalid percentile %q", agg)
+	}
+	return p, nil
+}
+
+func newBudgetSession(rules []*budgetRule) *budgetSession {
+	s := &budgetSession{series: make(map[string][]float64)}
+	for _, r := range rules {
+		if r.agg != "" {
+			s.series[r.field] = nil
+		}
+	}
+	return s
+}
+
+// add records one sample that stands for dt seconds. A field the sample
+// lacks, such as a fan that has gone or display_fps before the first
+// frame, adds nothing to its series.
+func (s *budgetSession) add(output HeadlessOutput, thermal thermalStateLevel, dt float64) {
+	fields := alertFieldValues(output, thermal)
+	for field := range s.series {
+		if v, ok := fields[field]; ok {
+			s.series[field] = append(s.series[field], v)
+		}
+	}
+	s.seconds += dt
+	if thermal >= thermalStateSerious {
+		s.throttled += dt
+	}
+	// In energyComponents order.
+	watts := [6]float64{fields["cpu_w"], fields["gpu_w"], fields["ane_w"], fields["dram_w"], fields["package_w"], fields["system_w"]}
+	for i, w := range watts {
+		s.joules[i] += w * dt
+	}
+}
+
+func (s *budgetSession) sessionValue(field string) float64 {
+	switch field {
+	case "wall_seconds":
+		return s.seconds
+	case "throttled_seconds":
+		return s.throttled
+	case "energy_j":
+		return s.joules[5]
+	case "energy_wh":
+		return s.joules[5] / 3600
+	case "package_j":
+		return s.joules[4]
+	case "cpu_j":
+		return s.joules[0]
+	case "gpu_j":
+		return s.joules[1]
+	case "ane_j":
+		return s.joules[2]
+	case "dram_j":
+		return s.joules[3]
+	}
+	return 0
+}
+
+func (s *budgetSession) evaluate(rules []*budgetRule) []budgetResult {
+	results := make([]budgetResult, 0, len(rules))
+	for _, r := range rules {
+		res := budgetResult{rule: r}
+		if r.agg == "" {
+			res.value = s.sessionValue(r.field)
+		} else {
+			res.value, res.err = aggregateBudget(r.agg, s.series[r.field])
+		}
+		res.ok = res.err == nil && compareAlert(res.value, r.op, r.threshold)
+		results = append(results, res)
+	}
+	return results
+}
+
+func aggregateBudget(agg string, values []float64) (float64, error) {
+	if len(values) == 0 {
+		return 0, errors.New("no samples")
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	switch agg {
+	case "max":
+		return sorted[len(sorted)-1], nil
+	case "min":
+		return sorted[0], nil
+	case "avg":
+		var sum float64
+		for _, v := range sorted {
+			sum += v
+		}
+		return sum / float64(len(sorted)), nil
+	}
+	// Nearest rank: the smallest value with at least p% of samples at or below it.
+	p, err := budgetPercentile(agg)
+	if err != nil {
+		return 0, err
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1], nil
+}
+
+// writeBudgetResults prints the pass/fail table and reports whether every
+// rule passed.
+func writeBudgetResults(w io.Writer, results []budgetResult) bool {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RULE\tLIMIT\tVALUE\tRESULT")
+	passed := true
+	for _, res := range results {
+		r := res.rule
+		status, value := "pass", strconv.FormatFloat(res.value, 'f', 2, 64)
+		if !res.ok {
+			status, passed = "FAIL", false
+		}
+		if res.err != nil {
+			value = res.err.Error()
+		}
+		name := r.field
+		if r.agg != "" {
+			name = r.agg + " " + r.field
+		}
+		fmt.Fprintf(tw, "%s\t%s %g\t%s\t%s\n", name, r.op, r.threshold, value, status)
+	}
+	tw.Flush()
+	return passed
+}
+
+// runCheck is `mactop check`. It evaluates --budget against the --replay
+// recording, or against a command it runs and samples like `mactop run`,
+// and exits 1 if any limit is exceeded.
+func runCheck() {
+	if budgetPath == "" {
+		fmt.Fprintln(os.Stderr, "Error: mactop check requires --budget budget.yaml")
+		os.Exit(2)
+	}
+	rules, err := loadBudget(budgetPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	command := flag.Args()
+	if (replayPath == "") == (len(command) == 0) {
+		fmt.Fprintln(os.Stderr, "Error: mactop check needs either --replay <file> or -- <command>")
+		os.Exit(2)
+	}
+
+	session := newBudgetSession(rules)
+	commandOK := true
+	if replayPath != "" {
+		rec, err := loadRecording(replayPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		addRecordingToBudget(session, rec)
+	} else {
+		commandOK = checkCommand(session, command)
+	}
+
+	passed := writeBudgetResults(os.Stdout, session.evaluate(rules))
+	if !commandOK {
+		fmt.Fprintf(os.Stdout, "command %q did not exit cleanly\n", strings.Join(command, " "))
+	}
+	if !passed || !commandOK {
+		os.Exit(1)
+	}
+}
+
+// addRecordingToBudget feeds every recorded sample to the session. Each
+// sample stands for its own interval, so gaps between recording sessions
+// don't count towards the totals.
+func addRecordingToBudget(session *budgetSession, rec *Recording) {
+	for _, s := range rec.Samples {
+		session.add(s.HeadlessOutput, s.ThermalLevel, float64(s.IntervalMs)/1000)
+	}
+}
+
+// checkCommand runs command, sampling every collector at the `mactop run`
+// rate, and reports whether it exited with status 0.
+func checkCommand(session *budgetSession, command []string) bool {
+	updateInterval = runSampleInterval()
+	if err := initSocMetrics(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to initialize metrics: %v\n", err)
+		os.Exit(1)
+	}
+	defer cleanupSocMetrics()
+	GetCPUPercentages()
+	getProcessList(0)
+
+	sysInfo := getSOCInfo()
+	var last time.Time
+	res, err := superviseCommand(command,
+		func(_ int, start time.Time) { last = start },
+		func() {
+			s := takeHeadlessSample()
+			now := time.Now()
+			session.add(buildHeadlessOutput(s, nil, sysInfo), s.thermal, now.Sub(last).Seconds())
+			last = now
+		})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	return res.state != nil && res.state.Success()
+}
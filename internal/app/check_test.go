@@ -0,0 +1,126 @@
+package app
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBudgetCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "budget.yaml")
+	os.WriteFile(path, []byte(`budget:
+  - max package_w 40
+  - avg gpu_active < 70
+  - p95 cpu_temp < 90
+  - min fan_rpm 1000
+  - throttled_seconds == 0
+  - energy_j < 1200
+`), 0644)
+	rules, err := loadBudget(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := newBudgetSession(rules)
+	for i := range 20 {
+		var o HeadlessOutput
+		// 20 W of SoC power plus 10 W for the rest of the machine.
+		o.SocMetrics.TotalPower, o.SocMetrics.SystemPower = 30, 10
+		o.SocMetrics.GPUActive = 50
+		o.SocMetrics.CPUTemp = float32(70 + i)
+		o.Fans = []HeadlessFan{{RPM: 1200}}
+		thermal := thermalStateNominal
+		if i == 19 {
+			thermal = thermalStateSerious
+		}
+		session.add(o, thermal, 2)
+	}
+
+	want := map[string]struct {
+		value float64
+		ok    bool
+	}{
+		"max package_w":     {20, true},
+		"avg gpu_active":    {50, true},
+		"p95 cpu_temp":      {88, true},
+		"min fan_rpm":       {1200, true},
+		"throttled_seconds": {2, false},
+		"energy_j":          {30 * 40, false},
+	}
+	for _, res := range session.evaluate(rules) {
+		name := strings.TrimPrefix(res.rule.agg+" "+res.rule.field, " ")
+		name = strings.Replace(name, "soc_metrics.", "", 1)
+		w, ok := want[name]
+		if !ok {
+			t.Errorf("unexpected rule %q", name)
+			continue
+		}
+		if res.err != nil || math.Abs(res.value-w.value) > 1e-9 || res.ok != w.ok {
+			t.Errorf("%s = %v (ok %v, err %v), want %v (ok %v)", name, res.value, res.ok, res.err, w.value, w.ok)
+		}
+	}
+
+	var out bytes.Buffer
+	if writeBudgetResults(&out, session.evaluate(rules)) {
+		t.Error("writeBudgetResults passed a session with violations")
+	}
+	if !strings.Contains(out.String(), "FAIL") || !strings.Contains(out.String(), "pass") {
+		t.Errorf("table missing pass/fail results:\n%s", out.String())
+	}
+}
+
+func TestBudgetListFields(t *testing.T) {
+	known := alertKnownFields()
+	var rules []*budgetRule
+	for _, expr := range []string{
+		"max display_fps < 200",
+		"max ecpu_usage.1 < 90",
+		"max fans.0.rpm < 3000",
+		"max temperatures.0.max_celsius < 90",
+		"min temperatures[group=GPU].min_celsius > 30",
+	} {
+		r, err := compileBudgetRule(expr, known)
+		if err != nil {
+			t.Fatalf("compileBudgetRule(%q): %v", expr, err)
+		}
+		rules = append(rules, r)
+	}
+
+	// Only the second sample has a display rate and a GPU group, so their
+	// series must not start with a 0 for the first.
+	session := newBudgetSession(rules)
+	session.add(HeadlessOutput{Fans: []HeadlessFan{{RPM: 1500}}}, thermalStateNominal, 1)
+	session.add(HeadlessOutput{
+		DisplayFPS:   60,
+		Temperatures: []HeadlessTempGroup{{Group: "GPU", Min: 41, Max: 44}},
+	}, thermalStateNominal, 1)
+	want := []float64{60, 0, 1500, 44, 41}
+	for i, res := range session.evaluate(rules) {
+		if i == 1 {
+			if res.err == nil {
+				t.Errorf("%s: got %v from no samples", res.rule.field, res.value)
+			}
+			continue
+		}
+		if res.err != nil || res.value != want[i] || !res.ok {
+			t.Errorf("%s %s = %v (ok %v, err %v), want %v", res.rule.agg, res.rule.field, res.value, res.ok, res.err, want[i])
+		}
+	}
+}
+
+func TestCompileBudgetRuleErrors(t *testing.T) {
+	known := alertKnownFields()
+	for _, expr := range []string{
+		"max throttled_seconds 0", // Session totals take no aggregate
+		"p0 cpu_temp < 90",
+		"avg no_such_field < 1",
+		"max package_w lots",
+	} {
+		if _, err := compileBudgetRule(expr, known); err == nil {
+			t.Errorf("compileBudgetRule(%q) succeeded, want an error", expr)
+		}
+	}
+}
@@ -66,6 +66,42 @@ func handleFlag(arg string, idx int, args []string) (int, string, int, bool, boo
 	return emptyResult(idx).values()
 }
 
+// subcommands maps the verbs accepted as the first argument to their entry
+// points. Flags may appear before or after the verb. An entry point returns
+// false when the TUI should still start afterwards.
+var subcommands = map[string]func() bool{
+	"serve":   func() bool { runServe(); return true },
+	"cluster": setupCluster,
+	"run":     func() bool { runCommand(); return true },
+	"check":   func() bool { runCheck(); return true },
+	"query":   func() bool { runQuery(); return true },
+	"schema":  func() bool { runSchema(); return true },
+}
+
+// splitSubcommand finds a subcommand among args and returns it along with
+// the remaining arguments for flag parsing.
+func splitSubcommand(args []string) (string, []string) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		if _, ok := subcommands[arg]; !ok {
+			// A flag value such as "--color green"; keep scanning.
+			continue
+		}
+		rest := append(append([]string(nil), args[:i]...), args[i+1:]...)
+		return arg, rest
+	}
+	return "", args
+}
+
+func runSubcommand(name string) bool {
+	return subcommands[name]()
+}
+
 func printHelpAndExit() {
 	fmt.Print(i18n.T("CLI_HelpText"))
 	os.Exit(0)
@@ -160,6 +196,9 @@ func earlyResolveLanguage() string {
 	args := os.Args
 	for i := 1; i < len(args); i++ {
 		a := args[i]
+		if a == "--" {
+			break
+		}
 		// --lang=xx or -lang=xx
 		if after, ok := strings.CutPrefix(a, "--lang="); ok {
 			return after
@@ -190,6 +229,10 @@ func handleLegacyFlags() (string, int, bool, bool) {
 		setColor, setInterval bool
 	)
 	for i := 1; i < len(os.Args); i++ {
+		// Everything after "--" belongs to the command given to `mactop run`.
+		if os.Args[i] == "--" {
+			break
+		}
 		newI, cName, intVal, isColor, isInt, err := handleFlag(os.Args[i], i, os.Args)
 		if err != nil {
 			fmt.Println(err)
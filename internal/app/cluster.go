@@ -0,0 +1,259 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// cluster.go - `mactop cluster`: one row per `mactop serve` node plus cluster totals
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/metaspartan/mactop/v2/internal/i18n"
+)
+
+// clusterNode is the last poll result for one host.
+type clusterNode struct {
+	addr    string
+	output  *HeadlessOutput
+	err     error
+	updated time.Time
+}
+
+// clusterTotals aggregates the reachable nodes.
+type clusterTotals struct {
+	up, rdma              int
+	cpuUsage, gpuUsage    float64
+	power                 float64
+	cpuTemp, gpuTemp      float64
+	tbIn, tbOut           float64
+	tflopsUsed, tflopsMax float64
+}
+
+var (
+	clusterMu    sync.Mutex
+	clusterNodes []clusterNode
+)
+
+// setupCluster handles `mactop cluster`: it starts polling --hosts and opens
+// the TUI on the cluster layout, which also joins the layout cycle.
+func setupCluster() bool {
+	hosts, err := parseClusterHosts(clusterHosts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --hosts: %v\n", err)
+		os.Exit(1)
+	}
+	if len(hosts) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: mactop cluster requires --hosts host1:port,host2:port")
+		os.Exit(1)
+	}
+	clusterNodes = make([]clusterNode, len(hosts))
+	for i, h := range hosts {
+		clusterNodes[i].addr = h
+	}
+	layoutOrder = append(layoutOrder, LayoutCluster)
+	currentConfig.DefaultLayout = LayoutCluster
+	go pollCluster()
+	return false
+}
+
+// parseClusterHosts splits the --hosts list, defaulting the port to the one
+// `mactop serve` listens on. An http:// prefix is accepted and dropped since
+// the snapshot is always fetched over plain HTTP.
+func parseClusterHosts(list string) ([]string, error) {
+	var hosts []string
+	for h := range strings.SplitSeq(list, ",") {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		if scheme, rest, ok := strings.Cut(h, "://"); ok {
+			if scheme != "http" {
+				return nil, fmt.Errorf("%s: mactop serve only speaks http", h)
+			}
+			h = strings.TrimSuffix(rest, "/")
+		}
+		if strings.Contains(h, "/") {
+			return nil, fmt.Errorf("%s: want host or host:port, not a URL path", h)
+		}
+		if _, _, err := net.SplitHostPort(h); err != nil {
+			// No port, possibly a bare or bracketed IPv6 address.
+			h = net.JoinHostPort(strings.Trim(h, "[]"), "7070")
+		}
+		hosts = append(hosts, h)
+	}
+	return hosts, nil
+}
+
+// pollCluster fetches every node's snapshot once per update interval. Nodes
+// are polled in parallel so one slow host doesn't delay the rest.
+func pollCluster() {
+	client := &http.Client{Timeout: 3 * time.Second}
+	for {
+		start := time.Now()
+		var wg sync.WaitGroup
+		for i := range clusterNodes {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				output, err := fetchClusterSnapshot(client, clusterNodes[i].addr)
+				clusterMu.Lock()
+				defer clusterMu.Unlock()
+				clusterNodes[i].err = err
+				if err == nil {
+					clusterNodes[i].output = output
+					clusterNodes[i].updated = time.Now()
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		if sleep := time.Duration(updateInterval)*time.Millisecond - time.Since(start); sleep > 0 {
+			time.Sleep(sleep)
+		}
+	}
+}
+
+func fetchClusterSnapshot(client *http.Client, addr string) (*HeadlessOutput, error) {
+	u := url.URL{Scheme: "http", Host: addr, Path: snapshotPath}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	var output HeadlessOutput
+	if err := json.NewDecoder(resp.Body).Decode(&output); err != nil {
+		return nil, fmt.Errorf("decode snapshot: %w", err)
+	}
+	return &output, nil
+}
+
+// snapshotClusterNodes copies the poll results for rendering.
+func snapshotClusterNodes() []clusterNode {
+	clusterMu.Lock()
+	defer clusterMu.Unlock()
+	return append([]clusterNode(nil), clusterNodes...)
+}
+
+// clusterNodeStale reports whether a node has missed enough polls that its
+// last values no longer describe it.
+func clusterNodeStale(n clusterNode) bool {
+	return n.output == nil || time.Since(n.updated) > 3*time.Duration(max(updateInterval, 1000))*time.Millisecond
+}
+
+func sumClusterTotals(nodes []clusterNode) clusterTotals {
+	var t clusterTotals
+	for _, n := range nodes {
+		if clusterNodeStale(n) {
+			continue
+		}
+		o := n.output
+		t.up++
+		t.cpuUsage += o.CPUUsage
+		t.gpuUsage += o.GPUUsage
+		t.power += o.SocMetrics.TotalPower
+		t.cpuTemp = math.Max(t.cpuTemp, float64(o.SocMetrics.CPUTemp))
+		t.gpuTemp = math.Max(t.gpuTemp, float64(o.SocMetrics.GPUTemp))
+		t.tbIn += o.TBNetTotalBytesInSec
+		t.tbOut += o.TBNetTotalBytesOutSec
+		t.tflopsMax += o.TFLOPsFP32
+		t.tflopsUsed += o.TFLOPsFP32 * o.GPUUsage / 100
+		if o.RDMAStatus.Available {
+			t.rdma++
+		}
+	}
+	if t.up > 0 {
+		t.cpuUsage /= float64(t.up)
+		t.gpuUsage /= float64(t.up)
+	}
+	return t
+}
+
+// clusterColumnWidths are the display widths of the table columns: node,
+// CPU, GPU, power, CPU temp, GPU temp, TB in, TB out, RDMA and TFLOPs.
+var clusterColumnWidths = []int{22, 6, 6, 9, 9, 9, 10, 10, 12, 12}
+
+func clusterRow(cells ...string) string {
+	var b strings.Builder
+	for i, c := range cells {
+		width := clusterColumnWidths[min(i, len(clusterColumnWidths)-1)]
+		b.WriteString(runewidth.FillRight(runewidth.Truncate(c, width, "…"), width))
+		b.WriteString(" ")
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+// escapeMarkup keeps brackets in host names and errors (IPv6 addresses) from
+// being read as gotui style tags.
+func escapeMarkup(s string) string {
+	return strings.NewReplacer("[", "(", "]", ")").Replace(s)
+}
+
+func formatClusterRDMA(s RDMAStatus) string {
+	if !s.Available {
+		return "-"
+	}
+	if len(s.Devices) > 0 {
+		return fmt.Sprintf("%s (%d)", s.Status, len(s.Devices))
+	}
+	return s.Status
+}
+
+// buildClusterText renders the node table and totals for the cluster layout.
+func buildClusterText(themeColor string) string {
+	nodes := snapshotClusterNodes()
+	totals := sumClusterTotals(nodes)
+
+	lines := []string{
+		fmt.Sprintf("[%s](fg:%s,mod:bold)", clusterRow(i18n.T("Cluster_Node"), i18n.T("Overlay_CPU"), i18n.T("Overlay_GPU"),
+			i18n.T("Overlay_Power"), i18n.T("Fan_CPUTemp"), i18n.T("Fan_GPUTemp"), "TB ↓", "TB ↑", "RDMA", "TFLOPs"), themeColor),
+	}
+	for _, n := range nodes {
+		if clusterNodeStale(n) {
+			reason := i18n.T("Cluster_Unreachable")
+			if n.err != nil {
+				reason += ": " + n.err.Error()
+			}
+			lines = append(lines, fmt.Sprintf("[%s %s](fg:red)", clusterRow(escapeMarkup(n.addr)), escapeMarkup(reason)))
+			continue
+		}
+		o := n.output
+		lines = append(lines, fmt.Sprintf("[%s](fg:%s)", clusterRow(
+			escapeMarkup(n.addr),
+			fmt.Sprintf("%.0f%%", o.CPUUsage),
+			fmt.Sprintf("%.0f%%", o.GPUUsage),
+			fmt.Sprintf("%.1f W", o.SocMetrics.TotalPower),
+			formatTemp(float64(o.SocMetrics.CPUTemp)),
+			formatTemp(float64(o.SocMetrics.GPUTemp)),
+			formatBytes(o.TBNetTotalBytesInSec, networkUnit)+"/s",
+			formatBytes(o.TBNetTotalBytesOutSec, networkUnit)+"/s",
+			formatClusterRDMA(o.RDMAStatus),
+			fmt.Sprintf("%.1f", o.TFLOPsFP32*o.GPUUsage/100),
+		), themeColor))
+	}
+
+	lines = append(lines, "", fmt.Sprintf("[%s](fg:%s,mod:bold)", clusterRow(
+		i18n.T("Cluster_Total"),
+		fmt.Sprintf("%.0f%%", totals.cpuUsage),
+		fmt.Sprintf("%.0f%%", totals.gpuUsage),
+		fmt.Sprintf("%.1f W", totals.power),
+		formatTemp(totals.cpuTemp),
+		formatTemp(totals.gpuTemp),
+		formatBytes(totals.tbIn, networkUnit)+"/s",
+		formatBytes(totals.tbOut, networkUnit)+"/s",
+		fmt.Sprintf("%d/%d", totals.rdma, totals.up),
+		fmt.Sprintf("%.1f/%.0f", totals.tflopsUsed, totals.tflopsMax),
+	), themeColor))
+	lines = append(lines, fmt.Sprintf("[%s](fg:%s)", fmt.Sprintf(i18n.T("Cluster_NodesUp"), totals.up, len(nodes)), themeColor))
+
+	return renderScrollableLines(lines, themeColor)
+}
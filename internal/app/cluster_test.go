@@ -0,0 +1,70 @@
+package app
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseClusterHosts(t *testing.T) {
+	tests := []struct {
+		name    string
+		list    string
+		want    []string
+		wantErr bool
+	}{
+		{"Empty", "", nil, false},
+		{"Default port", "studio-1, studio-2", []string{"studio-1:7070", "studio-2:7070"}, false},
+		{"Explicit port", "studio-1:9000,,10.0.0.2", []string{"studio-1:9000", "10.0.0.2:7070"}, false},
+		{"IPv6", "fe80::1,[fe80::2],[fe80::3]:9000", []string{"[fe80::1]:7070", "[fe80::2]:7070", "[fe80::3]:9000"}, false},
+		{"HTTP scheme", "http://mini1, http://mini2:9000/", []string{"mini1:7070", "mini2:9000"}, false},
+		{"HTTPS scheme", "https://mini1", nil, true},
+		{"URL path", "http://mini1/api/v1/snapshot", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseClusterHosts(tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseClusterHosts(%q) error = %v, wantErr %v", tt.list, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseClusterHosts(%q) = %q, want %q", tt.list, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSumClusterTotals(t *testing.T) {
+	node := func(cpu, gpu, watts, tflops float64, rdma bool, updated time.Time) clusterNode {
+		o := &HeadlessOutput{CPUUsage: cpu, GPUUsage: gpu, TFLOPsFP32: tflops, RDMAStatus: RDMAStatus{Available: rdma}}
+		o.SocMetrics.TotalPower = watts
+		o.SocMetrics.GPUTemp = float32(40 + gpu/2)
+		return clusterNode{addr: "n", output: o, updated: updated}
+	}
+	now := time.Now()
+	nodes := []clusterNode{
+		node(20, 50, 30, 10, true, now),
+		node(40, 100, 90, 20, false, now),
+		node(90, 90, 200, 40, true, now.Add(-time.Hour)), // stale
+		{addr: "down"},
+	}
+
+	got := sumClusterTotals(nodes)
+	if got.up != 2 || got.rdma != 1 {
+		t.Errorf("up, rdma = %d, %d, want 2, 1", got.up, got.rdma)
+	}
+	if got.power != 120 {
+		t.Errorf("power = %v, want 120", got.power)
+	}
+	if got.cpuUsage != 30 || got.gpuUsage != 75 {
+		t.Errorf("cpu, gpu usage = %v, %v, want 30, 75", got.cpuUsage, got.gpuUsage)
+	}
+	if got.gpuTemp != 90 {
+		t.Errorf("gpuTemp = %v, want the hottest node (90)", got.gpuTemp)
+	}
+	if math.Abs(got.tflopsUsed-25) > 1e-9 || got.tflopsMax != 30 {
+		t.Errorf("tflops = %v/%v, want 25/30", got.tflopsUsed, got.tflopsMax)
+	}
+}
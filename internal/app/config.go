@@ -26,6 +26,10 @@ type CustomThemeConfig struct {
 	ProcessListDim      string `json:"processListDim,omitempty"`      // Non-current-user process text color (default: grey)
 	ProcessListSelected string `json:"processListSelected,omitempty"` // Selected row foreground color (default: auto contrast)
 	SystemInfo          string `json:"systemInfo,omitempty"`          // Apple Silicon system info box color
+
+	// Threshold colors for usage bars, temperatures, fan speed and the status bar formats
+	Warning  string `json:"warning,omitempty"`  // Getting busy or hot (default: yellow)
+	Critical string `json:"critical,omitempty"` // Near the limit (default: red)
 }
 
 // MenuBarConfig controls the appearance of the --menubar status item
@@ -67,6 +71,37 @@ type OverlayConfig struct {
 	Opacity *float64 `json:"opacity,omitempty"`
 }
 
+// AlertConfig is one threshold rule from the "alerts" section.
+type AlertConfig struct {
+	// Name labels the alert in banners, logs and webhooks (default: When).
+	Name string `json:"name,omitempty"`
+
+	// When is the condition, e.g. "cpu_temp > 95 for 30s",
+	// "thermal_state >= serious" or `process "ollama" gpu > 500ms/s`.
+	When string `json:"when"`
+
+	// For is how long the condition must hold before the alert fires (e.g. "30s").
+	// A trailing "for <duration>" in When sets the same thing.
+	For string `json:"for,omitempty"`
+
+	// Hysteresis is how far the value must move back past the threshold,
+	// in the field's own unit, before a firing alert clears.
+	Hysteresis float64 `json:"hysteresis,omitempty"`
+
+	// Cooldown is the minimum time between two runs of the actions (e.g. "10m").
+	Cooldown string `json:"cooldown,omitempty"`
+
+	// Actions run when the alert fires (default: a banner).
+	Actions []AlertAction `json:"actions,omitempty"`
+}
+
+// AlertAction is something to do when an alert fires.
+type AlertAction struct {
+	Type    string `json:"type"`              // bell, banner, shell or webhook
+	Command string `json:"command,omitempty"` // shell: run with sh -c, alert details in MACTOP_ALERT_* variables
+	URL     string `json:"url,omitempty"`     // webhook: receives a JSON POST
+}
+
 type AppConfig struct {
 	Language      string             `json:"language,omitempty"`
 	DefaultLayout string             `json:"default_layout"`
@@ -78,6 +113,8 @@ type AppConfig struct {
 	CustomTheme   *CustomThemeConfig `json:"custom_theme,omitempty"`
 	MenuBar       *MenuBarConfig     `json:"menubar,omitempty"`
 	Overlay       *OverlayConfig     `json:"overlay,omitempty"`
+	Alerts        []AlertConfig      `json:"alerts,omitempty"`
+	PricePerKWh   float64            `json:"price_per_kwh,omitempty"`
 }
 
 // intOrDefault returns v if > 0, otherwise def.
@@ -294,7 +331,7 @@ func loadThemeFile() *CustomThemeConfig {
 	}
 
 	// Validate at least one color is set
-	if theme.Foreground == "" && theme.Background == "" {
+	if theme.Foreground == "" && theme.Background == "" && theme.Warning == "" && theme.Critical == "" {
 		return nil
 	}
 
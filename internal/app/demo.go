@@ -0,0 +1,622 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// demo.go - Synthetic workloads for --demo, rendered into a looping recording
+package app
+
+import (
+	"embed"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed demo/*.yaml
+var demoFS embed.FS
+
+// demoScenario is a workload timeline. Built-in scenarios live in demo/*.yaml
+// and use the same format accepted from a user-supplied file.
+type demoScenario struct {
+	Name       string        `yaml:"name"`
+	Model      string        `yaml:"model"`
+	ECores     int           `yaml:"e_cores"`
+	PCores     int           `yaml:"p_cores"`
+	GPUCores   int           `yaml:"gpu_cores"`
+	MemoryGB   float64       `yaml:"memory_gb"`
+	Fans       *int          `yaml:"fans"`
+	FanMaxRPM  int           `yaml:"fan_max_rpm"`
+	Background []demoProcess `yaml:"background"`
+	Phases     []demoPhase   `yaml:"phases"`
+	Seed       uint64        `yaml:"seed"`
+	fanCount   int
+	states     []demoState
+}
+
+// demoPhase sets targets the metrics ramp toward. Unset fields carry over
+// from the previous phase.
+type demoPhase struct {
+	Name         string         `yaml:"name"`
+	Duration     time.Duration  `yaml:"duration"`
+	Ramp         *time.Duration `yaml:"ramp"`
+	ECPU         *float64       `yaml:"ecpu"`
+	PCPU         *float64       `yaml:"pcpu"`
+	GPU          *float64       `yaml:"gpu"`
+	ANEWatts     *float64       `yaml:"ane_watts"`
+	CPUTemp      *float64       `yaml:"cpu_temp"`
+	GPUTemp      *float64       `yaml:"gpu_temp"`
+	FanRPM       []float64      `yaml:"fan_rpm"`
+	Thermal      string         `yaml:"thermal"`
+	FreqCap      *float64       `yaml:"freq_cap"`
+	MemoryUsedGB *float64       `yaml:"memory_used_gb"`
+	NetMBs       *float64       `yaml:"net_mb_s"`
+	DiskMBs      *float64       `yaml:"disk_mb_s"`
+	DRAMReadGBs  *float64       `yaml:"dram_read_gbs"`
+	DRAMWriteGBs *float64       `yaml:"dram_write_gbs"`
+	Processes    *[]demoProcess `yaml:"processes"`
+}
+
+// demoProcess is a process shown while its phase runs. CPU is percent of one
+// core as in ps; GPU is milliseconds of GPU time per second.
+type demoProcess struct {
+	Name  string  `yaml:"name"`
+	User  string  `yaml:"user"`
+	CPU   float64 `yaml:"cpu"`
+	GPU   float64 `yaml:"gpu"`
+	MemGB float64 `yaml:"mem_gb"`
+	Count int     `yaml:"count"`
+}
+
+// demoState is a fully resolved phase: every target has a value.
+type demoState struct {
+	name                       string
+	duration, ramp             time.Duration
+	ecpu, pcpu, gpu, ane       float64
+	cpuTemp, gpuTemp           float64
+	fanRPM                     []float64
+	thermal                    thermalStateLevel
+	freqCap                    float64
+	memUsedGB, netMBs, diskMBs float64
+	dramReadGBs, dramWriteGBs  float64
+	processes                  []demoProcess
+}
+
+var defaultDemoBackground = []demoProcess{
+	{Name: "kernel_task", User: "root", CPU: 3.5, MemGB: 0.01},
+	{Name: "WindowServer", User: "_windowserver", CPU: 6, GPU: 14, MemGB: 0.6},
+	{Name: "launchd", User: "root", CPU: 0.3, MemGB: 0.02},
+	{Name: "Terminal", CPU: 2.5, GPU: 2, MemGB: 0.2},
+	{Name: "Finder", CPU: 0.4, MemGB: 0.15},
+	{Name: "coreaudiod", User: "_coreaudiod", CPU: 0.8, MemGB: 0.02},
+	{Name: "Safari", CPU: 1.8, GPU: 3, MemGB: 0.9},
+}
+
+// demoScenarioIDs lists the built-in scenarios in help order.
+func demoScenarioIDs() []string {
+	entries, _ := demoFS.ReadDir("demo")
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, strings.TrimSuffix(e.Name(), ".yaml"))
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// normalizeDemoName folds "LLM inference on GPU", "llm-inference" and
+// "LLM_Inference" onto a comparable form.
+func normalizeDemoName(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// loadDemoScenario resolves a built-in scenario by id or title, or reads a
+// YAML timeline from disk.
+func loadDemoScenario(name string) (*demoScenario, error) {
+	if _, err := os.Stat(name); err == nil {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("read demo scenario: %w", err)
+		}
+		return parseDemoScenario(data)
+	}
+
+	want := normalizeDemoName(name)
+	for _, id := range demoScenarioIDs() {
+		data, err := demoFS.ReadFile(path.Join("demo", id+".yaml"))
+		if err != nil {
+			return nil, fmt.Errorf("read built-in demo %s: %w", id, err)
+		}
+		sc, err := parseDemoScenario(data)
+		if err != nil {
+			return nil, fmt.Errorf("built-in demo %s: %w", id, err)
+		}
+		if normalizeDemoName(id) == want || normalizeDemoName(sc.Name) == want ||
+			strings.HasPrefix(normalizeDemoName(sc.Name), want) {
+			return sc, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown demo scenario %q (built-in: %s, or a path to a YAML timeline)",
+		name, strings.Join(demoScenarioIDs(), ", "))
+}
+
+func parseDemoScenario(data []byte) (*demoScenario, error) {
+	sc := &demoScenario{}
+	if err := yaml.Unmarshal(data, sc); err != nil {
+		return nil, fmt.Errorf("parse demo scenario: %w", err)
+	}
+	if len(sc.Phases) == 0 {
+		return nil, fmt.Errorf("demo scenario %q has no phases", sc.Name)
+	}
+	sc.applyDefaults()
+	if err := sc.resolve(); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+func (sc *demoScenario) applyDefaults() {
+	if sc.Name == "" {
+		sc.Name = "Custom"
+	}
+	if sc.Model == "" {
+		sc.Model = "Apple M4 Pro"
+	}
+	if sc.ECores == 0 && sc.PCores == 0 {
+		sc.ECores, sc.PCores = 4, 10
+	}
+	if sc.GPUCores == 0 {
+		sc.GPUCores = 20
+	}
+	if sc.MemoryGB == 0 {
+		sc.MemoryGB = 48
+	}
+	sc.fanCount = 2
+	if sc.Fans != nil {
+		sc.fanCount = *sc.Fans
+	}
+	if sc.FanMaxRPM == 0 {
+		sc.FanMaxRPM = 5800
+	}
+	if sc.Background == nil {
+		sc.Background = defaultDemoBackground
+	}
+	if sc.Seed == 0 {
+		sc.Seed = 1
+	}
+}
+
+// resolve fills every phase from its predecessor, starting from a quiet desktop.
+func (sc *demoScenario) resolve() error {
+	prev := demoState{
+		ramp:         3 * time.Second,
+		ecpu:         10,
+		pcpu:         3,
+		gpu:          3,
+		cpuTemp:      42,
+		gpuTemp:      38,
+		fanRPM:       []float64{1250},
+		thermal:      thermalStateNominal,
+		freqCap:      1,
+		memUsedGB:    sc.MemoryGB * 0.35,
+		netMBs:       0.05,
+		diskMBs:      0.3,
+		dramReadGBs:  3,
+		dramWriteGBs: 1,
+	}
+	sc.states = sc.states[:0]
+	for i, p := range sc.Phases {
+		if p.Duration <= 0 {
+			return fmt.Errorf("demo phase %d (%s) needs a positive duration", i+1, p.Name)
+		}
+		st := prev
+		st.name = p.Name
+		st.duration = p.Duration
+		st.ramp = 3 * time.Second
+		if p.Ramp != nil {
+			st.ramp = *p.Ramp
+		}
+		for _, f := range []struct {
+			dst *float64
+			src *float64
+		}{
+			{&st.ecpu, p.ECPU}, {&st.pcpu, p.PCPU}, {&st.gpu, p.GPU}, {&st.ane, p.ANEWatts},
+			{&st.cpuTemp, p.CPUTemp}, {&st.gpuTemp, p.GPUTemp}, {&st.freqCap, p.FreqCap},
+			{&st.memUsedGB, p.MemoryUsedGB}, {&st.netMBs, p.NetMBs}, {&st.diskMBs, p.DiskMBs},
+			{&st.dramReadGBs, p.DRAMReadGBs}, {&st.dramWriteGBs, p.DRAMWriteGBs},
+		} {
+			if f.src != nil {
+				*f.dst = *f.src
+			}
+		}
+		if p.FanRPM != nil {
+			st.fanRPM = p.FanRPM
+		}
+		if p.Thermal != "" {
+			level, err := parseThermalLevel(p.Thermal)
+			if err != nil {
+				return fmt.Errorf("demo phase %d (%s): %w", i+1, p.Name, err)
+			}
+			st.thermal = level
+		}
+		if p.Processes != nil {
+			st.processes = *p.Processes
+		}
+		sc.states = append(sc.states, st)
+		prev = st
+	}
+	return nil
+}
+
+func parseThermalLevel(s string) (thermalStateLevel, error) {
+	switch strings.ToLower(s) {
+	case "nominal":
+		return thermalStateNominal, nil
+	case "fair":
+		return thermalStateFair, nil
+	case "serious":
+		return thermalStateSerious, nil
+	case "critical":
+		return thermalStateCritical, nil
+	}
+	return thermalStateUnknown, fmt.Errorf("unknown thermal state %q (nominal, fair, serious, critical)", s)
+}
+
+// startDemo renders the scenario into a recording and plays it on a loop.
+func startDemo(name string) error {
+	sc, err := loadDemoScenario(name)
+	if err != nil {
+		return err
+	}
+	interval := time.Duration(max(updateInterval, 100)) * time.Millisecond
+	b := newReplayBackend(sc.render(interval, time.Now()))
+	b.loop = true
+	b.label = sc.Name
+	activeBackend = b
+	return nil
+}
+
+// demoRun carries the state that evolves from one synthetic sample to the next.
+type demoRun struct {
+	sc       *demoScenario
+	rng      *rand.Rand
+	cpuTemp  float64
+	gpuTemp  float64
+	fanRPM   []float64
+	coreBias []float64
+	cpuTime  map[string]float64
+}
+
+// render synthesizes one sample per interval across the whole timeline.
+func (sc *demoScenario) render(interval time.Duration, start time.Time) *Recording {
+	labels, cpuIndexMap := make([]string, 0, sc.ECores+sc.PCores), make([]int, 0, sc.ECores+sc.PCores)
+	for i := range sc.ECores {
+		labels = append(labels, fmt.Sprintf("E%d", i))
+	}
+	for i := range sc.PCores {
+		labels = append(labels, fmt.Sprintf("P%d", i))
+	}
+	for i := range labels {
+		cpuIndexMap = append(cpuIndexMap, i)
+	}
+
+	rec := &Recording{Header: RecordingHeader{
+		Format:        recordingFormat,
+		Version:       recordingVersion,
+		MactopVersion: version,
+		Backend:       "demo",
+		Started:       start.Format(time.RFC3339),
+		SystemInfo: SystemInfo{
+			Name:         sc.Model,
+			CoreCount:    sc.ECores + sc.PCores,
+			ECoreCount:   sc.ECores,
+			PCoreCount:   sc.PCores,
+			GPUCoreCount: sc.GPUCores,
+		},
+		CoreLabels:   labels,
+		CoreIndexMap: cpuIndexMap,
+		ECores:       sc.ECores,
+		PCores:       sc.PCores,
+	}}
+
+	first := sc.states[0]
+	run := &demoRun{
+		sc:      sc,
+		rng:     rand.New(rand.NewPCG(sc.Seed, sc.Seed^0x9e3779b97f4a7c15)),
+		cpuTemp: first.cpuTemp,
+		gpuTemp: first.gpuTemp,
+		fanRPM:  sc.fanTargets(first.fanRPM),
+		cpuTime: make(map[string]float64),
+	}
+	for range sc.ECores + sc.PCores {
+		run.coreBias = append(run.coreBias, run.rng.Float64()*2-1)
+	}
+
+	prev := first
+	var t time.Duration
+	for i, st := range sc.states {
+		if i > 0 {
+			prev = sc.states[i-1]
+		}
+		for elapsed := time.Duration(0); elapsed < st.duration; elapsed += interval {
+			frac := 1.0
+			if st.ramp > 0 && elapsed < st.ramp {
+				frac = float64(elapsed) / float64(st.ramp)
+			}
+			target := lerpDemoState(prev, st, frac, sc)
+			s := run.sample(target, interval)
+			s.UnixMilli = start.Add(t).UnixMilli()
+			s.IntervalMs = int(interval / time.Millisecond)
+			s.Timestamp = start.Add(t).Format(time.RFC3339)
+			s.SystemInfo = rec.Header.SystemInfo
+			rec.Samples = append(rec.Samples, s)
+			t += interval
+		}
+	}
+	rec.buildOffsets()
+	return rec
+}
+
+func (sc *demoScenario) fanTargets(rpm []float64) []float64 {
+	out := make([]float64, sc.fanCount)
+	for i := range out {
+		if len(rpm) > 0 {
+			out[i] = rpm[min(i, len(rpm)-1)]
+		}
+	}
+	return out
+}
+
+func lerp(a, b, frac float64) float64 {
+	return a + (b-a)*frac
+}
+
+// lerpDemoState blends two phases. Discrete values switch at the phase start.
+func lerpDemoState(a, b demoState, frac float64, sc *demoScenario) demoState {
+	out := b
+	out.ecpu = lerp(a.ecpu, b.ecpu, frac)
+	out.pcpu = lerp(a.pcpu, b.pcpu, frac)
+	out.gpu = lerp(a.gpu, b.gpu, frac)
+	out.ane = lerp(a.ane, b.ane, frac)
+	out.freqCap = lerp(a.freqCap, b.freqCap, frac)
+	out.memUsedGB = lerp(a.memUsedGB, b.memUsedGB, frac)
+	out.netMBs = lerp(a.netMBs, b.netMBs, frac)
+	out.diskMBs = lerp(a.diskMBs, b.diskMBs, frac)
+	out.dramReadGBs = lerp(a.dramReadGBs, b.dramReadGBs, frac)
+	out.dramWriteGBs = lerp(a.dramWriteGBs, b.dramWriteGBs, frac)
+	// Temperatures and fan speeds have their own inertia in demoRun.sample.
+	out.fanRPM = sc.fanTargets(b.fanRPM)
+	return out
+}
+
+// jitter returns v with relative noise of +/- pct, clamped to [lo, hi].
+func (r *demoRun) jitter(v, pct, lo, hi float64) float64 {
+	v *= 1 + (r.rng.Float64()*2-1)*pct
+	return math.Max(lo, math.Min(hi, v))
+}
+
+// settle moves cur toward target with a first-order lag of time constant tau.
+func settle(cur, target float64, dt, tau time.Duration) float64 {
+	return target + (cur-target)*math.Exp(-float64(dt)/float64(tau))
+}
+
+func (r *demoRun) sample(st demoState, dt time.Duration) RecordedSample {
+	sc := r.sc
+	r.cpuTemp = settle(r.cpuTemp, st.cpuTemp, dt, 8*time.Second)
+	r.gpuTemp = settle(r.gpuTemp, st.gpuTemp, dt, 8*time.Second)
+	for i := range r.fanRPM {
+		r.fanRPM[i] = settle(r.fanRPM[i], st.fanRPM[i], dt, 3*time.Second)
+	}
+
+	coreUsages := r.coreUsages(st)
+	var eAvg, pAvg float64
+	for i, u := range coreUsages {
+		if i < sc.ECores {
+			eAvg += u / float64(max(sc.ECores, 1))
+		} else {
+			pAvg += u / float64(max(sc.PCores, 1))
+		}
+	}
+	gpuActive := r.jitter(st.gpu, 0.04, 0, 100)
+
+	m := r.socMetrics(st, eAvg, pAvg, gpuActive)
+	var cpuUsage float64
+	for _, u := range coreUsages {
+		cpuUsage += u / float64(len(coreUsages))
+	}
+
+	s := RecordedSample{
+		ThermalLevel: st.thermal,
+		FanDetail:    r.fans(),
+		TempSensors:  r.tempSensors(st),
+		ProcessList:  r.processes(st, dt),
+	}
+	s.SocMetrics = m
+	s.CoreUsages = coreUsages
+	s.CPUUsage = cpuUsage
+	s.GPUUsage = gpuActive
+	s.GPUMetrics = HeadlessGPUMetrics{FreqMHz: int(m.GPUFreqMHz), ActivePercent: gpuActive}
+	s.Memory = r.memory(st)
+	s.NetDisk = r.netDisk(st)
+	s.ThermalState = thermalStateString(st.thermal)
+	s.Volumes = []HeadlessVolume{{Name: "Macintosh HD", TotalGB: 994.7, UsedGB: 412.3, UsedPct: 41.45}}
+	return s
+}
+
+func (r *demoRun) coreUsages(st demoState) []float64 {
+	sc := r.sc
+	usages := make([]float64, sc.ECores+sc.PCores)
+	for i := range usages {
+		target := st.pcpu
+		if i < sc.ECores {
+			target = st.ecpu
+		}
+		// Lightly loaded systems spread work unevenly; saturated ones don't.
+		spread := 12 * (1 - target/100)
+		usages[i] = r.jitter(target+r.coreBias[i]*spread, 0.08, 0, 100)
+	}
+	return usages
+}
+
+// socMetrics models an M4 Pro class SoC: cluster clocks scale with load up to
+// the throttling cap and power follows clocks and utilization.
+func (r *demoRun) socMetrics(st demoState, eAvg, pAvg, gpuActive float64) SocMetrics {
+	sc := r.sc
+	capSq := st.freqCap * st.freqCap
+	eFreq := lerp(1020, 2592, math.Min(1, eAvg/60)) * math.Min(1, st.freqCap+0.15)
+	pFreq := lerp(1260, 4512, math.Min(1, pAvg/50)) * st.freqCap
+	gpuFreq := 0.0
+	if gpuActive > 0.5 {
+		gpuFreq = lerp(338, 1578, math.Min(1, gpuActive/70)) * st.freqCap
+	}
+
+	cpuW := 0.25 + float64(sc.ECores)*0.45*eAvg/100 + float64(sc.PCores)*3.3*pAvg/100*capSq
+	gpuW := float64(sc.GPUCores) * 1.15 * gpuActive / 100 * capSq
+	dramW := 0.35 + (st.dramReadGBs+st.dramWriteGBs)*0.012
+	sramW := 0.02 + gpuW*0.03
+	components := cpuW + gpuW + st.ane + dramW + sramW
+	residual := r.jitter(4.2, 0.1, 0, 100)
+
+	packageTemp := math.Max(r.cpuTemp, r.gpuTemp) - 2
+	return SocMetrics{
+		CPUPower:        r.jitter(cpuW, 0.03, 0, 200),
+		GPUPower:        r.jitter(gpuW, 0.03, 0, 200),
+		ANEPower:        st.ane,
+		DRAMPower:       dramW,
+		GPUSRAMPower:    sramW,
+		SystemPower:     residual,
+		TotalPower:      components + residual,
+		GPUFreqMHz:      int32(gpuFreq),
+		GPUActive:       gpuActive,
+		EClusterActive:  eAvg,
+		PClusterActive:  pAvg,
+		EClusterFreqMHz: int32(eFreq),
+		PClusterFreqMHz: int32(pFreq),
+		SocTemp:         float32(packageTemp),
+		CPUTemp:         float32(r.cpuTemp),
+		GPUTemp:         float32(r.gpuTemp),
+		DRAMReadBW:      r.jitter(st.dramReadGBs, 0.05, 0, 1000),
+		DRAMWriteBW:     r.jitter(st.dramWriteGBs, 0.05, 0, 1000),
+		DRAMBWCombined:  st.dramReadGBs + st.dramWriteGBs,
+	}
+}
+
+func (r *demoRun) fans() []FanInfo {
+	fans := make([]FanInfo, len(r.fanRPM))
+	for i, rpm := range r.fanRPM {
+		actual := 0
+		if rpm >= 1 {
+			actual = int(r.jitter(rpm, 0.01, 0, float64(r.sc.FanMaxRPM)))
+		}
+		fans[i] = FanInfo{
+			ID:        i,
+			Name:      fmt.Sprintf("Fan %d", i),
+			ActualRPM: actual,
+			MinRPM:    1200,
+			MaxRPM:    r.sc.FanMaxRPM,
+			TargetRPM: int(rpm),
+		}
+	}
+	return fans
+}
+
+// tempSensors emits SMC-style keys so the sensor grouping in the fan layout
+// and headless temperatures behaves as it does on real hardware.
+func (r *demoRun) tempSensors(st demoState) []TempSensor {
+	var sensors []TempSensor
+	add := func(key, group string, v float64) {
+		sensors = append(sensors, TempSensor{Key: key, Name: group + " " + key[2:], Value: math.Round(v*10) / 10})
+	}
+	for i := range (r.sc.ECores + 1) / 2 {
+		add(fmt.Sprintf("Te%02d", i), "CPU E-Core", r.jitter(r.cpuTemp-7, 0.02, 20, 110))
+	}
+	for i := range (r.sc.PCores + 1) / 2 {
+		add(fmt.Sprintf("Tp%02d", i), "CPU P-Core", r.jitter(r.cpuTemp, 0.02, 20, 110))
+	}
+	for i := range 4 {
+		add(fmt.Sprintf("Tg%02d", i), "GPU", r.jitter(r.gpuTemp, 0.02, 20, 110))
+	}
+	add("TPD0", "SoC Package", math.Max(r.cpuTemp, r.gpuTemp)-2)
+	add("Tm02", "Memory", r.jitter(0.55*r.cpuTemp+16, 0.01, 20, 100))
+	add("TS0P", "SSD", r.jitter(34+math.Min(st.diskMBs, 3000)/150, 0.01, 20, 90))
+	add("Ta0P", "Ambient", r.jitter(27, 0.01, 15, 45))
+	return sensors
+}
+
+func (r *demoRun) memory(st demoState) MemoryMetrics {
+	const gb = 1 << 30
+	total := r.sc.MemoryGB * gb
+	used := math.Min(r.jitter(st.memUsedGB*gb, 0.005, 0, total), total*0.98)
+	m := MemoryMetrics{
+		Total:     uint64(total),
+		Used:      uint64(used),
+		Available: uint64(total - used),
+		SwapTotal: 2 * gb,
+	}
+	if used > total*0.9 {
+		m.SwapUsed = uint64((used - total*0.9) * 3)
+	}
+	return m
+}
+
+func (r *demoRun) netDisk(st demoState) NetDiskMetrics {
+	net := r.jitter(st.netMBs*1e6, 0.2, 0, 1e11)
+	disk := r.jitter(st.diskMBs*1e3, 0.15, 0, 1e9)
+	return NetDiskMetrics{
+		InBytesPerSec:     net * 0.75,
+		OutBytesPerSec:    net * 0.25,
+		InPacketsPerSec:   net * 0.75 / 1400,
+		OutPacketsPerSec:  net * 0.25 / 900,
+		ReadKBytesPerSec:  disk * 0.7,
+		WriteKBytesPerSec: disk * 0.3,
+		ReadOpsPerSec:     disk * 0.7 / 64,
+		WriteOpsPerSec:    disk * 0.3 / 32,
+	}
+}
+
+func (r *demoRun) processes(st demoState, dt time.Duration) []RecordedProcess {
+	const gbKB = 1 << 20
+	var procs []RecordedProcess
+	pid := 400
+	for _, group := range [][]demoProcess{r.sc.Background, st.processes} {
+		for _, p := range group {
+			for n := range max(p.Count, 1) {
+				pid += 37
+				key := fmt.Sprintf("%s#%d", p.Name, n)
+				cpu := r.jitter(p.CPU, 0.1, 0, 100*float64(r.sc.ECores+r.sc.PCores))
+				r.cpuTime[key] += cpu / 100 * dt.Seconds()
+				user := p.User
+				if user == "" {
+					user = "demo"
+				}
+				state := "S"
+				if cpu > 5 {
+					state = "R"
+				}
+				rss := int64(r.jitter(p.MemGB, 0.01, 0, r.sc.MemoryGB) * gbKB)
+				procs = append(procs, RecordedProcess{
+					PID:     pid,
+					User:    user,
+					CPU:     cpu,
+					GPU:     r.jitter(p.GPU, 0.05, 0, 1000),
+					Memory:  float64(rss) / (r.sc.MemoryGB * gbKB) * 100,
+					VSZ:     rss + 400*gbKB,
+					RSS:     rss,
+					State:   state,
+					Time:    formatTime(r.cpuTime[key]),
+					Command: p.Name,
+				})
+			}
+		}
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].CPU > procs[j].CPU })
+	return procs
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadDemoScenario(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"idle", "Idle"},
+		{"xcode build", "Xcode build"},
+		{"LLM inference on GPU", "LLM inference on GPU"},
+		{"Thermal-Throttle", "Thermal throttle"},
+		{"fan_failure", "Fan failure"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			sc, err := loadDemoScenario(tt.input)
+			if err != nil {
+				t.Fatalf("loadDemoScenario(%q) error = %v", tt.input, err)
+			}
+			if sc.Name != tt.want {
+				t.Errorf("loadDemoScenario(%q).Name = %q, want %q", tt.input, sc.Name, tt.want)
+			}
+		})
+	}
+
+	if _, err := loadDemoScenario("benchmark"); err == nil {
+		t.Error("loadDemoScenario(\"benchmark\") error = nil, want error")
+	}
+}
+
+func TestDemoPhasesInherit(t *testing.T) {
+	sc, err := parseDemoScenario([]byte(`
+name: Test
+fans: 1
+phases:
+  - {name: load, duration: 2s, pcpu: 80, thermal: serious, processes: [{name: cc, cpu: 90}]}
+  - {name: cool, duration: 2s, pcpu: 5}
+  - {name: quiet, duration: 2s, processes: []}
+`))
+	if err != nil {
+		t.Fatalf("parseDemoScenario() error = %v", err)
+	}
+	cool, quiet := sc.states[1], sc.states[2]
+	if cool.thermal != thermalStateSerious || len(cool.processes) != 1 {
+		t.Errorf("cool phase = thermal %v, %d processes, want inherited serious and 1", cool.thermal, len(cool.processes))
+	}
+	if quiet.pcpu != 5 || len(quiet.processes) != 0 {
+		t.Errorf("quiet phase = pcpu %v, %d processes, want 5 and none", quiet.pcpu, len(quiet.processes))
+	}
+
+	rec := sc.render(time.Second, time.Unix(0, 0))
+	if len(rec.Samples) != 6 || rec.Duration() != 5*time.Second {
+		t.Fatalf("render() = %d samples over %v, want 6 over 5s", len(rec.Samples), rec.Duration())
+	}
+	s := rec.Samples[1]
+	if len(s.CoreUsages) != 14 || len(s.FanDetail) != 1 || len(s.TempSensors) == 0 {
+		t.Errorf("sample has %d cores, %d fans, %d sensors, want 14, 1 and some", len(s.CoreUsages), len(s.FanDetail), len(s.TempSensors))
+	}
+	if s.SocMetrics.TotalPower <= s.SocMetrics.CPUPower+s.SocMetrics.GPUPower {
+		t.Errorf("TotalPower = %v, want more than CPU %v + GPU %v", s.SocMetrics.TotalPower, s.SocMetrics.CPUPower, s.SocMetrics.GPUPower)
+	}
+}
+
+func TestReplayBackendLoops(t *testing.T) {
+	rec := &Recording{}
+	for i := range 3 {
+		rec.Samples = append(rec.Samples, testSample(int64(i)*1000, float64(i)))
+	}
+	rec.buildOffsets()
+	b := newReplayBackend(rec)
+	b.loop = true
+
+	b.seek(4 * time.Second)
+	b.togglePause()
+	m := b.SampleSoc(0)
+	if b.cursor != 1 || m.CPUPower != 1 {
+		t.Errorf("after looping seek cursor = %d (CPUPower %v), want 1", b.cursor, m.CPUPower)
+	}
+}
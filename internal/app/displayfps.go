@@ -1,3 +1,5 @@
+//go:build darwin
+
 // Copyright (c) 2024-2026 Carsen Klock under MIT License
 // displayfps.go - Go wrappers for standalone display FPS counter
 package app
@@ -14,12 +16,6 @@ void dumpDisplayFPSDiagnostics(void);
 */
 import "C"
 
-// DisplayFPSMetrics holds the current display FPS and frame interval
-type DisplayFPSMetrics struct {
-	FPS             uint32  // Current display FPS
-	FrameIntervalMs float64 // Average frame interval in milliseconds
-}
-
 // StartDisplayFPSCounter initializes the CGDisplayStream-based FPS counter.
 // Returns true if the counter was started successfully, false if CGDisplayStream
 // is unavailable (e.g., headless server with no display).
@@ -1,3 +1,5 @@
+//go:build darwin
+
 // Copyright (c) 2024-2026 Carsen Klock under MIT License
 // displayfps.m - Standalone display FPS counter via CGDisplayStream
 // Extracted from overlay.m to make FPS/frame interval metrics available
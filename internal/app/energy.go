@@ -0,0 +1,264 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// energy.go - integrates power samples into joules for counters and session totals
+package app
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// energyComponents are the rails integrated into joules, in the order
+// energyMeter stores them. gpu includes the GPU SRAM, as in the power panel;
+// package is the sum of the SoC components; system is the whole machine
+// where the SMC reports it, and never less than package.
+var energyComponents = []string{"cpu", "gpu", "ane", "dram", "package", "system"}
+
+// energyMeter accumulates joules from successive power samples. Each sample
+// is weighted by the wall-clock time since the previous one rather than the
+// nominal interval, so slow or skipped samples don't skew the totals.
+type energyMeter struct {
+	mu     sync.Mutex
+	start  time.Time
+	last   time.Time
+	soc    SocMetrics // Latest sample, for per-process attribution
+	joules [6]float64
+}
+
+var sessionEnergy = &energyMeter{}
+
+// energyMaxGap bounds the time one sample may stand for. A longer gap
+// (the Mac slept, or the process was stopped) counts only this much instead
+// of assuming the last reading held the whole time.
+func energyMaxGap() time.Duration {
+	gap := 3 * time.Duration(updateInterval) * time.Millisecond
+	if gap < 5*time.Second {
+		return 5 * time.Second
+	}
+	return gap
+}
+
+func energyWatts(m SocMetrics) [6]float64 {
+	system := m.SystemPower
+	if system < m.TotalPower {
+		system = m.TotalPower
+	}
+	return [6]float64{m.CPUPower, m.GPUPower + m.GPUSRAMPower, m.ANEPower, m.DRAMPower, m.TotalPower, system}
+}
+
+// add integrates one sample of raw SocMetrics taken at now.
+func (e *energyMeter) add(m SocMetrics, now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.soc = m
+	if e.last.IsZero() {
+		e.start, e.last = now, now
+		return
+	}
+	dt := min(now.Sub(e.last), energyMaxGap()).Seconds()
+	e.last = now
+	if dt <= 0 {
+		return
+	}
+	for i, w := range energyWatts(m) {
+		e.joules[i] += w * dt
+	}
+}
+
+// latestSample returns the most recent raw sample.
+func (e *energyMeter) latestSample() SocMetrics {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.soc
+}
+
+// total returns the joules accumulated for component i of energyComponents.
+func (e *energyMeter) total(i int) float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.joules[i]
+}
+
+// processEnergyKey identifies a process across samples. The command guards
+// against a PID being reused by an unrelated process.
+type processEnergyKey struct {
+	pid     int
+	command string
+}
+
+// processEnergyMeter estimates per-process power and accumulates it for as
+// long as each process keeps showing up in the process list.
+type processEnergyMeter struct {
+	mu     sync.Mutex
+	last   time.Time
+	joules map[processEnergyKey]float64
+}
+
+var processMeter = &processEnergyMeter{}
+
+// cpuPowerSplit divides the CPU rail between processes. Each cluster's
+// power goes by time on that cluster; the rest, or all of it when the
+// per-cluster power or placement is unknown, goes by share of CPU time.
+type cpuPowerSplit struct {
+	eWatts, pWatts, restWatts float64
+	eTotal, pTotal, cpuTotal  float64
+	pRatio                    float64 // P-core share assumed for processes without placement
+}
+
+func newCPUPowerSplit(processes []ProcessMetrics, m SocMetrics) cpuPowerSplit {
+	s := cpuPowerSplit{restWatts: m.CPUPower}
+	var eKnown, pKnown float64
+	for _, p := range processes {
+		s.cpuTotal += p.CPU
+		if p.CoreSplit {
+			eKnown += p.CPU - p.PCoreCPU
+			pKnown += p.PCoreCPU
+		}
+	}
+	if m.ECPUPower+m.PCPUPower <= 0 || eKnown+pKnown <= 0 {
+		return s
+	}
+	s.pRatio = pKnown / (eKnown + pKnown)
+	for _, p := range processes {
+		e, pc := s.place(p)
+		s.eTotal += e
+		s.pTotal += pc
+	}
+	// A cluster no process ran on has only idle power, which is shared
+	// out with the rest.
+	s.restWatts = math.Max(m.CPUPower-m.ECPUPower-m.PCPUPower, 0)
+	if s.eTotal > 0 {
+		s.eWatts = m.ECPUPower
+	} else {
+		s.restWatts += m.ECPUPower
+	}
+	if s.pTotal > 0 {
+		s.pWatts = m.PCPUPower
+	} else {
+		s.restWatts += m.PCPUPower
+	}
+	return s
+}
+
+// place splits a process's CPU percent into its E- and P-core parts.
+// Processes the kernel gave no placement for are assumed to be spread like
+// the ones it did.
+func (s cpuPowerSplit) place(p ProcessMetrics) (e, pc float64) {
+	if p.CoreSplit {
+		return p.CPU - p.PCoreCPU, p.PCoreCPU
+	}
+	return p.CPU * (1 - s.pRatio), p.CPU * s.pRatio
+}
+
+func (s cpuPowerSplit) watts(p ProcessMetrics) float64 {
+	var w float64
+	if s.cpuTotal > 0 {
+		w += s.restWatts * p.CPU / s.cpuTotal
+	}
+	e, pc := s.place(p)
+	if s.eTotal > 0 {
+		w += s.eWatts * e / s.eTotal
+	}
+	if s.pTotal > 0 {
+		w += s.pWatts * pc / s.pTotal
+	}
+	return w
+}
+
+// attribute fills in Watts and Joules for every process from the latest
+// raw sample. The CPU rail is split by cpuPowerSplit, so a second on a
+// P-core costs more than one on an E-core where the Energy Model and the
+// kernel say so, and the GPU rail, GPU SRAM included, by share of GPU
+// ms/s. Energy accrues over the real time since the previous call, capped
+// like the session totals.
+func (pe *processEnergyMeter) attribute(processes []ProcessMetrics, m SocMetrics, now time.Time) {
+	cpu := newCPUPowerSplit(processes, m)
+	gpuWatts := m.GPUPower + m.GPUSRAMPower
+	var gpuTotal float64
+	for _, p := range processes {
+		gpuTotal += p.GPU
+	}
+
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	var dt float64
+	if !pe.last.IsZero() && now.After(pe.last) {
+		dt = min(now.Sub(pe.last), energyMaxGap()).Seconds()
+	}
+	pe.last = now
+
+	// Rebuilding the map drops processes that have exited.
+	joules := make(map[processEnergyKey]float64, len(processes))
+	for i := range processes {
+		p := &processes[i]
+		p.Watts = cpu.watts(*p)
+		if gpuTotal > 0 {
+			p.Watts += gpuWatts * p.GPU / gpuTotal
+		}
+		key := processEnergyKey{p.PID, p.Command}
+		p.Joules = pe.joules[key] + p.Watts*dt
+		joules[key] = p.Joules
+	}
+	pe.joules = joules
+}
+
+// HeadlessEnergy is the energy used since mactop started.
+type HeadlessEnergy struct {
+	CPUJoules      float64 `json:"cpu_joules" yaml:"cpu_joules" xml:"CPUJoules" toon:"cpu_joules"`
+	GPUJoules      float64 `json:"gpu_joules" yaml:"gpu_joules" xml:"GPUJoules" toon:"gpu_joules"`
+	ANEJoules      float64 `json:"ane_joules" yaml:"ane_joules" xml:"ANEJoules" toon:"ane_joules"`
+	DRAMJoules     float64 `json:"dram_joules" yaml:"dram_joules" xml:"DRAMJoules" toon:"dram_joules"`
+	PackageJoules  float64 `json:"package_joules" yaml:"package_joules" xml:"PackageJoules" toon:"package_joules"`
+	SystemJoules   float64 `json:"system_joules" yaml:"system_joules" xml:"SystemJoules" toon:"system_joules"`
+	SessionSeconds float64 `json:"session_seconds" yaml:"session_seconds" xml:"SessionSeconds" toon:"session_seconds"`
+	SessionWh      float64 `json:"session_wh" yaml:"session_wh" xml:"SessionWh" toon:"session_wh"`
+	Cost           float64 `json:"cost,omitempty" yaml:"cost,omitempty" xml:"Cost,omitempty" toon:"cost"`
+}
+
+// snapshot reports the session so far. Session energy is the system rail;
+// the cost is only filled in when a price per kWh is configured.
+func (e *energyMeter) snapshot() HeadlessEnergy {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	j := e.joules
+	out := HeadlessEnergy{
+		CPUJoules:     j[0],
+		GPUJoules:     j[1],
+		ANEJoules:     j[2],
+		DRAMJoules:    j[3],
+		PackageJoules: j[4],
+		SystemJoules:  j[5],
+		SessionWh:     j[5] / 3600,
+	}
+	if !e.last.IsZero() {
+		out.SessionSeconds = e.last.Sub(e.start).Seconds()
+	}
+	if price := energyPricePerKWh(); price > 0 {
+		out.Cost = out.SessionWh / 1000 * price
+	}
+	return out
+}
+
+// energyPricePerKWh prefers --price-per-kwh over price_per_kwh in the config.
+func energyPricePerKWh() float64 {
+	if pricePerKWh > 0 {
+		return pricePerKWh
+	}
+	return currentConfig.PricePerKWh
+}
+
+// energyCounters exposes the session totals as mactop_energy_joules_total,
+// read straight from sessionEnergy whenever the registry is gathered.
+func energyCounters() []prometheus.Collector {
+	var counters []prometheus.Collector
+	for i, component := range energyComponents {
+		counters = append(counters, prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name:        "mactop_energy_joules_total",
+			Help:        "Energy used since mactop started, in joules",
+			ConstLabels: prometheus.Labels{"component": component},
+		}, func() float64 { return sessionEnergy.total(i) }))
+	}
+	return counters
+}
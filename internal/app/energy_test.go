@@ -0,0 +1,111 @@
+package app
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEnergyMeter(t *testing.T) {
+	prevPrice, prevInterval := pricePerKWh, updateInterval
+	t.Cleanup(func() { pricePerKWh, updateInterval = prevPrice, prevInterval })
+	pricePerKWh, updateInterval = 0.30, 1000
+
+	e := &energyMeter{}
+	t0 := time.Unix(1000, 0)
+	m := SocMetrics{CPUPower: 4, GPUPower: 2, GPUSRAMPower: 1, TotalPower: 9, SystemPower: 30}
+	e.add(m, t0)
+	e.add(m, t0.Add(2*time.Second))
+	// The Mac slept for an hour: only energyMaxGap (5s) is counted.
+	e.add(m, t0.Add(time.Hour))
+
+	got := e.snapshot()
+	for name, tc := range map[string]struct{ got, want float64 }{
+		"cpu":     {got.CPUJoules, 4 * 7},
+		"gpu":     {got.GPUJoules, 3 * 7},
+		"package": {got.PackageJoules, 9 * 7},
+		"system":  {got.SystemJoules, 30 * 7},
+		"wh":      {got.SessionWh, 30 * 7 / 3600.0},
+		"cost":    {got.Cost, 30 * 7 / 3600.0 / 1000 * 0.30},
+	} {
+		if math.Abs(tc.got-tc.want) > 1e-9 {
+			t.Errorf("%s = %v, want %v", name, tc.got, tc.want)
+		}
+	}
+	if got.SessionSeconds != time.Hour.Seconds() {
+		t.Errorf("SessionSeconds = %v, want the wall-clock session length", got.SessionSeconds)
+	}
+
+	// The system rail never reads below the SoC package.
+	e = &energyMeter{}
+	e.add(SocMetrics{TotalPower: 12}, t0)
+	e.add(SocMetrics{TotalPower: 12}, t0.Add(time.Second))
+	if j := e.total(5); j != 12 {
+		t.Errorf("system joules without an SMC reading = %v, want the package's 12", j)
+	}
+}
+
+func TestProcessEnergyAttribute(t *testing.T) {
+	prevInterval := updateInterval
+	t.Cleanup(func() { updateInterval = prevInterval })
+	updateInterval = 1000
+
+	pe := &processEnergyMeter{}
+	// The GPU rail includes the GPU SRAM.
+	m := SocMetrics{CPUPower: 10, GPUPower: 3, GPUSRAMPower: 1}
+	t0 := time.Unix(1000, 0)
+	sample := func() []ProcessMetrics {
+		return []ProcessMetrics{
+			{PID: 1, Command: "build", CPU: 300},
+			{PID: 2, Command: "render", CPU: 100, GPU: 500},
+		}
+	}
+
+	procs := sample()
+	pe.attribute(procs, m, t0)
+	if procs[0].Watts != 7.5 || procs[1].Watts != 6.5 {
+		t.Errorf("watts = %v, %v, want 7.5 and 6.5", procs[0].Watts, procs[1].Watts)
+	}
+	if procs[0].Joules != 0 {
+		t.Errorf("joules after the first sample = %v, want 0", procs[0].Joules)
+	}
+
+	procs = sample()
+	pe.attribute(procs, m, t0.Add(2*time.Second))
+	if procs[0].Joules != 15 || procs[1].Joules != 13 {
+		t.Errorf("joules = %v, %v, want 15 and 13", procs[0].Joules, procs[1].Joules)
+	}
+
+	// PID 2 now runs something else: its energy starts from zero.
+	procs = sample()
+	procs[1].Command = "other"
+	pe.attribute(procs, m, t0.Add(3*time.Second))
+	if procs[0].Joules != 22.5 || procs[1].Joules != 6.5 {
+		t.Errorf("joules = %v, %v, want 22.5 and 6.5", procs[0].Joules, procs[1].Joules)
+	}
+}
+
+func TestProcessEnergyClusters(t *testing.T) {
+	// 2 W of the CPU rail is on no cluster's channel.
+	m := SocMetrics{CPUPower: 12, ECPUPower: 2, PCPUPower: 8}
+	procs := []ProcessMetrics{
+		{PID: 1, Command: "p-bound", CPU: 100, PCoreCPU: 100, CoreSplit: true},
+		{PID: 2, Command: "e-bound", CPU: 100, CoreSplit: true},
+		{PID: 3, Command: "unplaced", CPU: 100},
+	}
+	(&processEnergyMeter{}).attribute(procs, m, time.Unix(1000, 0))
+	// The unplaced process is taken to be spread like the others, half on
+	// each cluster.
+	for i, want := range []float64{6, 2, 4} {
+		if math.Abs(procs[i].Watts-want) > 1e-9 {
+			t.Errorf("%s watts = %v, want %v", procs[i].Command, procs[i].Watts, want)
+		}
+	}
+
+	// Nothing ran on the E-cluster, so its power is shared like the rest.
+	procs = procs[:1]
+	(&processEnergyMeter{}).attribute(procs, m, time.Unix(1000, 0))
+	if math.Abs(procs[0].Watts-12) > 1e-9 {
+		t.Errorf("watts = %v, want all 12", procs[0].Watts)
+	}
+}
@@ -62,6 +62,12 @@ func startBackgroundUpdates(done chan struct{}) {
 				// Update info UI once per cycle instead of multiple times
 				renderMutex.Lock()
 				updateInfoUI()
+				if _, ok := activeBackend.(statusReporter); ok {
+					updateIntervalText()
+				}
+				if activeAlerts != nil {
+					updateAlertBanner()
+				}
 				renderMutex.Unlock()
 				renderUI()
 
@@ -177,19 +183,7 @@ func handleKeyboardEvent(e ui.Event, done chan struct{}) {
 	renderMutex.Lock()
 
 	if showHelp {
-		switch key {
-		case "j", "<Down>":
-			helpScrollOffset++
-			updateHelpText()
-			drawScreen(GetCachedTerminalDimensions())
-			renderMutex.Unlock()
-			return
-		case "k", "<Up>":
-			if helpScrollOffset > 0 {
-				helpScrollOffset--
-			}
-			updateHelpText()
-			drawScreen(GetCachedTerminalDimensions())
+		if handleHelpScrollKeys(key) {
 			renderMutex.Unlock()
 			return
 		}
@@ -218,6 +212,8 @@ func handleKeyboardEvent(e ui.Event, done chan struct{}) {
 		}
 	case "a", "A", "0", "9", "R":
 		handleFanControlKeys(key)
+	case "s", ",", ".", "[", "]":
+		handleReplayKeys(key)
 	case "j", "<Down>":
 		handleInfoFanScroll(1)
 	case "k", "<Up>":
@@ -225,6 +221,24 @@ func handleKeyboardEvent(e ui.Event, done chan struct{}) {
 	}
 }
 
+// handleHelpScrollKeys scrolls the help text and reports whether key was consumed.
+// Caller must hold renderMutex.
+func handleHelpScrollKeys(key string) bool {
+	switch key {
+	case "j", "<Down>":
+		helpScrollOffset++
+	case "k", "<Up>":
+		if helpScrollOffset > 0 {
+			helpScrollOffset--
+		}
+	default:
+		return false
+	}
+	updateHelpText()
+	drawScreen(GetCachedTerminalDimensions())
+	return true
+}
+
 func handleFanControlKeys(key string) bool {
 	if !fanControl || currentConfig.DefaultLayout != LayoutFan {
 		return false
@@ -247,7 +261,7 @@ func handleFanControlKeys(key string) bool {
 }
 
 func handleInfoFanScroll(direction int) {
-	if currentConfig.DefaultLayout != LayoutInfo && currentConfig.DefaultLayout != LayoutFan {
+	if currentConfig.DefaultLayout != LayoutInfo && currentConfig.DefaultLayout != LayoutFan && currentConfig.DefaultLayout != LayoutCluster {
 		return
 	}
 	renderMutex.Lock()
@@ -280,8 +294,8 @@ func handleGenericMouseEvent(e ui.Event) {
 		return
 	}
 
-	// Handle mouse wheel scrolling in Info or Fan layout
-	if currentConfig.DefaultLayout == LayoutInfo || currentConfig.DefaultLayout == LayoutFan {
+	// Handle mouse wheel scrolling in Info, Fan or Cluster layout
+	if currentConfig.DefaultLayout == LayoutInfo || currentConfig.DefaultLayout == LayoutFan || currentConfig.DefaultLayout == LayoutCluster {
 		switch e.ID {
 		case "<MouseWheelUp>":
 			if infoScrollOffset > 0 {
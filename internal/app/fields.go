@@ -0,0 +1,355 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// fields.go - --fields: trims headless output to selected paths in every format
+package app
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// fieldSegment matches one path element: a JSON name with an optional
+// [i] index or [lo:hi] range.
+var fieldSegment = regexp.MustCompile(`^([A-Za-z0-9_]+)(?:\[([0-9]*)(:?)([0-9]*)\])?$`)
+
+// fieldSel is one element of a --fields path, merged with every other path
+// through the same element. hi is -1 for an open range.
+type fieldSel struct {
+	name     string
+	ranged   bool
+	index    bool
+	lo, hi   int
+	whole    bool
+	children []*fieldSel
+}
+
+// fieldSelection is a compiled --fields list. The selected fields form a
+// struct type built at startup that keeps the original field order and
+// json, yaml, xml and toon tags, so every encoder trims the same way.
+type fieldSelection struct {
+	paths []string
+	sels  [][]*fieldSel // Per path, for CSV cells
+	typ   reflect.Type
+	fill  func(dst, src reflect.Value)
+}
+
+var activeFields *fieldSelection
+
+// setupHeadlessFields compiles --fields before sampling starts.
+func setupHeadlessFields() {
+	if headlessFields == "" {
+		return
+	}
+	f, err := newFieldSelection(headlessFields)
+	if err != nil {
+		stderrLogger.Fatalf("invalid --fields: %v", err)
+	}
+	activeFields = f
+}
+
+func newFieldSelection(list string) (*fieldSelection, error) {
+	f := &fieldSelection{}
+	var root []*fieldSel
+	for p := range strings.SplitSeq(list, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		chain, err := parseFieldPath(p)
+		if err != nil {
+			return nil, err
+		}
+		// Check each path on its own too: one that selects a whole object
+		// would otherwise hide a typo in another path below it.
+		alone, _ := mergeFieldPath(nil, chain)
+		if _, _, err := compileFieldStruct(reflect.TypeFor[HeadlessOutput](), alone); err != nil {
+			return nil, fmt.Errorf("%q: %w", p, err)
+		}
+		if root, err = mergeFieldPath(root, chain); err != nil {
+			return nil, err
+		}
+		f.paths = append(f.paths, p)
+		f.sels = append(f.sels, chain)
+	}
+	if len(root) == 0 {
+		return nil, fmt.Errorf("no fields given")
+	}
+
+	typ, fill, err := compileFieldStruct(reflect.TypeFor[HeadlessOutput](), root)
+	if err != nil {
+		return nil, err
+	}
+	// Keep the root element name encoding/xml gives HeadlessOutput.
+	xmlName := reflect.StructField{
+		Name: "XMLName",
+		Type: reflect.TypeFor[xml.Name](),
+		Tag:  `xml:"HeadlessOutput" json:"-" yaml:"-" toon:"-"`,
+	}
+	fields := []reflect.StructField{xmlName}
+	for i := range typ.NumField() {
+		fields = append(fields, typ.Field(i))
+	}
+	f.typ = reflect.StructOf(fields)
+	f.fill = func(dst, src reflect.Value) {
+		trimmed := reflect.New(typ).Elem()
+		fill(trimmed, src)
+		for i := range typ.NumField() {
+			dst.Field(i + 1).Set(trimmed.Field(i))
+		}
+	}
+	return f, nil
+}
+
+// parseFieldPath turns "processes[0:5].command" into a chain of selections,
+// one per path element.
+func parseFieldPath(path string) ([]*fieldSel, error) {
+	var chain []*fieldSel
+	for seg := range strings.SplitSeq(path, ".") {
+		m := fieldSegment.FindStringSubmatch(seg)
+		if m == nil {
+			return nil, fmt.Errorf("%q: invalid path element %q", path, seg)
+		}
+		s := &fieldSel{name: m[1], hi: -1}
+		switch {
+		case m[3] == ":":
+			s.ranged = true
+			s.lo, _ = strconv.Atoi(m[2])
+			if m[4] != "" {
+				s.hi, _ = strconv.Atoi(m[4])
+			}
+		case m[2] != "":
+			s.ranged, s.index = true, true
+			s.lo, _ = strconv.Atoi(m[2])
+			s.hi = s.lo + 1
+		case strings.Contains(seg, "["):
+			return nil, fmt.Errorf("%q: empty index in %q", path, seg)
+		}
+		if s.hi >= 0 && s.hi < s.lo {
+			return nil, fmt.Errorf("%q: range ends before it starts in %q", path, seg)
+		}
+		chain = append(chain, s)
+	}
+	chain[len(chain)-1].whole = true
+	return chain, nil
+}
+
+// mergeFieldPath adds chain to the selection tree at level. Paths through
+// the same list keep the span covering all of their ranges.
+func mergeFieldPath(level []*fieldSel, chain []*fieldSel) ([]*fieldSel, error) {
+	head := chain[0]
+	for _, s := range level {
+		if s.name != head.name {
+			continue
+		}
+		if s.ranged != head.ranged {
+			return nil, fmt.Errorf("%s is selected both as a list and as a whole", head.name)
+		}
+		s.lo = min(s.lo, head.lo)
+		if s.hi >= 0 && (head.hi < 0 || head.hi > s.hi) {
+			s.hi = head.hi
+		}
+		s.whole = s.whole || head.whole
+		if len(chain) > 1 {
+			var err error
+			if s.children, err = mergeFieldPath(s.children, chain[1:]); err != nil {
+				return nil, err
+			}
+		}
+		return level, nil
+	}
+	// Copy, so the tree doesn't share nodes with the chain kept for CSV.
+	node := *head
+	if len(chain) > 1 {
+		var err error
+		if node.children, err = mergeFieldPath(nil, chain[1:]); err != nil {
+			return nil, err
+		}
+	}
+	return append(level, &node), nil
+}
+
+// jsonFieldIndex finds the field of struct type t whose JSON name is name.
+func jsonFieldIndex(t reflect.Type, name string) (int, bool) {
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if tag == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// compileFieldStruct builds the trimmed type for struct type t and a fill
+// func that copies the selected fields of a t value into it. Fields keep
+// their order in t, whatever the order of the paths.
+func compileFieldStruct(t reflect.Type, sels []*fieldSel) (reflect.Type, func(dst, src reflect.Value), error) {
+	indexes := make(map[*fieldSel]int, len(sels))
+	for _, s := range sels {
+		idx, ok := jsonFieldIndex(t, s.name)
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown field %q", s.name)
+		}
+		indexes[s] = idx
+	}
+	sorted := slices.Clone(sels)
+	slices.SortFunc(sorted, func(a, b *fieldSel) int { return indexes[a] - indexes[b] })
+
+	var fields []reflect.StructField
+	var fills []func(dst, src reflect.Value)
+	for _, s := range sorted {
+		idx := indexes[s]
+		sf := t.Field(idx)
+		typ, fill, err := compileFieldValue(sf.Type, s)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", s.name, err)
+		}
+		out := len(fields)
+		fields = append(fields, reflect.StructField{Name: sf.Name, Type: typ, Tag: sf.Tag})
+		fills = append(fills, func(dst, src reflect.Value) { fill(dst.Field(out), src.Field(idx)) })
+	}
+	return reflect.StructOf(fields), func(dst, src reflect.Value) {
+		for _, fill := range fills {
+			fill(dst, src)
+		}
+	}, nil
+}
+
+// compileFieldValue handles one selected field of type t: the whole value,
+// or its selected children through pointers and lists.
+func compileFieldValue(t reflect.Type, s *fieldSel) (reflect.Type, func(dst, src reflect.Value), error) {
+	if s.ranged {
+		if t.Kind() != reflect.Slice {
+			return nil, nil, fmt.Errorf("not a list")
+		}
+		elemType, elemFill, err := compileFieldElem(t.Elem(), s)
+		if err != nil {
+			return nil, nil, err
+		}
+		sliceType := reflect.SliceOf(elemType)
+		return sliceType, func(dst, src reflect.Value) {
+			lo, hi := min(s.lo, src.Len()), src.Len()
+			if s.hi >= 0 {
+				hi = min(s.hi, hi)
+			}
+			out := reflect.MakeSlice(sliceType, hi-lo, hi-lo)
+			for i := lo; i < hi; i++ {
+				elemFill(out.Index(i-lo), src.Index(i))
+			}
+			dst.Set(out)
+		}, nil
+	}
+	return compileFieldElem(t, s)
+}
+
+func compileFieldElem(t reflect.Type, s *fieldSel) (reflect.Type, func(dst, src reflect.Value), error) {
+	if s.whole || len(s.children) == 0 {
+		return t, func(dst, src reflect.Value) { dst.Set(src) }, nil
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		inner, fill, err := compileFieldElem(t.Elem(), s)
+		if err != nil {
+			return nil, nil, err
+		}
+		return reflect.PointerTo(inner), func(dst, src reflect.Value) {
+			if src.IsNil() {
+				return
+			}
+			p := reflect.New(inner)
+			fill(p.Elem(), src.Elem())
+			dst.Set(p)
+		}, nil
+	case reflect.Slice:
+		return nil, nil, fmt.Errorf("is a list; select elements with %s[0:5]", s.name)
+	case reflect.Struct:
+		return compileFieldStruct(t, s.children)
+	}
+	return nil, nil, fmt.Errorf("has no fields")
+}
+
+// trim returns the selected fields of output, ready for any encoder.
+func (f *fieldSelection) trim(output HeadlessOutput) any {
+	v := reflect.New(f.typ)
+	f.fill(v.Elem(), reflect.ValueOf(output))
+	return v.Interface()
+}
+
+// csvHeader is one column per --fields path.
+func (f *fieldSelection) csvHeader() []string {
+	return f.paths
+}
+
+// csvRecord formats each path's value as one cell: scalars as they are, and
+// objects and lists as JSON. An [i] index picks that element rather than a
+// one-element list.
+func (f *fieldSelection) csvRecord(output HeadlessOutput) []string {
+	record := make([]string, len(f.sels))
+	for i, chain := range f.sels {
+		record[i] = formatFieldCell(fieldCell(reflect.ValueOf(output), chain))
+	}
+	return record
+}
+
+func fieldCell(v reflect.Value, chain []*fieldSel) any {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if len(chain) == 0 {
+		return v.Interface()
+	}
+	s := chain[0]
+	idx, ok := jsonFieldIndex(v.Type(), s.name)
+	if !ok {
+		return nil
+	}
+	fv := v.Field(idx)
+	if !s.ranged {
+		return fieldCell(fv, chain[1:])
+	}
+	if s.index {
+		if s.lo >= fv.Len() {
+			return nil
+		}
+		return fieldCell(fv.Index(s.lo), chain[1:])
+	}
+	lo, hi := min(s.lo, fv.Len()), fv.Len()
+	if s.hi >= 0 {
+		hi = min(s.hi, hi)
+	}
+	cells := make([]any, 0, hi-lo)
+	for i := lo; i < hi; i++ {
+		cells = append(cells, fieldCell(fv.Index(i), chain[1:]))
+	}
+	return cells
+}
+
+func formatFieldCell(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return fmt.Sprintf("%.2f", x)
+	case float32:
+		return fmt.Sprintf("%.2f", x)
+	case bool:
+		return strconv.FormatBool(x)
+	case int, int32, int64, uint32, uint64:
+		return fmt.Sprintf("%d", x)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
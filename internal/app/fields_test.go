@@ -0,0 +1,91 @@
+package app
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	toon "github.com/toon-format/toon-go"
+	"gopkg.in/yaml.v3"
+)
+
+func fieldsTestOutput() HeadlessOutput {
+	output := HeadlessOutput{GPUUsage: 42.5, CPUUsage: 10}
+	output.SocMetrics.CPUPower = 3.25
+	output.SocMetrics.GPUTemp = 51
+	for _, cmd := range []string{"ollama", "Xcode", "Safari"} {
+		output.Processes = append(output.Processes, HeadlessProcess{PID: len(cmd), Command: cmd, CPU: 1})
+	}
+	return output
+}
+
+func TestFieldSelection(t *testing.T) {
+	f, err := newFieldSelection("soc_metrics.cpu_power, gpu_usage,processes[0:2].command,soc_metrics.gpu_temp,thunderbolt_info,processes[0:2].pid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := fieldsTestOutput()
+
+	data, err := json.Marshal(f.trim(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Struct order, not the order given.
+	want := `{"soc_metrics":{"cpu_power":3.25,"gpu_temp":51},"gpu_usage":42.5,` +
+		`"processes":[{"pid":6,"command":"ollama"},{"pid":5,"command":"Xcode"}],"thunderbolt_info":null}`
+	if string(data) != want {
+		t.Errorf("json:\n got %s\nwant %s", data, want)
+	}
+
+	data, err = yaml.Marshal(f.trim(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "\ngpu_usage: 42.5\n") || strings.Contains(string(data), "cpu_usage") {
+		t.Errorf("yaml:\n%s", data)
+	}
+
+	data, err = xml.Marshal(f.trim(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "<HeadlessOutput><SocMetrics><CPUPower>3.25</CPUPower>") ||
+		!strings.Contains(string(data), "<Processes><PID>6</PID><Command>ollama</Command></Processes>") {
+		t.Errorf("xml:\n%s", data)
+	}
+
+	if _, err := toon.Marshal(f.trim(output)); err != nil {
+		t.Errorf("toon: %v", err)
+	}
+}
+
+func TestFieldSelectionCSV(t *testing.T) {
+	f, err := newFieldSelection("gpu_usage,processes[1].command,processes[0:2].command,soc_metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := f.csvRecord(fieldsTestOutput())
+	if strings.Join(f.csvHeader(), ",") != "gpu_usage,processes[1].command,processes[0:2].command,soc_metrics" {
+		t.Errorf("csv header = %v", f.csvHeader())
+	}
+	if record[0] != "42.50" || record[1] != "Xcode" || record[2] != `["ollama","Xcode"]` || !strings.HasPrefix(record[3], `{"cpu_power":3.25`) {
+		t.Errorf("csv record = %q", record)
+	}
+}
+
+func TestFieldSelectionErrors(t *testing.T) {
+	for _, list := range []string{
+		"",
+		"no_such_field",
+		"soc_metrics,soc_metrics.bogus",
+		"gpu_usage[0]",
+		"processes.command",
+		"processes,processes[0:3].command",
+		"processes[3:1]",
+	} {
+		if _, err := newFieldSelection(list); err == nil {
+			t.Errorf("newFieldSelection(%q) succeeded, want an error", list)
+		}
+	}
+}
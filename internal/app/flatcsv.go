@@ -0,0 +1,314 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// flatcsv.go - --flat: CSV with a real column for every nested value instead of JSON blobs
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// flatCSVLayout is the --flat column set. It is fixed from the first sample,
+// before the header is printed: each temperature group, fan, volume,
+// Ethernet link and Thunderbolt bus seen then gets its own columns, matched
+// by name (or ID, or position for buses) in later samples. Names that turn
+// into the same column name, or that repeat, get _2, _3 suffixes in the
+// order they appear, and the n-th of a repeated name keeps matching the n-th
+// element of that name. A later sample
+// that lacks one leaves its cells empty, and anything that appears later is
+// left out, so every row has the header's columns.
+type flatCSVLayout struct {
+	cores   int
+	columns []flatCSVColumn // After the lead and Core_N columns
+}
+
+// flatCSVColumn is a --flat column and how its cell is read from a sample.
+type flatCSVColumn struct {
+	csvColumn
+	cell func(HeadlessOutput) string
+}
+
+var activeFlatCSV *flatCSVLayout
+
+// csvCellFormats are how cells of each column kind are printed, the same as
+// the default CSV columns.
+var csvCellFormats = map[string]string{
+	"string":  "%s",
+	"integer": "%d",
+	"number":  "%.2f",
+	"boolean": "%t",
+}
+
+// setupHeadlessFlat checks --flat before sampling starts. The layout itself
+// waits for the first sample.
+func setupHeadlessFlat(format string) {
+	if flatProcesses < 0 || flatProcesses > headlessProcessLimit {
+		stderrLogger.Fatalf("invalid --flat-processes: %d, use 0 to %d", flatProcesses, headlessProcessLimit)
+	}
+	if !flatCSV {
+		return
+	}
+	if format != "csv" || headlessFields != "" || aggregatePeriod != "" {
+		stderrLogger.Fatalf("invalid --flat: needs --format csv, and can't be combined with --fields or --aggregate")
+	}
+}
+
+// newFlatCSVLayout fixes the columns from the first sample, with the top
+// processes processes.
+func newFlatCSVLayout(first HeadlessOutput, processes int) *flatCSVLayout {
+	l := &flatCSVLayout{cores: first.SystemInfo.CoreCount}
+	l.columns = append(l.columns, flatTempColumns(first.Temperatures)...)
+	l.columns = append(l.columns, flatFanColumns(first.Fans)...)
+	l.columns = append(l.columns, flatVolumeColumns(first.Volumes)...)
+	l.columns = append(l.columns, flatLinkColumns(first.NetworkLinks.Ethernet)...)
+	l.columns = append(l.columns, flatThunderboltColumns(first.ThunderboltInfo)...)
+	l.columns = append(l.columns, flatProcessColumns(processes)...)
+	return l
+}
+
+// allColumns is the whole layout: the default lead and Core_N columns, then
+// the flattened ones in place of the JSON blobs.
+func (l *flatCSVLayout) allColumns() []csvColumn {
+	columns := headlessCSVColumns(l.cores)
+	columns = columns[:len(columns)-len(headlessCSVTrailColumns)]
+	for _, c := range l.columns {
+		columns = append(columns, c.csvColumn)
+	}
+	return columns
+}
+
+func (l *flatCSVLayout) csvHeader() []string {
+	var header []string
+	for _, c := range l.allColumns() {
+		header = append(header, c.name)
+	}
+	return header
+}
+
+func (l *flatCSVLayout) csvRecord(output HeadlessOutput) []string {
+	record := append(headlessCSVLeadRecord(output), headlessCSVCoreRecord(output, l.cores)...)
+	for _, c := range l.columns {
+		record = append(record, c.cell(output))
+	}
+	return record
+}
+
+// flatColumn is a column whose cell is what get finds in a sample, printed
+// as kind, or empty when get finds nothing.
+func flatColumn[T any](name, source, kind string, get func(HeadlessOutput) (T, bool)) flatCSVColumn {
+	return flatCSVColumn{csvColumn{name, source, kind}, func(o HeadlessOutput) string {
+		v, ok := get(o)
+		if !ok {
+			return ""
+		}
+		return fmt.Sprintf(csvCellFormats[kind], v)
+	}}
+}
+
+// flatItem finds the nth element, from 0, of a sample's list that match
+// picks.
+func flatItem[T any](list func(HeadlessOutput) []T, match func(T) bool, nth int) func(HeadlessOutput) (T, bool) {
+	return func(o HeadlessOutput) (T, bool) {
+		seen := 0
+		for _, item := range list(o) {
+			if !match(item) {
+				continue
+			}
+			if seen == nth {
+				return item, true
+			}
+			seen++
+		}
+		var zero T
+		return zero, false
+	}
+}
+
+// flatField reads one field of the element find finds.
+func flatField[T, V any](find func(HeadlessOutput) (T, bool), field func(T) V) func(HeadlessOutput) (V, bool) {
+	return func(o HeadlessOutput) (V, bool) {
+		item, ok := find(o)
+		return field(item), ok
+	}
+}
+
+var csvNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// csvColumnName turns a sensor group, fan, volume or interface name into
+// something usable in a column name: "Macintosh HD" becomes Macintosh_HD.
+func csvColumnName(s string) string {
+	if name := strings.Trim(csvNameUnsafe.ReplaceAllString(s, "_"), "_"); name != "" {
+		return name
+	}
+	return "Unnamed"
+}
+
+// flatCSVNames hands out the column name prefixes of one kind of element,
+// and counts how often each element name has been seen.
+type flatCSVNames struct {
+	used map[string]bool
+	seen map[string]int
+}
+
+func newFlatCSVNames() *flatCSVNames {
+	return &flatCSVNames{used: make(map[string]bool), seen: make(map[string]int)}
+}
+
+// next returns the prefix for the next element called name, kind_<name>
+// with a _2, _3 suffix if that is taken, and which element of that name it
+// is, counting from 0.
+func (n *flatCSVNames) next(kind, name string) (string, int) {
+	base := kind + "_" + csvColumnName(name)
+	prefix := base
+	for i := 2; n.used[prefix]; i++ {
+		prefix = fmt.Sprintf("%s_%d", base, i)
+	}
+	n.used[prefix] = true
+	nth := n.seen[name]
+	n.seen[name]++
+	return prefix, nth
+}
+
+func flatTempColumns(groups []HeadlessTempGroup) []flatCSVColumn {
+	var columns []flatCSVColumn
+	names := newFlatCSVNames()
+	for _, g := range groups {
+		group := g.Group
+		prefix, nth := names.next("Temp", group)
+		find := flatItem(func(o HeadlessOutput) []HeadlessTempGroup { return o.Temperatures },
+			func(t HeadlessTempGroup) bool { return t.Group == group }, nth)
+		source := "temperatures[group=" + group + "]."
+		columns = append(columns,
+			flatColumn(prefix+"_Avg_C", source+"avg_celsius", "number", flatField(find, func(t HeadlessTempGroup) float64 { return t.Avg })),
+			flatColumn(prefix+"_Min_C", source+"min_celsius", "number", flatField(find, func(t HeadlessTempGroup) float64 { return t.Min })),
+			flatColumn(prefix+"_Max_C", source+"max_celsius", "number", flatField(find, func(t HeadlessTempGroup) float64 { return t.Max })),
+		)
+	}
+	return columns
+}
+
+func flatFanColumns(fans []HeadlessFan) []flatCSVColumn {
+	var columns []flatCSVColumn
+	for _, f := range fans {
+		id := f.ID
+		find := flatItem(func(o HeadlessOutput) []HeadlessFan { return o.Fans },
+			func(f HeadlessFan) bool { return f.ID == id }, 0)
+		prefix := fmt.Sprintf("Fan_%d", id)
+		source := fmt.Sprintf("fans[id=%d].", id)
+		columns = append(columns,
+			flatColumn(prefix+"_RPM", source+"rpm", "integer", flatField(find, func(f HeadlessFan) int { return f.RPM })),
+			flatColumn(prefix+"_Target_RPM", source+"target_rpm", "integer", flatField(find, func(f HeadlessFan) int { return f.TargetRPM })),
+			flatColumn(prefix+"_Mode", source+"mode", "string", flatField(find, func(f HeadlessFan) string { return f.Mode })),
+		)
+	}
+	return columns
+}
+
+func flatVolumeColumns(volumes []HeadlessVolume) []flatCSVColumn {
+	var columns []flatCSVColumn
+	names := newFlatCSVNames()
+	for _, v := range volumes {
+		name := v.Name
+		prefix, nth := names.next("Volume", name)
+		find := flatItem(func(o HeadlessOutput) []HeadlessVolume { return o.Volumes },
+			func(v HeadlessVolume) bool { return v.Name == name }, nth)
+		source := "volumes[name=" + name + "]."
+		columns = append(columns,
+			flatColumn(prefix+"_Total_GB", source+"total_gb", "number", flatField(find, func(v HeadlessVolume) float64 { return v.TotalGB })),
+			flatColumn(prefix+"_Used_GB", source+"used_gb", "number", flatField(find, func(v HeadlessVolume) float64 { return v.UsedGB })),
+			flatColumn(prefix+"_Used_Percent", source+"used_percent", "number", flatField(find, func(v HeadlessVolume) float64 { return v.UsedPct })),
+		)
+	}
+	return columns
+}
+
+// flatLinkColumns has columns for each Ethernet interface of the first
+// sample, and always for Wi-Fi, which a Mac has at most one of and which
+// may only connect later.
+func flatLinkColumns(ethernet []HeadlessEthernetLink) []flatCSVColumn {
+	var columns []flatCSVColumn
+	names := newFlatCSVNames()
+	for _, e := range ethernet {
+		name := e.Name
+		prefix, nth := names.next("Eth", name)
+		find := flatItem(func(o HeadlessOutput) []HeadlessEthernetLink { return o.NetworkLinks.Ethernet },
+			func(e HeadlessEthernetLink) bool { return e.Name == name }, nth)
+		source := "network_links.ethernet[name=" + name + "]."
+		columns = append(columns,
+			flatColumn(prefix+"_Link_Up", source+"link_up", "boolean", flatField(find, func(e HeadlessEthernetLink) bool { return e.LinkUp })),
+			flatColumn(prefix+"_Speed_Mbps", source+"speed_mbps", "integer", flatField(find, func(e HeadlessEthernetLink) uint64 { return e.SpeedMbps })),
+		)
+	}
+	wifi := func(o HeadlessOutput) (HeadlessWiFiLink, bool) {
+		if o.NetworkLinks.WiFi == nil {
+			return HeadlessWiFiLink{}, false
+		}
+		return *o.NetworkLinks.WiFi, true
+	}
+	return append(columns,
+		flatColumn("WiFi_Connected", "network_links.wifi.connected", "boolean", flatField(wifi, func(w HeadlessWiFiLink) bool { return w.Connected })),
+		flatColumn("WiFi_Tx_Rate_Mbps", "network_links.wifi.tx_rate_mbps", "integer", flatField(wifi, func(w HeadlessWiFiLink) int { return w.TxRateMbps })),
+		flatColumn("WiFi_PHY_Mode", "network_links.wifi.phy_mode", "string", flatField(wifi, func(w HeadlessWiFiLink) string { return w.PHYMode })),
+		flatColumn("WiFi_Generation", "network_links.wifi.generation", "string", flatField(wifi, func(w HeadlessWiFiLink) string { return w.Generation })),
+	)
+}
+
+// flatThunderboltColumns has columns per bus, by position: buses are read
+// once at startup, so they don't change during a run.
+func flatThunderboltColumns(info *ThunderboltOutput) []flatCSVColumn {
+	if info == nil {
+		return nil
+	}
+	var columns []flatCSVColumn
+	for i := range info.Buses {
+		find := func(o HeadlessOutput) (ThunderboltBusOutput, bool) {
+			if o.ThunderboltInfo == nil || i >= len(o.ThunderboltInfo.Buses) {
+				return ThunderboltBusOutput{}, false
+			}
+			return o.ThunderboltInfo.Buses[i], true
+		}
+		stats := func(o HeadlessOutput) (ThunderboltNetStats, bool) {
+			bus, ok := find(o)
+			if !ok || bus.NetworkStats == nil {
+				return ThunderboltNetStats{}, false
+			}
+			return *bus.NetworkStats, true
+		}
+		prefix := fmt.Sprintf("TB_Bus_%d", i)
+		source := fmt.Sprintf("thunderbolt_info.buses[%d].", i)
+		columns = append(columns,
+			flatColumn(prefix+"_Status", source+"status", "string", flatField(find, func(b ThunderboltBusOutput) string { return b.Status })),
+			flatColumn(prefix+"_Speed", source+"speed", "string", flatField(find, func(b ThunderboltBusOutput) string { return b.Speed })),
+			flatColumn(prefix+"_Device_Count", source+"devices", "integer", flatField(find, func(b ThunderboltBusOutput) int { return len(b.Devices) })),
+			flatColumn(prefix+"_Net_In_Bytes", source+"network_stats.bytes_in_per_sec", "number", flatField(stats, func(s ThunderboltNetStats) float64 { return s.BytesInPerSec })),
+			flatColumn(prefix+"_Net_Out_Bytes", source+"network_stats.bytes_out_per_sec", "number", flatField(stats, func(s ThunderboltNetStats) float64 { return s.BytesOutPerSec })),
+		)
+	}
+	return columns
+}
+
+// flatProcessColumns has columns for the top n processes by CPU, by rank.
+func flatProcessColumns(n int) []flatCSVColumn {
+	var columns []flatCSVColumn
+	for i := range n {
+		find := func(o HeadlessOutput) (HeadlessProcess, bool) {
+			if i >= len(o.Processes) {
+				return HeadlessProcess{}, false
+			}
+			return o.Processes[i], true
+		}
+		prefix := fmt.Sprintf("Proc_%d", i+1)
+		source := fmt.Sprintf("processes[%d].", i)
+		columns = append(columns,
+			flatColumn(prefix+"_PID", source+"pid", "integer", flatField(find, func(p HeadlessProcess) int { return p.PID })),
+			flatColumn(prefix+"_Command", source+"command", "string", flatField(find, func(p HeadlessProcess) string { return p.Command })),
+			flatColumn(prefix+"_CPU_Percent", source+"cpu_percent", "number", flatField(find, func(p HeadlessProcess) float64 { return p.CPU })),
+			flatColumn(prefix+"_GPU_Ms_Per_Sec", source+"gpu_ms_per_sec", "number", flatField(find, func(p HeadlessProcess) float64 { return p.GPU })),
+			flatColumn(prefix+"_Memory_Percent", source+"memory_percent", "number", flatField(find, func(p HeadlessProcess) float64 { return p.Memory })),
+			flatColumn(prefix+"_RSS_KB", source+"rss_kb", "integer", flatField(find, func(p HeadlessProcess) int64 { return p.RSS })),
+			flatColumn(prefix+"_Watts", source+"watts", "number", flatField(find, func(p HeadlessProcess) float64 { return p.Watts })),
+			flatColumn(prefix+"_Energy_J", source+"energy_joules", "number", flatField(find, func(p HeadlessProcess) float64 { return p.Joules })),
+		)
+	}
+	return columns
+}
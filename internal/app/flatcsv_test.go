@@ -0,0 +1,145 @@
+package app
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func flatTestOutput() HeadlessOutput {
+	output := fieldsTestOutput()
+	output.SystemInfo.CoreCount = 2
+	output.CoreUsages = []float64{12.5, 30}
+	output.Temperatures = []HeadlessTempGroup{{Group: "CPU P-Core", Avg: 55, Min: 50, Max: 61.25}, {Group: "GPU", Avg: 48, Min: 47, Max: 49}}
+	output.Fans = []HeadlessFan{{ID: 0, RPM: 1800, TargetRPM: 2000, Mode: "auto"}}
+	output.Volumes = []HeadlessVolume{{Name: "Macintosh HD", TotalGB: 994.66, UsedGB: 512, UsedPct: 51.48}}
+	output.NetworkLinks.Ethernet = []HeadlessEthernetLink{{Name: "en0", LinkUp: true, SpeedMbps: 10000}}
+	output.ThunderboltInfo = &ThunderboltOutput{Buses: []ThunderboltBusOutput{{Status: "Active", Speed: "40 Gb/s", NetworkStats: &ThunderboltNetStats{BytesInPerSec: 1024}}}}
+	return output
+}
+
+func flatCell(t *testing.T, l *flatCSVLayout, record []string, name string) string {
+	t.Helper()
+	i := slices.Index(l.csvHeader(), name)
+	if i < 0 {
+		t.Fatalf("no %s column in %v", name, l.csvHeader())
+	}
+	return record[i]
+}
+
+func TestFlatCSVLayout(t *testing.T) {
+	first := flatTestOutput()
+	l := newFlatCSVLayout(first, 2)
+	header := l.csvHeader()
+	if !slices.Contains(header, "Core_1") || slices.Contains(header, "Processes_JSON") {
+		t.Errorf("header = %v", header)
+	}
+	record := l.csvRecord(first)
+	if len(record) != len(header) {
+		t.Fatalf("%d cells for %d columns", len(record), len(header))
+	}
+	for name, want := range map[string]string{
+		"Core_1":                      "30.00",
+		"Temp_CPU_P_Core_Max_C":       "61.25",
+		"Fan_0_Mode":                  "auto",
+		"Volume_Macintosh_HD_Used_GB": "512.00",
+		"Eth_en0_Link_Up":             "true",
+		"Eth_en0_Speed_Mbps":          "10000",
+		"WiFi_Connected":              "",
+		"TB_Bus_0_Speed":              "40 Gb/s",
+		"TB_Bus_0_Net_In_Bytes":       "1024.00",
+		"Proc_2_Command":              "Xcode",
+	} {
+		if got := flatCell(t, l, record, name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestFlatCSVLayoutKeepsColumns(t *testing.T) {
+	l := newFlatCSVLayout(flatTestOutput(), 5)
+	header := l.csvHeader()
+
+	// The GPU group and the fan are gone, a volume has appeared and there
+	// are fewer processes than columns for them.
+	later := flatTestOutput()
+	later.Temperatures = later.Temperatures[:1]
+	later.Fans = nil
+	later.Volumes = append(later.Volumes, HeadlessVolume{Name: "Backup"})
+	later.CoreUsages = []float64{99}
+	record := l.csvRecord(later)
+	if len(record) != len(header) {
+		t.Fatalf("%d cells for %d columns", len(record), len(header))
+	}
+	for name, want := range map[string]string{
+		"Temp_GPU_Avg_C": "",
+		"Fan_0_RPM":      "",
+		"Core_1":         "0.00",
+		"Proc_3_PID":     "6",
+		"Proc_4_PID":     "",
+	} {
+		if got := flatCell(t, l, record, name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if strings.Contains(strings.Join(header, ","), "Backup") {
+		t.Error("columns should be fixed by the first sample")
+	}
+}
+
+func TestFlatCSVSchema(t *testing.T) {
+	data, err := headlessCSVSchema(newFlatCSVLayout(flatTestOutput(), 1).allColumns())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Temp_GPU_Avg_C,number,temperatures[group=GPU].avg_celsius,",
+		"Volume_Macintosh_HD_Used_Percent,number,volumes[name=Macintosh HD].used_percent,",
+		"Proc_1_RSS_KB,integer,processes[0].rss_kb,",
+		"Proc_1_Energy_J,number,processes[0].energy_joules,",
+	} {
+		if !strings.Contains(string(data), want+"\n") {
+			t.Errorf("CSV columns lack %s", want)
+		}
+	}
+}
+
+func TestCSVColumnName(t *testing.T) {
+	cases := map[string]string{
+		"Macintosh HD": "Macintosh_HD",
+		"CPU P-Core":   "CPU_P_Core",
+		" (disk2s1) ":  "disk2s1",
+		"---":          "Unnamed",
+	}
+	for in, want := range cases {
+		if got := csvColumnName(in); got != want {
+			t.Errorf("csvColumnName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFlatCSVLayoutNameCollisions(t *testing.T) {
+	first := flatTestOutput()
+	first.Volumes = []HeadlessVolume{{Name: "Macintosh HD", UsedGB: 1}, {Name: "Macintosh-HD", UsedGB: 2}, {Name: "Untitled", UsedGB: 3}, {Name: "Untitled", UsedGB: 4}}
+	l := newFlatCSVLayout(first, 0)
+
+	// The first Untitled volume is gone, so the second one now comes first
+	// among its namesakes and fills the first Untitled columns.
+	later := first
+	later.Volumes = []HeadlessVolume{{Name: "Untitled", UsedGB: 4}, {Name: "Macintosh-HD", UsedGB: 2}, {Name: "Macintosh HD", UsedGB: 1}}
+	record := l.csvRecord(later)
+	for name, want := range map[string]string{
+		"Volume_Macintosh_HD_Used_GB":   "1.00",
+		"Volume_Macintosh_HD_2_Used_GB": "2.00",
+		"Volume_Untitled_Used_GB":       "4.00",
+		"Volume_Untitled_2_Used_GB":     "",
+	} {
+		if got := flatCell(t, l, record, name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	record = l.csvRecord(first)
+	if got := flatCell(t, l, record, "Volume_Untitled_2_Used_GB"); got != "4.00" {
+		t.Errorf("Volume_Untitled_2_Used_GB = %q, want 4.00", got)
+	}
+}
@@ -69,7 +69,7 @@ var (
 	lastCPUTimes                  []CPUUsage
 	firstRun                      = true
 	sortReverse                   = false
-	columns                       = []string{"PID", "USER", "VIRT", "RES", "CPU", "GPU", "MEM", "TIME", "CMD"}
+	columns                       = []string{"PID", "USER", "VIRT", "RES", "CPU", "GPU", "MEM", "TIME", "ENERGY", "CMD"}
 	selectedColumn                = 4
 	maxPowerSeen                  = 0.1
 	gpuValues                     = make([]float64, 100)
@@ -93,6 +93,38 @@ var (
 	dumpTemps        bool    // Diagnostic: dump all SMC temperature keys
 	dumpDebug        bool    // Diagnostic: dump IOReport/HID/SMC/NVMe debug info
 	dumpFPS          bool    // Diagnostic: dump CGDisplayStream FPS info
+	recordPath       string  // Append every collected sample to this recording
+	replayPath       string  // Play this recording back instead of sampling the host
+	demoName         string  // Built-in demo scenario or YAML timeline to play on a loop
+	connectAddr      string  // host:port of a `mactop serve` instance to draw from
+	subcommand       string  // Verb given as the first argument, e.g. "serve"
+	serveListen      string  // Address `mactop serve` listens on
+	clusterHosts     string  // Comma-separated `mactop serve` nodes for `mactop cluster`
+	otlpEndpoint     string  // OTLP/HTTP collector to push metrics to
+	influxURL        string  // InfluxDB write endpoint to batch line protocol to
+	statsdAddr       string  // host:port of a StatsD agent to send gauges to
+	statsdPrefix     string  // Metric name prefix for --statsd
+	statsdTags       string  // Comma-separated DogStatsD tags; enables tagged output
+	promTextfilePath string  // .prom file rewritten every interval for node_exporter
+	pricePerKWh      float64 // Electricity price used to estimate session cost
+	budgetPath       string  // YAML limits checked by `mactop check`
+	headlessFields   string  // Comma-separated paths kept in headless output
+	headlessTemplate string  // Go template rendered per sample by --format template
+	templateFile     string  // File holding the --format template template
+	tableHeaderEvery int     // Lines between --format table headers; 0 follows the terminal
+	aggregatePeriod  string  // Window each headless record summarises, e.g. "60s"
+	outputPath       string  // File headless records are written to instead of stdout
+	rotateSize       string  // Size at which the --output file is rotated, e.g. "100MB"
+	rotateEvery      string  // Period after which the --output file is rotated, e.g. "1h"
+	rotateCompress   string  // gzip or zstd for rotated --output files
+	rotateKeep       int     // Rotated --output files kept; 0 keeps them all
+	flatCSV          bool    // --format csv with columns for nested data instead of JSON blobs
+	flatProcesses    int     // Top processes given columns by --flat
+	historyEnabled   bool    // Keep samples under ~/.mactop/history for `mactop query`
+	queryMetric      string  // Comma-separated fields read back by `mactop query`
+	querySince       string  // How far back `mactop query` reads, e.g. "7d"
+	queryStep        string  // Length of each `mactop query` point, e.g. "1h"
+	queryAgg         string  // avg, min or max of the samples in each step
 	interruptChan    = make(chan struct{}, 10)
 
 	cachedTermWidth    int
@@ -136,6 +168,7 @@ var (
 	cachedModelName  string
 	cachedSystemInfo SystemInfo
 	tbDeviceInfo     string
+	tbBusInfo        *ThunderboltOutput // Buses behind tbDeviceInfo, for exporters
 	tbInfoMutex      sync.Mutex
 	infoScrollOffset int
 	helpScrollOffset int
@@ -287,4 +320,32 @@ var (
 		},
 		[]string{"model", "core_count", "e_core_count", "p_core_count", "s_core_count", "gpu_core_count"},
 	)
+
+	// CPU clusters, GPU compute and display
+	cpuClusterFreqMHz  = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "mactop_cpu_cluster_freq_mhz", Help: "CPU cluster frequency in MHz"}, []string{"cluster"})
+	cpuClusterActive   = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "mactop_cpu_cluster_active_percent", Help: "CPU cluster active residency percentage"}, []string{"cluster"})
+	gpuTFLOPs          = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "mactop_gpu_tflops", Help: "Theoretical peak GPU TFLOPs at the maximum GPU frequency"}, []string{"precision"})
+	displayFPS         = prometheus.NewGauge(prometheus.GaugeOpts{Name: "mactop_display_fps", Help: "Main display frames per second"})
+	displayFrameTimeMs = prometheus.NewGauge(prometheus.GaugeOpts{Name: "mactop_display_frame_interval_ms", Help: "Main display average frame interval in milliseconds"})
+
+	// Volumes and network links
+	volumeGB          = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "mactop_volume_gb", Help: "Disk volume size in GB"}, []string{"volume", "type"})
+	volumeUsedPercent = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "mactop_volume_used_percent", Help: "Disk volume usage percentage"}, []string{"volume"})
+	linkSpeedMbps     = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "mactop_network_link_speed_mbps", Help: "Ethernet link speed in Mbps"}, []string{"interface"})
+	linkUp            = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "mactop_network_link_up", Help: "Ethernet link state (1=up, 0=down)"}, []string{"interface"})
+	wifiTxRateMbps    = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "mactop_wifi_tx_rate_mbps", Help: "Wi-Fi transmit rate in Mbps (0 when disconnected)"}, []string{"interface", "phy_mode"})
+
+	// Thunderbolt buses and RDMA devices
+	tbBusActive   = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "mactop_thunderbolt_bus_active", Help: "Thunderbolt bus state (1=active, 0=inactive)"}, []string{"bus", "speed"})
+	tbBusDevices  = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "mactop_thunderbolt_bus_devices", Help: "Devices connected to a Thunderbolt bus"}, []string{"bus"})
+	rdmaDeviceMTU = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "mactop_rdma_device_active_mtu", Help: "RDMA device active MTU (labels describe the device)"}, []string{"device", "interface", "transport", "port_state", "link_layer"})
+
+	// Top processes, summed by command and capped at promProcessLimit commands
+	processCPU    = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "mactop_process_cpu_percent", Help: "CPU usage percentage of a top command"}, []string{"command"})
+	processGPU    = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "mactop_process_gpu_ms_per_sec", Help: "GPU time in ms per second of a top command"}, []string{"command"})
+	processMemory = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "mactop_process_memory_percent", Help: "Memory usage percentage of a top command"}, []string{"command"})
+	processRSS    = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "mactop_process_rss_bytes", Help: "Resident memory in bytes of a top command"}, []string{"command"})
+	processCount  = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "mactop_process_count", Help: "Number of processes running a top command"}, []string{"command"})
+	processWatts  = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "mactop_process_watts", Help: "Estimated CPU and GPU power of a top command"}, []string{"command"})
+	processEnergy = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "mactop_process_energy_joules", Help: "Estimated energy used by the running processes of a top command"}, []string{"command"})
 )
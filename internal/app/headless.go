@@ -5,8 +5,8 @@ import (
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"math"
-	"net/http"
 	"os"
 	"os/signal"
 	"sort"
@@ -17,7 +17,6 @@ import (
 	"time"
 
 	"github.com/metaspartan/mactop/v2/internal/i18n"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/toon-format/toon-go"
 	"gopkg.in/yaml.v3"
 )
@@ -37,6 +36,8 @@ type HeadlessProcess struct {
 	GPU     float64 `json:"gpu_ms_per_sec" yaml:"gpu_ms_per_sec" xml:"GPUMsPerSec" toon:"gpu_ms_per_sec"`
 	Memory  float64 `json:"memory_percent" yaml:"memory_percent" xml:"MemoryPercent" toon:"memory_percent"`
 	RSS     int64   `json:"rss_kb" yaml:"rss_kb" xml:"RSSKB" toon:"rss_kb"`
+	Watts   float64 `json:"watts" yaml:"watts" xml:"Watts" toon:"watts"`
+	Joules  float64 `json:"energy_joules" yaml:"energy_joules" xml:"EnergyJoules" toon:"energy_joules"`
 }
 
 // HeadlessNetworkLinks holds link speed info for all network interfaces
@@ -97,6 +98,7 @@ type HeadlessFan struct {
 }
 
 type HeadlessOutput struct {
+	SchemaVersion         int                  `json:"schema_version" yaml:"schema_version" xml:"SchemaVersion" toon:"schema_version"`
 	Timestamp             string               `json:"timestamp" yaml:"timestamp" xml:"Timestamp" toon:"timestamp"`
 	SocMetrics            SocMetrics           `json:"soc_metrics" yaml:"soc_metrics" xml:"SocMetrics" toon:"soc_metrics"`
 	Memory                MemoryMetrics        `json:"memory" yaml:"memory" xml:"Memory" toon:"memory"`
@@ -123,6 +125,7 @@ type HeadlessOutput struct {
 	RDMAStatus            RDMAStatus           `json:"rdma_status" yaml:"rdma_status" xml:"RDMAStatus" toon:"rdma_status"`
 	Fans                  []HeadlessFan        `json:"fans,omitempty" yaml:"fans,omitempty" xml:"Fans" toon:"fans"`
 	Temperatures          []HeadlessTempGroup  `json:"temperatures,omitempty" yaml:"temperatures,omitempty" xml:"Temperatures" toon:"temperatures"`
+	Energy                HeadlessEnergy       `json:"energy" yaml:"energy" xml:"Energy" toon:"energy"`
 }
 
 func runHeadless(count int) {
@@ -138,36 +141,30 @@ func runHeadless(count int) {
 
 	startHeadlessPrometheus()
 
-	// Validate format
-	format := strings.ToLower(headlessFormat)
-	switch format {
-	case "json", "yaml", "xml", "toon", "csv":
-	default:
-		fmt.Fprintf(os.Stderr, i18n.T("Headless_ErrorUnknownFormat")+"\n", format)
-		format = "json"
-	}
+	format := setupHeadlessFormat(headlessFormat)
 
 	tbInfo := performHeadlessWarmup()
 
-	printHeadlessStart(format, count)
+	// Cache SystemInfo since it doesn't change
+	e := &headlessEmitter{format: format, count: count, w: os.Stdout, tbInfo: tbInfo, sysInfo: getSOCInfo()}
+	if activeOutput != nil {
+		e.w, e.out = activeOutput, activeOutput
+	}
 
 	// Setup signal handling for graceful shutdown (to close XML tags)
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	samplesCollected := 0
-
-	// Cache SystemInfo since it doesn't change
-	cachedHeadlessSysInfo := getSOCInfo()
-
-	// First manual collection
-	if err := processHeadlessSample(format, tbInfo, cachedHeadlessSysInfo); err != nil {
-		fmt.Fprintf(os.Stderr, i18n.T("Headless_ErrorFormattingOutput")+"\n", err)
+	// First manual collection. It comes before the header, since --flat
+	// takes its columns from it.
+	first := nextHeadlessOutput(e.tbInfo, e.sysInfo)
+	if flatCSV {
+		activeFlatCSV = newFlatCSVLayout(first, flatProcesses)
 	}
-	samplesCollected++
-
-	if count > 0 && samplesCollected >= count {
-		printHeadlessEnd(format, count)
+	printHeadlessStart(e.w, format, count)
+	e.add(first)
+	if e.done() {
+		e.close()
 		return
 	}
 
@@ -177,112 +174,242 @@ func runHeadless(count int) {
 	for {
 		select {
 		case <-sigChan:
-			printHeadlessEnd(format, count)
+			e.flush()
+			e.close()
 			return
 		case <-ticker.C:
-			printHeadlessSeparator(format, count, samplesCollected)
-
-			if err := processHeadlessSample(format, tbInfo, cachedHeadlessSysInfo); err != nil {
-				fmt.Fprintf(os.Stderr, i18n.T("Headless_ErrorFormattingOutput")+"\n", err)
-			}
-
-			samplesCollected++
-			if count > 0 && samplesCollected >= count {
-				printHeadlessEnd(format, count)
+			e.sample()
+			if e.done() {
+				e.close()
 				return
 			}
 		}
 	}
 }
 
-func printHeadlessStart(format string, count int) {
+// setupHeadlessFormat validates --format and prepares everything the format
+// and the output options need before sampling starts.
+func setupHeadlessFormat(name string) string {
+	format := strings.ToLower(name)
+	switch format {
+	case "json", "yaml", "xml", "toon", "csv", "influx", "template", "i3bar", "waybar", "tmux", "sketchybar", "table":
+	default:
+		fmt.Fprintf(os.Stderr, i18n.T("Headless_ErrorUnknownFormat")+"\n", format)
+		format = "json"
+	}
+
+	if headlessFields != "" && (format == "influx" || format == "table" || isStatusBarFormat(format)) {
+		stderrLogger.Fatalf("invalid --fields: not supported with --format %s", format)
+	}
+	setupHeadlessFields()
+	setupHeadlessAggregate()
+	setupHeadlessFlat(format)
+	setupHeadlessOutput()
+	switch {
+	case format == "template":
+		setupHeadlessTemplate()
+	case format == "table":
+		setupHeadlessTable()
+	case isStatusBarFormat(format):
+		setupStatusPalette()
+	}
+	return format
+}
+
+// headlessEmitter prints records as samples come in and counts them for
+// --count. With --aggregate a record is a whole window, not a sample.
+type headlessEmitter struct {
+	format  string
+	count   int
+	emitted int
+	inFile  int           // Records written since the output was opened
+	w       io.Writer     // stdout, or out
+	out     *rotatingFile // The --output file, if any
+	tbInfo  *ThunderboltOutput
+	sysInfo SystemInfo
+}
+
+// headlessRecord is what one printed record is made from.
+type headlessRecord struct {
+	output HeadlessOutput   // The sample, or the window average
+	doc    any              // What the structured encoders print
+	window *aggregateWindow // Set when aggregating
+}
+
+func newHeadlessRecord(output HeadlessOutput) headlessRecord {
+	var doc any = output
+	if activeFields != nil {
+		doc = activeFields.trim(output)
+	}
+	return headlessRecord{output: output, doc: doc}
+}
+
+// sample takes one sample and adds it.
+func (e *headlessEmitter) sample() {
+	e.add(nextHeadlessOutput(e.tbInfo, e.sysInfo))
+}
+
+// add prints a sample, or adds it to the current --aggregate window and
+// prints the window it closes, if any.
+func (e *headlessEmitter) add(output HeadlessOutput) {
+	if activeAggregate == nil {
+		e.print(newHeadlessRecord(output))
+		return
+	}
+	if w := activeAggregate.add(output, time.Now()); w != nil {
+		e.print(w.record())
+	}
+}
+
+// flush prints the partly filled --aggregate window on shutdown.
+func (e *headlessEmitter) flush() {
+	if activeAggregate == nil {
+		return
+	}
+	if w := activeAggregate.flush(); w != nil {
+		e.print(w.record())
+	}
+}
+
+func (e *headlessEmitter) print(rec headlessRecord) {
+	if e.out != nil && e.out.due(time.Now()) {
+		e.rotate()
+	}
+	if e.inFile > 0 {
+		printHeadlessSeparator(e.w, e.format, e.count, e.inFile)
+	}
+	if err := writeHeadlessRecord(e.w, e.format, rec); err != nil {
+		fmt.Fprintf(os.Stderr, i18n.T("Headless_ErrorFormattingOutput")+"\n", err)
+	}
+	e.inFile++
+	e.emitted++
+}
+
+// rotate closes the --output file with the same trailer and opens its
+// successor with the same header as a whole run, so that every file can be
+// read on its own.
+func (e *headlessEmitter) rotate() {
+	printHeadlessEnd(e.w, e.format, e.count)
+	if err := e.out.rotate(time.Now()); err != nil {
+		stderrLogger.Fatalf("output: rotating %s: %v", e.out.path, err)
+	}
+	e.inFile = 0
+	if activeTable != nil {
+		activeTable.left = 0
+	}
+	printHeadlessStart(e.w, e.format, e.count)
+}
+
+// close ends the output and waits for rotated files to be compressed.
+func (e *headlessEmitter) close() {
+	printHeadlessEnd(e.w, e.format, e.count)
+	if e.out != nil {
+		if err := e.out.close(); err != nil {
+			stderrLogger.Printf("output: closing %s: %v\n", e.out.path, err)
+		}
+	}
+}
+
+func (e *headlessEmitter) done() bool {
+	return e.count > 0 && e.emitted >= e.count
+}
+
+func printHeadlessStart(w io.Writer, format string, count int) {
 	if count > 0 {
 		switch format {
 		case "json":
-			fmt.Print("[")
+			fmt.Fprint(w, "[")
 		case "xml":
-			fmt.Print("<MactopOutputList>")
+			fmt.Fprint(w, "<MactopOutputList>")
 		case "csv":
-			printCSVHeader()
+			printCSVHeader(w)
+		case "i3bar":
+			printI3barHeader(w)
 		}
 	} else {
 		switch format {
 		case "xml":
 			// XML always needs a root element, even in infinite mode
-			fmt.Print("<MactopOutputList>")
+			fmt.Fprint(w, "<MactopOutputList>")
 		case "csv":
-			printCSVHeader()
+			printCSVHeader(w)
+		case "i3bar":
+			printI3barHeader(w)
 		}
 	}
 }
 
-func printCSVHeader() {
-	headers := []string{
-		"Timestamp",
-		"System_Name", "Core_Count", "E_Core_Count", "P_Core_Count", "S_Core_Count", "GPU_Core_Count",
-		"CPU_Usage", "ECPU_Freq_MHz", "ECPU_Active", "PCPU_Freq_MHz", "PCPU_Active",
-		"SCPU_Freq_MHz", "SCPU_Active",
-		"GPU_Usage",
-		"GPU_Freq_MHz", "GPU_Active_Percent",
-		"Display_FPS", "Frame_Interval_Ms",
-		"Mem_Used", "Mem_Total", "Swap_Used",
-		"Disk_Read_KB", "Disk_Write_KB",
-		"Net_In_Bytes", "Net_Out_Bytes",
-		"TB_Net_In_Bytes", "TB_Net_Out_Bytes",
-		"Total_Power", "System_Power",
-		"CPU_Temp", "GPU_Temp", "Thermal_State",
-		"DRAM_Read_BW_GBs", "DRAM_Write_BW_GBs", "DRAM_BW_Combined_GBs",
-		"RDMA_Available", "RDMA_Status", "RDMA_Device_Count",
+func printCSVHeader(w io.Writer) {
+	if activeAggregate != nil {
+		fmt.Fprintln(w, strings.Join(activeAggregate.csvHeader(), ","))
+		return
 	}
-
-	// Add dynamic core headers
-	sysInfo := getSOCInfo()
-	for i := 0; i < sysInfo.CoreCount; i++ {
-		headers = append(headers, fmt.Sprintf("Core_%d", i))
+	if activeFields != nil {
+		fmt.Fprintln(w, strings.Join(activeFields.csvHeader(), ","))
+		return
+	}
+	if activeFlatCSV != nil {
+		fmt.Fprintln(w, strings.Join(activeFlatCSV.csvHeader(), ","))
+		return
+	}
+	var headers []string
+	for _, c := range headlessCSVColumns(getSOCInfo().CoreCount) {
+		headers = append(headers, c.name)
 	}
-
-	// Add JSON blob headers for complex nested data
-	headers = append(headers, "Thunderbolt_Info_JSON", "Processes_JSON", "Network_Links_JSON", "Volumes_JSON")
 
 	// Print CSV header line
-	fmt.Println(strings.Join(headers, ","))
+	fmt.Fprintln(w, strings.Join(headers, ","))
+}
+
+// printI3barHeader opens the i3bar protocol: the version header, then the
+// endless array every status line is an element of.
+func printI3barHeader(w io.Writer) {
+	fmt.Fprintln(w, `{"version":1}`)
+	fmt.Fprintln(w, "[")
 }
 
-func printHeadlessEnd(format string, count int) {
+func printHeadlessEnd(w io.Writer, format string, count int) {
 	if count > 0 {
 		switch format {
 		case "json":
-			fmt.Println("]")
+			fmt.Fprintln(w, "]")
+		case "xml":
+			fmt.Fprintln(w, "</MactopOutputList>")
+		case "i3bar":
+			fmt.Fprintln(w, "]")
+		}
+	} else {
+		switch format {
 		case "xml":
-			fmt.Println("</MactopOutputList>")
+			fmt.Fprintln(w, "</MactopOutputList>")
+		case "i3bar":
+			fmt.Fprintln(w, "]")
 		}
-	} else if format == "xml" {
-		fmt.Println("</MactopOutputList>")
 	}
 }
 
-func printHeadlessSeparator(format string, count int, samplesCollected int) {
+func printHeadlessSeparator(w io.Writer, format string, count int, samplesCollected int) {
+	if format == "i3bar" {
+		// Status lines after the first are continuations of the array
+		fmt.Fprint(w, ",")
+		return
+	}
 	if samplesCollected > 0 && count > 0 {
 		switch format {
 		case "json":
-			fmt.Print(",")
+			fmt.Fprint(w, ",")
 		case "yaml":
-			fmt.Println("---")
+			fmt.Fprintln(w, "---")
 		}
 	} else if format == "yaml" {
 		// Even for infinite stream, YAML docs are best separated by ---
-		fmt.Println("---")
+		fmt.Fprintln(w, "---")
 	}
 }
 
 func startHeadlessPrometheus() {
 	if prometheusPort != "" {
-		go func() {
-			http.Handle("/metrics", promhttp.Handler())
-			if err := http.ListenAndServe(prometheusPort, nil); err != nil {
-				fmt.Fprintf(os.Stderr, i18n.T("Headless_ErrorPrometheusServer")+"\n", err)
-			}
-		}()
+		startPrometheusServer(prometheusPort)
 	}
 }
 
@@ -302,101 +429,87 @@ func performHeadlessWarmup() *ThunderboltOutput {
 	return tbInfo
 }
 
-func processHeadlessSample(format string, tbInfo *ThunderboltOutput, sysInfo SystemInfo) error {
-	output := collectHeadlessData(tbInfo, sysInfo)
+func writeHeadlessRecord(w io.Writer, format string, rec headlessRecord) error {
+	output, doc := rec.output, rec.doc
+
 	var data []byte
 	var err error
 
 	switch format {
 	case "json":
 		if headlessPretty {
-			data, err = json.MarshalIndent(output, "", "  ")
+			data, err = json.MarshalIndent(doc, "", "  ")
 		} else {
-			data, err = json.Marshal(output)
+			data, err = json.Marshal(doc)
 		}
 	case "yaml":
-		data, err = yaml.Marshal(output)
+		data, err = yaml.Marshal(doc)
 	case "xml":
 		if headlessPretty {
-			data, err = xml.MarshalIndent(output, "", "  ")
+			data, err = xml.MarshalIndent(doc, "", "  ")
 		} else {
-			data, err = xml.Marshal(output)
+			data, err = xml.Marshal(doc)
 		}
 	case "toon":
-		data, err = toon.Marshal(output)
-	case "csv":
-		// Use encoding/csv for correct escaping
-		writer := csv.NewWriter(os.Stdout)
-
-		var record []string
-
-		// Standard fields
-		record = append(record,
-			output.Timestamp,
-			output.SystemInfo.Name,
-			fmt.Sprintf("%d", output.SystemInfo.CoreCount),
-			fmt.Sprintf("%d", output.SystemInfo.ECoreCount),
-			fmt.Sprintf("%d", output.SystemInfo.PCoreCount),
-			fmt.Sprintf("%d", output.SystemInfo.SCoreCount),
-			fmt.Sprintf("%d", output.SystemInfo.GPUCoreCount),
-			fmt.Sprintf("%.2f", output.CPUUsage),
-			fmt.Sprintf("%.2f", safeFloat64At(output.ECPUUsage, 0)),
-			fmt.Sprintf("%.2f", safeFloat64At(output.ECPUUsage, 1)),
-			fmt.Sprintf("%.2f", safeFloat64At(output.PCPUUsage, 0)),
-			fmt.Sprintf("%.2f", safeFloat64At(output.PCPUUsage, 1)),
-			fmt.Sprintf("%.2f", safeFloat64At(output.SCPUUsage, 0)),
-			fmt.Sprintf("%.2f", safeFloat64At(output.SCPUUsage, 1)),
-			fmt.Sprintf("%.2f", output.GPUUsage),
-			fmt.Sprintf("%d", output.GPUMetrics.FreqMHz),
-			fmt.Sprintf("%.2f", output.GPUMetrics.ActivePercent),
-			fmt.Sprintf("%d", output.DisplayFPS),
-			fmt.Sprintf("%.2f", output.FrameIntervalMs),
-			fmt.Sprintf("%d", output.Memory.Used),
-			fmt.Sprintf("%d", output.Memory.Total),
-			fmt.Sprintf("%d", output.Memory.SwapUsed),
-			fmt.Sprintf("%.2f", output.NetDisk.ReadKBytesPerSec),
-			fmt.Sprintf("%.2f", output.NetDisk.WriteKBytesPerSec),
-			fmt.Sprintf("%.2f", output.NetDisk.InBytesPerSec),
-			fmt.Sprintf("%.2f", output.NetDisk.OutBytesPerSec),
-			fmt.Sprintf("%.2f", output.TBNetTotalBytesInSec),
-			fmt.Sprintf("%.2f", output.TBNetTotalBytesOutSec),
-			fmt.Sprintf("%.2f", output.SocMetrics.TotalPower),
-			fmt.Sprintf("%.2f", output.SocMetrics.SystemPower),
-			fmt.Sprintf("%.2f", output.SocMetrics.CPUTemp),
-			fmt.Sprintf("%.2f", output.SocMetrics.GPUTemp),
-			output.ThermalState,
-			fmt.Sprintf("%.2f", output.SocMetrics.DRAMReadBW),
-			fmt.Sprintf("%.2f", output.SocMetrics.DRAMWriteBW),
-			fmt.Sprintf("%.2f", output.SocMetrics.DRAMBWCombined),
-			fmt.Sprintf("%t", output.RDMAStatus.Available),
-			output.RDMAStatus.Status,
-			fmt.Sprintf("%d", len(output.RDMAStatus.Devices)),
-		)
-
-		for i := 0; i < output.SystemInfo.CoreCount; i++ {
-			val := 0.0
-			if i < len(output.CoreUsages) {
-				val = output.CoreUsages[i]
-			}
-			record = append(record, fmt.Sprintf("%.2f", val))
+		data, err = toon.Marshal(doc)
+	case "influx":
+		host, _ := os.Hostname()
+		lines := formatInfluxLines(output, host, time.Now())
+		if rec.window != nil {
+			lines = formatInfluxAggregate(rec.window, host)
 		}
+		_, err = w.Write(lines)
+		return err
+	case "template":
+		return writeTemplateSample(w, activeTemplate, doc)
+	case "i3bar", "waybar", "tmux", "sketchybar":
+		return writeStatusBar(w, format, activeStatusPalette, output)
+	case "table":
+		return activeTable.write(w, output)
+	case "csv":
+		return writeHeadlessCSV(w, rec)
+	}
 
-		tbJSON, _ := json.Marshal(output.ThunderboltInfo)
-		procsJSON, _ := json.Marshal(output.Processes)
-		linksJSON, _ := json.Marshal(output.NetworkLinks)
-		volsJSON, _ := json.Marshal(output.Volumes)
-		record = append(record, string(tbJSON), string(procsJSON), string(linksJSON), string(volsJSON))
+	if err != nil {
+		return err
+	}
 
-		writer.Write(record)
+	fmt.Fprintln(w, string(data))
+	return nil
+}
+
+// writeHeadlessCSV writes one CSV row: the standard columns, the --fields
+// paths, the --flat columns, or an --aggregate window.
+func writeHeadlessCSV(w io.Writer, rec headlessRecord) error {
+	output := rec.output
+	// Use encoding/csv for correct escaping
+	writer := csv.NewWriter(w)
+	if rec.window != nil {
+		writer.Write(rec.window.csvRecord())
 		writer.Flush()
 		return nil
 	}
-
-	if err != nil {
-		return err
+	if activeFields != nil {
+		writer.Write(activeFields.csvRecord(output))
+		writer.Flush()
+		return nil
 	}
+	if activeFlatCSV != nil {
+		writer.Write(activeFlatCSV.csvRecord(output))
+		writer.Flush()
+		return nil
+	}
+
+	record := append(headlessCSVLeadRecord(output), headlessCSVCoreRecord(output, output.SystemInfo.CoreCount)...)
 
-	fmt.Println(string(data))
+	tbJSON, _ := json.Marshal(output.ThunderboltInfo)
+	procsJSON, _ := json.Marshal(output.Processes)
+	linksJSON, _ := json.Marshal(output.NetworkLinks)
+	volsJSON, _ := json.Marshal(output.Volumes)
+	record = append(record, string(tbJSON), string(procsJSON), string(linksJSON), string(volsJSON))
+
+	writer.Write(record)
+	writer.Flush()
 	return nil
 }
 
@@ -447,14 +560,111 @@ func getHeadlessNetworkLinks() HeadlessNetworkLinks {
 	return links
 }
 
-func collectHeadlessData(tbInfo *ThunderboltOutput, sysInfo SystemInfo) HeadlessOutput {
-	m := sampleSocMetrics(updateInterval)
-	mem := getMemoryMetrics()
-	netDisk := getNetDiskMetrics()
+// headlessSample holds the raw readings a HeadlessOutput is built from, so the
+// same snapshot can be formatted for stdout and written to a recording.
+type headlessSample struct {
+	soc        SocMetrics
+	mem        MemoryMetrics
+	netDisk    NetDiskMetrics
+	coreUsages []float64
+	thermal    thermalStateLevel
+	tbNetStats []ThunderboltNetStats
+	rdma       RDMAStatus
+	processes  []ProcessMetrics
+}
+
+// sampleHeadlessOnce takes one sample the way --headless --count 1 does, for
+// commands that need to see this machine's fans, volumes and links.
+func sampleHeadlessOnce() (HeadlessOutput, error) {
+	if err := initSocMetrics(); err != nil {
+		return HeadlessOutput{}, err
+	}
+	defer cleanupSocMetrics()
+	tbInfo := performHeadlessWarmup()
+	return nextHeadlessOutput(tbInfo, getSOCInfo()), nil
+}
+
+// nextHeadlessOutput samples every collector once, publishes the result and
+// returns it.
+func nextHeadlessOutput(tbInfo *ThunderboltOutput, sysInfo SystemInfo) HeadlessOutput {
+	sample := takeHeadlessSample()
+	output := buildHeadlessOutput(sample, tbInfo, sysInfo)
+	publishSample(output, sample, false)
+	return output
+}
+
+// publishSample hands a finished sample to everything that consumes one: the
+// recording, the history store, the alert rules and the push exporters.
+func publishSample(output HeadlessOutput, s headlessSample, inTUI bool) {
+	recordSample(output, s)
+	recordHistory(output, s)
+	checkAlerts(output, s, inTUI)
+	exportOTLP(output, s)
+	exportInflux(output)
+	exportStatsd(output, s)
+	updatePrometheusFromSample(output, s)
+	writePrometheusTextfile()
+}
+
+// sampleConsumersActive reports whether publishSample has anything to do, so
+// the TUI can skip assembling snapshots nobody reads.
+func sampleConsumersActive() bool {
+	return activeRecorder != nil || activeHistory != nil || activeAlerts != nil || activeOTLP != nil || activeInflux != nil || activeStatsd != nil || activeTextfile != nil || prometheusPort != ""
+}
+
+// publishTUISample assembles a headless snapshot from what the TUI collectors
+// already have on hand and publishes it.
+func publishTUISample(m SocMetrics, coreUsages []float64, tbNetStats []ThunderboltNetStats, sysInfo SystemInfo) {
+	if !sampleConsumersActive() {
+		return
+	}
+	renderMutex.Lock()
+	netDisk := lastNetDiskMetrics
+	processes := lastProcesses
+	renderMutex.Unlock()
+	tbInfoMutex.Lock()
+	tbInfo := tbBusInfo
+	tbInfoMutex.Unlock()
+
+	s := headlessSample{
+		soc:        m,
+		mem:        getMemoryMetrics(),
+		netDisk:    netDisk,
+		coreUsages: coreUsages,
+		thermal:    getThermalStateLevel(),
+		tbNetStats: tbNetStats,
+		rdma:       CheckRDMAAvailable(),
+		processes:  processes,
+	}
+	publishSample(buildHeadlessOutput(s, tbInfo, sysInfo), s, true)
+}
+
+func takeHeadlessSample() headlessSample {
+	s := headlessSample{
+		soc:     sampleSocMetrics(updateInterval),
+		mem:     getMemoryMetrics(),
+		netDisk: getNetDiskMetrics(),
+	}
+	sessionEnergy.add(s.soc, time.Now())
+	s.coreUsages, _ = GetCPUPercentages()
+	s.thermal = getThermalStateLevel()
+	s.tbNetStats = GetThunderboltNetStats()
+	s.rdma = CheckRDMAAvailable()
+	if procs, err := getProcessList(s.soc.GPUActive); err == nil {
+		s.processes = procs
+	}
+	return s
+}
+
+// headlessProcessLimit is how many of the busiest processes a sample lists.
+const headlessProcessLimit = 20
+
+func buildHeadlessOutput(s headlessSample, tbInfo *ThunderboltOutput, sysInfo SystemInfo) HeadlessOutput {
+	m := s.soc
+	percentages := s.coreUsages
 
 	var cpuUsage float64
-	percentages, err := GetCPUPercentages()
-	if err == nil && len(percentages) > 0 {
+	if len(percentages) > 0 {
 		var total float64
 		for _, p := range percentages {
 			total += p
@@ -462,7 +672,7 @@ func collectHeadlessData(tbInfo *ThunderboltOutput, sysInfo SystemInfo) Headless
 		cpuUsage = total / float64(len(percentages))
 	}
 
-	thermalStr, _ := getThermalStateString()
+	thermalStr := thermalStateString(s.thermal)
 
 	componentSum := m.TotalPower
 	totalPower := m.SystemPower
@@ -476,17 +686,16 @@ func collectHeadlessData(tbInfo *ThunderboltOutput, sysInfo SystemInfo) Headless
 	m.SystemPower = residualSystem
 	m.TotalPower = totalPower
 
-	tbNetStats := GetThunderboltNetStats()
 	var tbNetTotalIn, tbNetTotalOut float64
-	for _, stat := range tbNetStats {
+	for _, stat := range s.tbNetStats {
 		tbNetTotalIn += stat.BytesInPerSec
 		tbNetTotalOut += stat.BytesOutPerSec
 	}
 
-	mapTBNetStatsToBuses(tbNetStats, tbInfo)
+	mapTBNetStatsToBuses(s.tbNetStats, tbInfo)
 
-	// Get RDMA status and map devices to TB buses
-	rdmaStatus := CheckRDMAAvailable()
+	// Map RDMA devices to TB buses
+	rdmaStatus := s.rdma
 	mapRDMADevicesToBuses(rdmaStatus.Devices, tbInfo)
 
 	// Calculate TFLOPs
@@ -497,20 +706,20 @@ func collectHeadlessData(tbInfo *ThunderboltOutput, sysInfo SystemInfo) Headless
 		fp16TFLOPs = fp32TFLOPs * 2
 	}
 
-	// Collect per-process metrics (top 20 by CPU, includes GPU time)
+	// Collect per-process metrics (top by CPU, includes GPU time)
 	var headlessProcesses []HeadlessProcess
-	if procs, err := getProcessList(m.GPUActive); err == nil {
-		limit := min(len(procs), 20)
-		for _, p := range procs[:limit] {
-			headlessProcesses = append(headlessProcesses, HeadlessProcess{
-				PID:     p.PID,
-				Command: p.Command,
-				CPU:     p.CPU,
-				GPU:     p.GPU,
-				Memory:  p.Memory,
-				RSS:     p.RSS,
-			})
-		}
+	limit := min(len(s.processes), headlessProcessLimit)
+	for _, p := range s.processes[:limit] {
+		headlessProcesses = append(headlessProcesses, HeadlessProcess{
+			PID:     p.PID,
+			Command: p.Command,
+			CPU:     p.CPU,
+			GPU:     p.GPU,
+			Memory:  p.Memory,
+			RSS:     p.RSS,
+			Watts:   p.Watts,
+			Joules:  p.Joules,
+		})
 	}
 
 	// Collect network link speed info
@@ -534,10 +743,11 @@ func collectHeadlessData(tbInfo *ThunderboltOutput, sysInfo SystemInfo) Headless
 	fpsMetrics := GetDisplayFPSMetrics()
 
 	output := HeadlessOutput{
+		SchemaVersion:         headlessSchemaVersion,
 		Timestamp:             time.Now().Format(time.RFC3339),
 		SocMetrics:            m,
-		Memory:                mem,
-		NetDisk:               netDisk,
+		Memory:                s.mem,
+		NetDisk:               s.netDisk,
 		CPUUsage:              cpuUsage,
 		PCPUUsage:             []float64{float64(m.PClusterFreqMHz), m.PClusterActive},
 		GPUUsage:              m.GPUActive,
@@ -558,6 +768,7 @@ func collectHeadlessData(tbInfo *ThunderboltOutput, sysInfo SystemInfo) Headless
 		ThermalState:          thermalStr,
 		Fans:                  headlessFans,
 		Temperatures:          orderedTemps,
+		Energy:                sessionEnergy.snapshot(),
 	}
 	if sysInfo.ECoreCount > 0 {
 		output.ECPUUsage = []float64{float64(m.EClusterFreqMHz), m.EClusterActive}
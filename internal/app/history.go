@@ -0,0 +1,376 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// history.go - --history: samples and their 1m/1h rollups kept under ~/.mactop/history for `mactop query`
+package app
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	historyRawRetention    = 24 * time.Hour
+	historyRollupRetention = 90 * 24 * time.Hour
+	// historySettle is how long a finished segment is left alone before it
+	// is compressed, in case another mactop is still appending its last row.
+	historySettle = time.Minute
+)
+
+// historyTier is one resolution of the store. Each tier is a directory of
+// JSON Lines segments named after the UTC start of the period they cover;
+// finished segments are gzipped.
+type historyTier struct {
+	name      string        // Directory under the store
+	step      time.Duration // Rollup length; 0 for raw samples
+	retention time.Duration
+	layout    string // Segment file name
+	next      func(start time.Time) time.Time
+}
+
+var historyTiers = []historyTier{
+	{"raw", 0, historyRawRetention, "20060102-15", func(t time.Time) time.Time { return t.Add(time.Hour) }},
+	{"1m", time.Minute, historyRollupRetention, "20060102", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+	{"1h", time.Hour, historyRollupRetention, "200601", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+}
+
+// segment returns the start of the segment holding t.
+func (tier historyTier) segment(t time.Time) time.Time {
+	start, _ := time.Parse(tier.layout, t.UTC().Format(tier.layout))
+	return start
+}
+
+// historyRow is one line of a segment. A raw row holds a sample in V; a
+// rollup row holds the N samples of one step. Zero values are left out of
+// every map to keep the files small.
+type historyRow struct {
+	T   int64              `json:"t"` // Unix milliseconds; the step start for rollups
+	N   int                `json:"n,omitempty"`
+	V   map[string]float64 `json:"v,omitempty"`
+	Min map[string]float64 `json:"min,omitempty"`
+	Max map[string]float64 `json:"max,omitempty"`
+	Sum map[string]float64 `json:"sum,omitempty"`
+}
+
+// historyRollup accumulates the current step of a rollup tier.
+type historyRollup struct {
+	tier  historyTier
+	start time.Time
+	n     int
+	min   map[string]float64
+	max   map[string]float64
+	sum   map[string]float64
+}
+
+// historyStore appends samples to the raw tier and rolls them up as steps
+// end. Several mactop processes may share one store: rows are appended
+// with a single write each, and query merges rollup rows for the same step.
+type historyStore struct {
+	dir     string
+	mu      sync.Mutex
+	files   map[string]*os.File // Open segment per tier
+	opened  map[string]time.Time
+	rollups []*historyRollup
+	sweeps  sync.WaitGroup
+	sweepMu sync.Mutex
+}
+
+var activeHistory *historyStore
+
+// setupHistory opens the store when --history is set.
+func setupHistory() {
+	if !historyEnabled {
+		return
+	}
+	if !readsThisHost() {
+		stderrLogger.Fatalf("invalid --history: only samples of this machine are kept")
+	}
+	dir, err := historyDir()
+	if err != nil {
+		stderrLogger.Fatalf("invalid --history: %v", err)
+	}
+	h, err := openHistoryStore(dir)
+	if err != nil {
+		stderrLogger.Fatalf("invalid --history: %v", err)
+	}
+	activeHistory = h
+}
+
+// stopHistory writes the partial rollups and closes the store.
+func stopHistory() {
+	if activeHistory == nil {
+		return
+	}
+	if err := activeHistory.close(); err != nil {
+		stderrLogger.Printf("history: %v\n", err)
+	}
+	activeHistory = nil
+}
+
+func historyDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".mactop", "history"), nil
+}
+
+func openHistoryStore(dir string) (*historyStore, error) {
+	h := &historyStore{dir: dir, files: make(map[string]*os.File), opened: make(map[string]time.Time)}
+	for _, tier := range historyTiers {
+		if err := os.MkdirAll(filepath.Join(dir, tier.name), 0755); err != nil {
+			return nil, err
+		}
+		if tier.step > 0 {
+			h.rollups = append(h.rollups, &historyRollup{tier: tier})
+		}
+	}
+	return h, nil
+}
+
+// recordHistory adds a published sample to the store, if any.
+func recordHistory(output HeadlessOutput, s headlessSample) {
+	if activeHistory == nil {
+		return
+	}
+	if err := activeHistory.add(historyFieldValues(output, s.thermal), time.Now()); err != nil {
+		stderrLogger.Printf("history: %v\n", err)
+	}
+}
+
+// historyFieldValues is what the store keeps of a sample: the fields alert
+// and budget rules read, less the ones historyKeeps drops.
+func historyFieldValues(output HeadlessOutput, thermal thermalStateLevel) map[string]float64 {
+	fields := alertFieldValues(output, thermal)
+	for name := range fields {
+		if !historyKeeps(name) {
+			delete(fields, name)
+		}
+	}
+	return fields
+}
+
+// historyKnownFields is every field --metric may name.
+func historyKnownFields() map[string]bool {
+	known := alertKnownFields()
+	for name := range known {
+		if !historyKeeps(name) {
+			delete(known, name)
+		}
+	}
+	return known
+}
+
+// historyKeeps reports whether the store keeps a field. The machine
+// description and schema version never change, and processes[i] is
+// whichever process ranks i-th, which is no series to chart.
+func historyKeeps(name string) bool {
+	return name != "schema_version" && !strings.HasPrefix(name, "system_info.") && !strings.HasPrefix(name, "processes[")
+}
+
+func (h *historyStore) add(fields map[string]float64, now time.Time) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	raw := historyRow{T: now.UnixMilli(), V: make(map[string]float64)}
+	for name, v := range fields {
+		if v = historyRound(v); v != 0 {
+			raw.V[name] = v
+		}
+	}
+	if err := h.append(historyTiers[0], raw); err != nil {
+		return err
+	}
+	for _, r := range h.rollups {
+		start := now.Truncate(r.tier.step)
+		if r.n > 0 && !start.Equal(r.start) {
+			if err := h.append(r.tier, r.row()); err != nil {
+				return err
+			}
+			r.n = 0
+		}
+		r.add(start, fields)
+	}
+	return nil
+}
+
+func (h *historyStore) close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var firstErr error
+	for _, r := range h.rollups {
+		if r.n > 0 {
+			if err := h.append(r.tier, r.row()); err != nil && firstErr == nil {
+				firstErr = err
+			}
+			r.n = 0
+		}
+	}
+	for name, f := range h.files {
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(h.files, name)
+	}
+	h.sweeps.Wait()
+	return firstErr
+}
+
+// append writes a row to the segment its time falls in. Moving on to a new
+// segment starts a sweep of the old ones.
+func (h *historyStore) append(tier historyTier, row historyRow) error {
+	seg := tier.segment(time.UnixMilli(row.T))
+	f := h.files[tier.name]
+	if f == nil || !h.opened[tier.name].Equal(seg) {
+		if f != nil {
+			f.Close()
+		}
+		name := filepath.Join(h.dir, tier.name, seg.Format(tier.layout)+".jsonl")
+		var err error
+		if f, err = os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+			return err
+		}
+		h.files[tier.name], h.opened[tier.name] = f, seg
+		h.sweeps.Add(1)
+		go h.sweep(tier, seg, time.Now())
+	}
+	data, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// sweep removes the tier's segments that have aged out and compresses the
+// finished ones, leaving alone the segment being written.
+func (h *historyStore) sweep(tier historyTier, current, now time.Time) {
+	defer h.sweeps.Done()
+	h.sweepMu.Lock()
+	defer h.sweepMu.Unlock()
+	segs, err := historySegments(h.dir, tier)
+	if err != nil {
+		stderrLogger.Printf("history: %v\n", err)
+		return
+	}
+	for _, seg := range segs {
+		if seg.start.Equal(current) {
+			continue
+		}
+		end := tier.next(seg.start)
+		var err error
+		switch {
+		case end.Before(now.Add(-tier.retention)):
+			err = os.Remove(seg.path)
+		case !seg.compressed && end.Before(now.Add(-historySettle)):
+			err = compressFile(seg.path, "gzip")
+		}
+		if err != nil {
+			stderrLogger.Printf("history: %v\n", err)
+		}
+	}
+}
+
+func (r *historyRollup) add(start time.Time, fields map[string]float64) {
+	if r.n == 0 {
+		r.start = start
+		r.min = make(map[string]float64, len(fields))
+		r.max = make(map[string]float64, len(fields))
+		r.sum = make(map[string]float64, len(fields))
+	}
+	for name, v := range fields {
+		// A field that first shows up partway through the step, such as
+		// display_fps once frames arrive, starts from its own value.
+		if _, ok := r.min[name]; !ok {
+			r.min[name], r.max[name] = v, v
+		}
+		r.min[name] = math.Min(r.min[name], v)
+		r.max[name] = math.Max(r.max[name], v)
+		r.sum[name] += v
+	}
+	r.n++
+}
+
+func (r *historyRollup) row() historyRow {
+	return historyRow{T: r.start.UnixMilli(), N: r.n, Min: historyNonZero(r.min), Max: historyNonZero(r.max), Sum: historyNonZero(r.sum)}
+}
+
+func historyNonZero(m map[string]float64) map[string]float64 {
+	out := make(map[string]float64, len(m))
+	for name, v := range m {
+		if v = historyRound(v); v != 0 {
+			out[name] = v
+		}
+	}
+	return out
+}
+
+// historyRound keeps three decimals, which is finer than any sensor reads.
+func historyRound(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
+
+// historySegment is one segment file on disk.
+type historySegment struct {
+	path       string
+	start      time.Time
+	compressed bool
+}
+
+// historySegments lists a tier's segments, oldest first.
+func historySegments(dir string, tier historyTier) ([]historySegment, error) {
+	entries, err := os.ReadDir(filepath.Join(dir, tier.name))
+	if err != nil {
+		return nil, err
+	}
+	var segs []historySegment
+	for _, e := range entries {
+		name := e.Name()
+		compressed := strings.HasSuffix(name, ".jsonl.gz")
+		stem, ok := strings.CutSuffix(strings.TrimSuffix(name, ".gz"), ".jsonl")
+		if !ok {
+			continue
+		}
+		start, err := time.Parse(tier.layout, stem)
+		if err != nil {
+			continue
+		}
+		segs = append(segs, historySegment{filepath.Join(dir, tier.name, name), start, compressed})
+	}
+	// ReadDir sorts by name, and the layouts sort by time.
+	return segs, nil
+}
+
+// readHistorySegment calls fn for every row of a segment. A line cut short
+// by a crash or by a concurrent append is skipped.
+func readHistorySegment(seg historySegment, fn func(historyRow)) error {
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if seg.compressed {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("%s: %w", seg.path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var row historyRow
+		if json.Unmarshal(scanner.Bytes(), &row) == nil {
+			fn(row)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fillHistoryStore writes four samples 20s apart, half an hour ago, so they
+// span two minutes of one hour, and closes the store.
+func fillHistoryStore(t *testing.T) (string, time.Time) {
+	t.Helper()
+	dir := t.TempDir()
+	h, err := openHistoryStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().Truncate(time.Hour).Add(-30 * time.Minute)
+	for i, watts := range []float64{10, 20, 30, 40} {
+		fields := map[string]float64{"package_w": watts, "soc_metrics.gpu_temp": 50 + watts, "rdma_status.available": 0}
+		if err := h.add(fields, start.Add(time.Duration(i)*20*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := h.close(); err != nil {
+		t.Fatal(err)
+	}
+	return dir, start
+}
+
+// readHistoryTier returns the rows of a tier that should hold one segment.
+func readHistoryTier(t *testing.T, dir string, tier historyTier) []historyRow {
+	t.Helper()
+	segs, err := historySegments(dir, tier)
+	if err != nil || len(segs) != 1 {
+		t.Fatalf("%s segments = %v, %v", tier.name, segs, err)
+	}
+	var rows []historyRow
+	if err := readHistorySegment(segs[0], func(r historyRow) { rows = append(rows, r) }); err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestHistoryStoreRows(t *testing.T) {
+	dir, start := fillHistoryStore(t)
+	raw := readHistoryTier(t, dir, historyTiers[0])
+	minutes := readHistoryTier(t, dir, historyTiers[1])
+	hours := readHistoryTier(t, dir, historyTiers[2])
+	if len(raw) != 4 || raw[3].V["package_w"] != 40 {
+		t.Errorf("raw rows = %+v", raw)
+	}
+	if _, ok := raw[0].V["rdma_status.available"]; ok {
+		t.Error("zero values should be left out")
+	}
+	// The first minute closes when the fourth sample arrives; the partial
+	// second minute and hour are written on close.
+	if len(minutes) != 2 || minutes[0].N != 3 || minutes[0].T != start.UnixMilli() {
+		t.Fatalf("1m rows = %+v", minutes)
+	}
+	if m := minutes[0]; m.Min["package_w"] != 10 || m.Max["package_w"] != 30 || m.Sum["package_w"] != 60 {
+		t.Errorf("first minute = %+v", m)
+	}
+	if len(hours) != 1 || hours[0].N != 4 || hours[0].Max["soc_metrics.gpu_temp"] != 90 {
+		t.Errorf("1h rows = %+v", hours)
+	}
+}
+
+func TestHistoryRollupLateField(t *testing.T) {
+	r := &historyRollup{tier: historyTiers[1]}
+	start := time.Now().Truncate(time.Minute)
+	r.add(start, map[string]float64{"package_w": 10})
+	r.add(start, map[string]float64{"package_w": 12, "display_fps": 60})
+	r.add(start, map[string]float64{"package_w": 11, "display_fps": 120})
+	if row := r.row(); row.Min["display_fps"] != 60 || row.Max["display_fps"] != 120 || row.Min["package_w"] != 10 {
+		t.Errorf("row = %+v", row)
+	}
+}
+
+func TestHistoryFieldValues(t *testing.T) {
+	output := HeadlessOutput{
+		DisplayFPS:   60,
+		Fans:         []HeadlessFan{{Name: "Left", RPM: 2400}},
+		Temperatures: []HeadlessTempGroup{{Group: "GPU", Max: 71}},
+		Processes:    []HeadlessProcess{{PID: 1, CPU: 50}},
+		SystemInfo:   SystemInfo{CoreCount: 10},
+	}
+	fields := historyFieldValues(output, thermalStateNominal)
+	for _, name := range []string{"display_fps", "fans[0].rpm", "fans[name=Left].rpm", "temperatures[group=GPU].max_celsius"} {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("%s is not kept", name)
+		}
+	}
+	for _, name := range []string{"processes[0].cpu_percent", "system_info.core_count", "schema_version"} {
+		if _, ok := fields[name]; ok {
+			t.Errorf("%s should not be kept", name)
+		}
+	}
+}
+
+func TestHistorySweep(t *testing.T) {
+	dir := t.TempDir()
+	h, err := openHistoryStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 2, 12, 30, 0, 0, time.UTC)
+	raw := historyTiers[0]
+	for _, name := range []string{"20260301-06.jsonl", "20260302-10.jsonl", "20260302-12.jsonl", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, "raw", name), []byte("{\"t\":1}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h.sweeps.Add(1)
+	h.sweep(raw, raw.segment(now), now)
+
+	entries, _ := os.ReadDir(filepath.Join(dir, "raw"))
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	// Out of retention, finished, being written, not a segment.
+	want := []string{"20260302-10.jsonl.gz", "20260302-12.jsonl", "notes.txt"}
+	if len(names) != len(want) {
+		t.Fatalf("after sweep: %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("after sweep: %v, want %v", names, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,407 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// influx.go - InfluxDB line protocol for --format influx and --influx-url
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	influxFlushInterval = 10 * time.Second
+	influxMaxBatchLines = 5000
+	influxTimeout       = 10 * time.Second
+)
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxTagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	influxStringEscaper      = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+)
+
+// influxLine builds one line of line protocol.
+type influxLine struct {
+	measurement string
+	tags        [][2]string
+	fields      []string
+}
+
+func newInfluxLine(measurement string, tags ...string) *influxLine {
+	l := &influxLine{measurement: measurement}
+	for i := 0; i+1 < len(tags); i += 2 {
+		if tags[i+1] != "" {
+			l.tags = append(l.tags, [2]string{tags[i], tags[i+1]})
+		}
+	}
+	return l
+}
+
+func (l *influxLine) float(key string, v float64) *influxLine {
+	// Line protocol has no NaN or infinity.
+	if !math.IsNaN(v) && !math.IsInf(v, 0) {
+		l.fields = append(l.fields, influxTagEscaper.Replace(key)+"="+strconv.FormatFloat(v, 'f', -1, 64))
+	}
+	return l
+}
+
+func (l *influxLine) int(key string, v int64) *influxLine {
+	l.fields = append(l.fields, influxTagEscaper.Replace(key)+"="+strconv.FormatInt(v, 10)+"i")
+	return l
+}
+
+func (l *influxLine) str(key, v string) *influxLine {
+	l.fields = append(l.fields, influxTagEscaper.Replace(key)+`="`+influxStringEscaper.Replace(v)+`"`)
+	return l
+}
+
+func (l *influxLine) bool(key string, v bool) *influxLine {
+	l.fields = append(l.fields, influxTagEscaper.Replace(key)+"="+strconv.FormatBool(v))
+	return l
+}
+
+// write appends the line to buf; tags are sorted as InfluxDB recommends.
+func (l *influxLine) write(buf *bytes.Buffer, host string, ts int64) {
+	if len(l.fields) == 0 {
+		return
+	}
+	tags := append(append([][2]string(nil), l.tags...), [2]string{"host", host})
+	sort.Slice(tags, func(i, j int) bool { return tags[i][0] < tags[j][0] })
+	buf.WriteString(influxMeasurementEscaper.Replace(l.measurement))
+	for _, t := range tags {
+		if t[1] == "" {
+			continue
+		}
+		buf.WriteByte(',')
+		buf.WriteString(influxTagEscaper.Replace(t[0]))
+		buf.WriteByte('=')
+		buf.WriteString(influxTagEscaper.Replace(t[1]))
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(strings.Join(l.fields, ","))
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.FormatInt(ts, 10))
+	buf.WriteByte('\n')
+}
+
+// formatInfluxLines renders a snapshot as line protocol with nanosecond
+// timestamps, one measurement per subsystem.
+func formatInfluxLines(o HeadlessOutput, host string, now time.Time) []byte {
+	var buf bytes.Buffer
+	ts := now.UnixNano()
+	for _, l := range influxSnapshotLines(o) {
+		l.write(&buf, host, ts)
+	}
+	return buf.Bytes()
+}
+
+// formatInfluxAggregate renders an --aggregate window as the usual lines once
+// per statistic, told apart by a stat tag and stamped with the window start.
+// Processes are the window's latest list, so they are written once, untagged.
+func formatInfluxAggregate(w *aggregateWindow, host string) []byte {
+	var buf bytes.Buffer
+	ts := w.start.UnixNano()
+	for _, stat := range aggregateStats {
+		o := w.project(stat)
+		o.Processes = nil
+		for _, l := range influxSnapshotLines(o) {
+			l.tags = append(l.tags, [2]string{"stat", stat})
+			l.write(&buf, host, ts)
+		}
+	}
+	for _, l := range influxProcessLines(w.samples[len(w.samples)-1].Processes) {
+		l.write(&buf, host, ts)
+	}
+	return buf.Bytes()
+}
+
+func influxSnapshotLines(o HeadlessOutput) []*influxLine {
+	var lines []*influxLine
+	lines = append(lines, influxComputeLines(o)...)
+	lines = append(lines, influxPowerMemoryLines(o)...)
+	lines = append(lines, influxThermalLines(o)...)
+	return append(lines, influxProcessLines(o.Processes)...)
+}
+
+func influxComputeLines(o HeadlessOutput) []*influxLine {
+	lines := []*influxLine{
+		newInfluxLine("cpu").float("usage_percent", o.CPUUsage),
+	}
+	for _, c := range []struct {
+		coreType string
+		usage    []float64
+	}{{"e", o.ECPUUsage}, {"p", o.PCPUUsage}, {"s", o.SCPUUsage}} {
+		if len(c.usage) == 2 {
+			lines = append(lines, newInfluxLine("cpu", "core_type", c.coreType).
+				float("freq_mhz", c.usage[0]).float("usage_percent", c.usage[1]))
+		}
+	}
+	for i, u := range o.CoreUsages {
+		lines = append(lines, newInfluxLine("cpu_core", "core", strconv.Itoa(i)).float("usage_percent", u))
+	}
+	lines = append(lines, newInfluxLine("gpu").
+		float("usage_percent", o.GPUUsage).
+		int("freq_mhz", int64(o.GPUMetrics.FreqMHz)).
+		float("tflops_fp32", o.TFLOPsFP32).
+		float("tflops_fp16", o.TFLOPsFP16))
+	return lines
+}
+
+func influxPowerMemoryLines(o HeadlessOutput) []*influxLine {
+	m := o.SocMetrics
+	nd := o.NetDisk
+	return []*influxLine{
+		newInfluxLine("power").
+			float("cpu_watts", m.CPUPower).
+			float("gpu_watts", m.GPUPower).
+			float("ane_watts", m.ANEPower).
+			float("dram_watts", m.DRAMPower).
+			float("gpu_sram_watts", m.GPUSRAMPower).
+			float("system_watts", m.SystemPower).
+			float("total_watts", m.TotalPower),
+		newInfluxLine("memory").
+			int("total_bytes", int64(o.Memory.Total)).
+			int("used_bytes", int64(o.Memory.Used)).
+			int("available_bytes", int64(o.Memory.Available)).
+			int("swap_total_bytes", int64(o.Memory.SwapTotal)).
+			int("swap_used_bytes", int64(o.Memory.SwapUsed)).
+			float("dram_read_gbs", m.DRAMReadBW).
+			float("dram_write_gbs", m.DRAMWriteBW),
+		newInfluxLine("net_disk").
+			float("in_bytes_per_sec", nd.InBytesPerSec).
+			float("out_bytes_per_sec", nd.OutBytesPerSec).
+			float("in_packets_per_sec", nd.InPacketsPerSec).
+			float("out_packets_per_sec", nd.OutPacketsPerSec).
+			float("read_kbytes_per_sec", nd.ReadKBytesPerSec).
+			float("write_kbytes_per_sec", nd.WriteKBytesPerSec).
+			float("read_ops_per_sec", nd.ReadOpsPerSec).
+			float("write_ops_per_sec", nd.WriteOpsPerSec).
+			float("tb_in_bytes_per_sec", o.TBNetTotalBytesInSec).
+			float("tb_out_bytes_per_sec", o.TBNetTotalBytesOutSec).
+			bool("rdma_available", o.RDMAStatus.Available),
+	}
+}
+
+func influxThermalLines(o HeadlessOutput) []*influxLine {
+	m := o.SocMetrics
+	lines := []*influxLine{
+		newInfluxLine("thermal").
+			float("cpu_celsius", float64(m.CPUTemp)).
+			float("gpu_celsius", float64(m.GPUTemp)).
+			float("soc_celsius", float64(m.SocTemp)).
+			str("state", o.ThermalState),
+	}
+	for _, g := range o.Temperatures {
+		lines = append(lines, newInfluxLine("temperature", "group", g.Group).
+			float("avg_celsius", g.Avg).
+			float("min_celsius", g.Min).
+			float("max_celsius", g.Max).
+			int("sensor_count", int64(g.Sensors)))
+	}
+	for _, f := range o.Fans {
+		lines = append(lines, newInfluxLine("fan", "fan", f.Name, "fan_id", strconv.Itoa(f.ID)).
+			int("rpm", int64(f.RPM)).
+			int("target_rpm", int64(f.TargetRPM)).
+			int("min_rpm", int64(f.MinRPM)).
+			int("max_rpm", int64(f.MaxRPM)).
+			str("mode", f.Mode))
+	}
+	return lines
+}
+
+// processTotal is every process sharing one command, summed.
+type processTotal struct {
+	Command          string
+	CPU, GPU, Memory float64
+	Watts, Joules    float64
+	RSS, Count       int64
+}
+
+// sumProcessesByCommand groups processes by command, keeping the order in
+// which each command first appears. PIDs churn, so labelling a series by
+// PID would create a new series for every process ever seen.
+func sumProcessesByCommand(processes []HeadlessProcess) []processTotal {
+	index := make(map[string]int)
+	var totals []processTotal
+	for _, p := range processes {
+		i, ok := index[p.Command]
+		if !ok {
+			i = len(totals)
+			index[p.Command] = i
+			totals = append(totals, processTotal{Command: p.Command})
+		}
+		t := &totals[i]
+		t.CPU += p.CPU
+		t.GPU += p.GPU
+		t.Memory += p.Memory
+		t.Watts += p.Watts
+		t.Joules += p.Joules
+		t.RSS += p.RSS
+		t.Count++
+	}
+	return totals
+}
+
+func influxProcessLines(processes []HeadlessProcess) []*influxLine {
+	totals := sumProcessesByCommand(processes)
+	lines := make([]*influxLine, 0, len(totals))
+	for _, t := range totals {
+		lines = append(lines, newInfluxLine("process", "command", t.Command).
+			float("cpu_percent", t.CPU).
+			float("gpu_ms_per_sec", t.GPU).
+			float("memory_percent", t.Memory).
+			float("watts", t.Watts).
+			float("energy_joules", t.Joules).
+			int("rss_kb", t.RSS).
+			int("count", t.Count))
+	}
+	return lines
+}
+
+// influxWriter batches line protocol and POSTs it to an InfluxDB v2 write
+// endpoint every influxFlushInterval, or sooner when the batch grows large.
+type influxWriter struct {
+	url   string
+	token string
+	host  string
+
+	mu      sync.Mutex
+	batch   bytes.Buffer
+	lines   int
+	flushCh chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	failing bool
+	client  *http.Client
+}
+
+var activeInflux *influxWriter
+
+// setupInflux starts the writer when --influx-url is set. The token comes
+// from INFLUX_TOKEN, as with the influx CLI.
+func setupInflux() {
+	if influxURL == "" {
+		return
+	}
+	w, err := newInfluxWriter(influxURL, os.Getenv("INFLUX_TOKEN"))
+	if err != nil {
+		stderrLogger.Fatalf("invalid --influx-url: %v", err)
+	}
+	activeInflux = w
+	go w.run()
+}
+
+// stopInflux sends whatever is still batched.
+func stopInflux() {
+	if activeInflux == nil {
+		return
+	}
+	close(activeInflux.done)
+	<-activeInflux.stopped
+	activeInflux = nil
+}
+
+func newInfluxWriter(rawURL, token string) (*influxWriter, error) {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/api/v2/write"
+	}
+	q := u.Query()
+	if q.Get("bucket") == "" {
+		return nil, fmt.Errorf("%s: missing ?bucket= (and ?org= for InfluxDB 2.x)", rawURL)
+	}
+	q.Set("precision", "ns")
+	u.RawQuery = q.Encode()
+	host, _ := os.Hostname()
+	return &influxWriter{
+		url:     u.String(),
+		token:   token,
+		host:    host,
+		flushCh: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		client:  &http.Client{Timeout: influxTimeout},
+	}, nil
+}
+
+// exportInflux adds a sample to the active writer's batch, if any.
+func exportInflux(output HeadlessOutput) {
+	w := activeInflux
+	if w == nil {
+		return
+	}
+	data := formatInfluxLines(output, w.host, time.Now())
+	w.mu.Lock()
+	w.batch.Write(data)
+	w.lines += bytes.Count(data, []byte{'\n'})
+	full := w.lines >= influxMaxBatchLines
+	w.mu.Unlock()
+	if full {
+		select {
+		case w.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (w *influxWriter) run() {
+	defer close(w.stopped)
+	ticker := time.NewTicker(influxFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			w.flush()
+			return
+		case <-ticker.C:
+		case <-w.flushCh:
+		}
+		w.flush()
+	}
+}
+
+func (w *influxWriter) flush() {
+	w.mu.Lock()
+	body := bytes.Clone(w.batch.Bytes())
+	w.batch.Reset()
+	w.lines = 0
+	w.mu.Unlock()
+	if len(body) == 0 {
+		return
+	}
+	logExportTransition(&w.failing, w.post(body), "influx", w.url)
+}
+
+func (w *influxWriter) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.token != "" {
+		req.Header.Set("Authorization", "Token "+w.token)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("POST %s: %s", w.url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatInfluxLines(t *testing.T) {
+	output := HeadlessOutput{
+		CPUUsage:     12.5,
+		PCPUUsage:    []float64{4512, 30},
+		Temperatures: []HeadlessTempGroup{{Group: "CPU Cores", Avg: 55, Min: 50, Max: 61, Sensors: 8}},
+		Fans:         []HeadlessFan{{ID: 0, Name: "Left Fan", RPM: 2400, Mode: "auto"}},
+		Processes: []HeadlessProcess{
+			{PID: 10, Command: "ollama", CPU: 50, GPU: 300, RSS: 1000},
+			{PID: 11, Command: "ollama", CPU: 25, GPU: 200, RSS: 500},
+		},
+		ThermalState: `Fair "hot"`,
+	}
+	got := string(formatInfluxLines(output, "mac,1", time.Unix(0, 42)))
+
+	for _, want := range []string{
+		`cpu,host=mac\,1 usage_percent=12.5 42`,
+		`cpu,core_type=p,host=mac\,1 freq_mhz=4512,usage_percent=30 42`,
+		`temperature,group=CPU\ Cores,host=mac\,1 avg_celsius=55,min_celsius=50,max_celsius=61,sensor_count=8i 42`,
+		`fan,fan=Left\ Fan,fan_id=0,host=mac\,1 rpm=2400i,target_rpm=0i,min_rpm=0i,max_rpm=0i,mode="auto" 42`,
+		`process,command=ollama,host=mac\,1 cpu_percent=75,gpu_ms_per_sec=500,memory_percent=0,watts=0,energy_joules=0,rss_kb=1500i,count=2i 42`,
+		`state="Fair \"hot\""`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("line protocol missing %q\ngot:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "core_type=e") {
+		t.Errorf("line protocol has an E-cluster line for a chip without E-cores:\n%s", got)
+	}
+}
+
+func TestInfluxWriterFlushesOnStop(t *testing.T) {
+	var gotQuery, gotAuth, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Path + "?" + r.URL.RawQuery
+		gotAuth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	if _, err := newInfluxWriter(srv.URL, ""); err == nil {
+		t.Error("newInfluxWriter() without a bucket: want an error")
+	}
+	w, err := newInfluxWriter(srv.URL+"?org=lab&bucket=mactop", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := activeInflux
+	t.Cleanup(func() { activeInflux = prev })
+	activeInflux = w
+	go w.run()
+
+	exportInflux(HeadlessOutput{CPUUsage: 1})
+	exportInflux(HeadlessOutput{CPUUsage: 2})
+	stopInflux()
+
+	if gotQuery != "/api/v2/write?bucket=mactop&org=lab&precision=ns" {
+		t.Errorf("request = %s, want the v2 write path with precision=ns", gotQuery)
+	}
+	if gotAuth != "Token secret" {
+		t.Errorf("Authorization = %q, want %q", gotAuth, "Token secret")
+	}
+	if strings.Count(gotBody, "cpu,host=") != 2 {
+		t.Errorf("body has %d cpu lines, want both samples in one batch:\n%s", strings.Count(gotBody, "cpu,host="), gotBody)
+	}
+}
@@ -399,9 +399,9 @@ func fanRPMBar(fan FanInfo, themeColor string) []string {
 
 	rpmColor := themeColor
 	if pct > 80 {
-		rpmColor = "red"
+		rpmColor = CriticalColor
 	} else if pct > 50 {
-		rpmColor = "yellow"
+		rpmColor = WarningColor
 	}
 
 	return []string{
@@ -666,9 +666,9 @@ func formatTempGroupLine(cat string, g *tempGroup, themeColor string) string {
 	avg := g.sum / float64(g.count)
 	tempColor := themeColor
 	if avg > 90 {
-		tempColor = "red"
+		tempColor = CriticalColor
 	} else if avg > 70 {
-		tempColor = "yellow"
+		tempColor = WarningColor
 	}
 	displayName := localizeTempCategory(cat, g.count > 1)
 	if g.count == 1 {
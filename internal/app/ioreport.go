@@ -1,3 +1,5 @@
+//go:build darwin
+
 // Copyright (c) 2024-2026 Carsen Klock under MIT License
 // ioreport.go - Go wrappers for IOReport power/thermal metrics
 package app
@@ -63,6 +65,8 @@ typedef struct {
 
 typedef struct {
     double cpuPower;
+    double eCPUPower;
+    double pCPUPower;
     double gpuPower;
     double anePower;
     double dramPower;
@@ -115,51 +119,7 @@ int get_wifi_link_info(wifi_link_info_t *info);
 */
 import "C"
 
-// FanInfo represents a single system fan's state
-type FanInfo struct {
-	ID        int    `json:"id"`
-	Name      string `json:"name"`
-	ActualRPM int    `json:"actual_rpm"`
-	MinRPM    int    `json:"min_rpm"`
-	MaxRPM    int    `json:"max_rpm"`
-	TargetRPM int    `json:"target_rpm"`
-	Mode      int    `json:"mode"` // 0=auto, 1=forced
-}
-
-// TempSensor represents a single temperature sensor reading
-type TempSensor struct {
-	Key   string  `json:"key"`
-	Name  string  `json:"name"`
-	Value float64 `json:"value"`
-}
-
-type SocMetrics struct {
-	CPUPower        float64      `json:"cpu_power"`
-	GPUPower        float64      `json:"gpu_power"`
-	ANEPower        float64      `json:"ane_power"`
-	DRAMPower       float64      `json:"dram_power"`
-	GPUSRAMPower    float64      `json:"gpu_sram_power"`
-	SystemPower     float64      `json:"system_power"`
-	TotalPower      float64      `json:"total_power"`
-	GPUFreqMHz      int32        `json:"gpu_freq_mhz"`
-	GPUActive       float64      `json:"gpu_active"`
-	EClusterActive  float64      `json:"e_cluster_active"`
-	PClusterActive  float64      `json:"p_cluster_active"`
-	SClusterActive  float64      `json:"s_cluster_active,omitempty"`
-	EClusterFreqMHz int32        `json:"e_cluster_freq_mhz"`
-	PClusterFreqMHz int32        `json:"p_cluster_freq_mhz"`
-	SClusterFreqMHz int32        `json:"s_cluster_freq_mhz,omitempty"`
-	SocTemp         float32      `json:"soc_temp"`
-	CPUTemp         float32      `json:"cpu_temp"`
-	GPUTemp         float32      `json:"gpu_temp"`
-	DRAMReadBW      float64      `json:"dram_read_bw_gbs"`
-	DRAMWriteBW     float64      `json:"dram_write_bw_gbs"`
-	DRAMBWCombined  float64      `json:"dram_bw_combined_gbs"`
-	Fans            []FanInfo    `json:"-"`
-	TempSensors     []TempSensor `json:"-"`
-}
-
-func initSocMetrics() error {
+func initIOReportMetrics() error {
 	// Pass expected core counts to C for HID sensor validation.
 	// HID per-core sensors are only used when count >= expected physical cores.
 	sysInfo := getSOCInfo()
@@ -176,7 +136,7 @@ func DumpIOReportDebug() {
 	C.dumpIOReportDebug()
 }
 
-func sampleSocMetrics(durationMs int) SocMetrics {
+func sampleIOReportMetrics(durationMs int) SocMetrics {
 	pm := C.samplePowerMetrics(C.int(durationMs))
 
 	var dramReadBW, dramWriteBW, dramBWCombined float64
@@ -214,6 +174,8 @@ func sampleSocMetrics(durationMs int) SocMetrics {
 
 	return SocMetrics{
 		CPUPower:        float64(pm.cpuPower),
+		ECPUPower:       float64(pm.eCPUPower),
+		PCPUPower:       float64(pm.pCPUPower),
 		GPUPower:        float64(pm.gpuPower),
 		ANEPower:        float64(pm.anePower),
 		DRAMPower:       float64(pm.dramPower),
@@ -239,7 +201,7 @@ func sampleSocMetrics(durationMs int) SocMetrics {
 	}
 }
 
-func cleanupSocMetrics() {
+func cleanupIOReportMetrics() {
 	C.cleanupIOReport()
 }
 
@@ -293,15 +255,6 @@ func DumpAllSMCTemps() {
 	C.dumpAllSMCTemps()
 }
 
-// WiFiLinkInfo represents Wi-Fi interface link information
-type WiFiLinkInfo struct {
-	InterfaceName  string // Interface name (en0, en1, etc.)
-	PHYMode        string // "802.11n", "802.11ac", "802.11ax", etc.
-	WiFiGeneration string // "Wi-Fi 4", "Wi-Fi 5", "Wi-Fi 6", etc.
-	TxRateMbps     int    // Current transmit rate in Mbps
-	IsConnected    bool   // True if associated to a network
-}
-
 // GetWiFiLinkInfo returns Wi-Fi link information
 func GetWiFiLinkInfo() *WiFiLinkInfo {
 	var info C.wifi_link_info_t
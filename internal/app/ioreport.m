@@ -1,3 +1,5 @@
+//go:build darwin
+
 // Copyright (c) 2024-2026 Carsen Klock under MIT License
 // ioreport.m - Objective-C implementation for IOReport power/thermal metrics
 
@@ -1223,6 +1225,8 @@ void dumpIOReportDebug(void) {
 }
 typedef struct {
   double cpuPower;
+  double eCPUPower;
+  double pCPUPower;
   double gpuPower;
   double anePower;
   double dramPower;
@@ -2109,6 +2113,14 @@ PowerMetrics samplePowerMetrics(int durationMs) {
 
       if (strstr(chn, "CPU Energy") != NULL) {
         metrics.cpuPower += watts;
+        // Per-cluster channels carry the same names as the residency
+        // channels below. MCPU (M5+) and SCPU are performance cores too.
+        if (strstr(chn, "ECPU") != NULL || strstr(chn, "EACC") != NULL) {
+          metrics.eCPUPower += watts;
+        } else if (strstr(chn, "PCPU") != NULL || strstr(chn, "MCPU") != NULL ||
+                   strstr(chn, "SCPU") != NULL || strstr(chn, "PACC") != NULL) {
+          metrics.pCPUPower += watts;
+        }
       } else if (strcmp(chn, "GPU Energy") == 0) {
         metrics.gpuPower += watts;
       } else if (strncmp(chn, "ANE", 3) == 0) {
@@ -27,11 +27,16 @@ const (
 	LayoutHistory         = "history"      // StepChart history for GPU, Power, and Memory
 	LayoutHistoryFull     = "history_full" // StepChart history including CPU
 	LayoutFan             = "fan"          // Fan control and temperature sensors
+	LayoutCluster         = "cluster"      // Per-node table for mactop cluster
 )
 
 var layoutOrder = []string{LayoutDefault, LayoutAlternative, LayoutAlternativeFull, LayoutVertical, LayoutCompact, LayoutDashboard, LayoutGaugesOnly, LayoutGPUFocus, LayoutCPUFocus, LayoutNetworkIO, LayoutSmall, LayoutTiny, LayoutMicro, LayoutNano, LayoutPico, LayoutHistory, LayoutHistoryFull, LayoutFan}
 
 func setupGrid() {
+	// A saved "cluster" layout only makes sense while running mactop cluster.
+	if currentConfig.DefaultLayout == LayoutCluster && len(clusterNodes) == 0 {
+		currentConfig.DefaultLayout = LayoutDefault
+	}
 	totalLayouts = len(layoutOrder)
 	for i, layout := range layoutOrder {
 		if layout == currentConfig.DefaultLayout {
@@ -252,7 +257,7 @@ func setLayoutGrid(layoutName string) {
 		)
 	case LayoutTiny, LayoutMicro, LayoutNano, LayoutPico:
 		setCompactLayoutGrid(layoutName)
-	case LayoutInfo, LayoutFan:
+	case LayoutInfo, LayoutFan, LayoutCluster:
 		setInfoFanLayoutGrid(layoutName)
 	case LayoutHistory:
 		setHistoryLayoutGrid()
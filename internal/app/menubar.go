@@ -1,3 +1,5 @@
+//go:build darwin
+
 // Copyright (c) 2024-2026 Carsen Klock under MIT License
 // menubar.go - Go wrappers for native macOS menu bar status item
 package app
@@ -1,49 +1,9 @@
 package app
 
 import (
-	"fmt"
-	"net/http"
 	"time"
-
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func startPrometheusServer(port string) {
-	registry := prometheus.NewRegistry()
-	registry.MustRegister(cpuUsage)
-	registry.MustRegister(ecoreUsage)
-	registry.MustRegister(pcoreUsage)
-	registry.MustRegister(gpuUsage)
-	registry.MustRegister(gpuFreqMHz)
-	registry.MustRegister(powerUsage)
-	registry.MustRegister(socTemp)
-	registry.MustRegister(gpuTemp)
-	registry.MustRegister(thermalState)
-	registry.MustRegister(memoryUsage)
-	registry.MustRegister(networkSpeed)
-	registry.MustRegister(diskIOSpeed)
-	registry.MustRegister(diskIOPS)
-	registry.MustRegister(tbNetworkSpeed)
-	registry.MustRegister(rdmaAvailable)
-	registry.MustRegister(scoreUsage)
-	registry.MustRegister(dramBandwidth)
-	registry.MustRegister(cpuCoreUsage)
-	registry.MustRegister(systemInfoGauge)
-	registry.MustRegister(fanRPM)
-	registry.MustRegister(tempSensorGauge)
-
-	handler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
-
-	http.Handle("/metrics", handler)
-	go func() {
-		err := http.ListenAndServe(":"+port, nil)
-		if err != nil {
-			stderrLogger.Printf("Failed to start Prometheus metrics server: %v\n", err)
-		}
-	}()
-}
-
 func GetCPUPercentages() ([]float64, error) {
 	currentTimes, err := GetCPUUsage()
 	if err != nil {
@@ -79,6 +39,9 @@ func GetCPUPercentages() ([]float64, error) {
 }
 
 func getNetDiskMetrics() NetDiskMetrics {
+	if src, ok := activeBackend.(snapshotSource); ok {
+		return src.NetDisk()
+	}
 	var metrics NetDiskMetrics
 
 	netDiskMutex.Lock()
@@ -132,13 +95,6 @@ func getNetDiskMetrics() NetDiskMetrics {
 		lastDiskStats = totalDisk
 	}
 
-	networkSpeed.With(prometheus.Labels{"direction": "upload"}).Set(metrics.OutBytesPerSec)
-	networkSpeed.With(prometheus.Labels{"direction": "download"}).Set(metrics.InBytesPerSec)
-	diskIOSpeed.With(prometheus.Labels{"operation": "read"}).Set(metrics.ReadKBytesPerSec * 1024)
-	diskIOSpeed.With(prometheus.Labels{"operation": "write"}).Set(metrics.WriteKBytesPerSec * 1024)
-	diskIOPS.With(prometheus.Labels{"operation": "read"}).Set(metrics.ReadOpsPerSec)
-	diskIOPS.With(prometheus.Labels{"operation": "write"}).Set(metrics.WriteOpsPerSec)
-
 	lastNetDiskTime = now
 	return metrics
 }
@@ -209,6 +165,7 @@ func collectMetrics(done chan struct{}, cpumetricsChan chan CPUMetrics, gpumetri
 		}
 
 		m := sampleSocMetrics(sampleDuration / 2)
+		sessionEnergy.add(m, time.Now())
 
 		thermalStr, throttled := getThermalStateString()
 		rdmaStat := CheckRDMAAvailable().Status
@@ -264,12 +221,11 @@ func collectMetrics(done chan struct{}, cpumetricsChan chan CPUMetrics, gpumetri
 			Temp:          m.GPUTemp,
 		}
 
-		// Update fan and temp sensor Prometheus metrics
-		updatePrometheusSensors(m.Fans, m.TempSensors)
-
-		if dispatchMetrics(done, cpumetricsChan, gpumetricsChan, tbNetStatsChan, triggerProcessCollectionChan, cpuMetrics, gpuMetrics, GetThunderboltNetStats()) {
+		tbNetStats := GetThunderboltNetStats()
+		if dispatchMetrics(done, cpumetricsChan, gpumetricsChan, tbNetStatsChan, triggerProcessCollectionChan, cpuMetrics, gpuMetrics, tbNetStats) {
 			return
 		}
+		publishTUISample(m, coreUsages, tbNetStats, sysInfo)
 
 		// Push to menubar worker — snapshot net metrics under lock to avoid race
 		if menubar {
@@ -298,15 +254,6 @@ func collectMetrics(done chan struct{}, cpumetricsChan chan CPUMetrics, gpumetri
 	}
 }
 
-func updatePrometheusSensors(fans []FanInfo, sensors []TempSensor) {
-	for _, fan := range fans {
-		fanRPM.With(prometheus.Labels{"fan_id": fmt.Sprintf("%d", fan.ID), "fan_name": fan.Name}).Set(float64(fan.ActualRPM))
-	}
-	for _, sensor := range sensors {
-		tempSensorGauge.With(prometheus.Labels{"key": sensor.Key, "name": sensor.Name}).Set(sensor.Value)
-	}
-}
-
 func collectProcessMetrics(done chan struct{}, processMetricsChan chan []ProcessMetrics, triggerChan chan struct{}) {
 	for {
 		select {
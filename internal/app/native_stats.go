@@ -1,3 +1,5 @@
+//go:build darwin
+
 package app
 
 /*
@@ -829,14 +831,6 @@ import (
 	"unsafe"
 )
 
-type NativeMemoryMetrics struct {
-	Total     uint64
-	Used      uint64
-	Available uint64
-	SwapTotal uint64
-	SwapUsed  uint64
-}
-
 var (
 	pageSize    uint64
 	totalMemory uint64
@@ -865,7 +859,7 @@ func initNativeStats() error {
 	return nil
 }
 
-func GetNativeMemoryMetrics() (NativeMemoryMetrics, error) {
+func getMachMemoryMetrics() (NativeMemoryMetrics, error) {
 	if totalMemory == 0 {
 		if err := initNativeStats(); err != nil {
 			return NativeMemoryMetrics{}, err
@@ -911,21 +905,6 @@ func GetNativeMemoryMetrics() (NativeMemoryMetrics, error) {
 	}, nil
 }
 
-// NativeDiskUsage represents filesystem usage
-type NativeDiskUsage struct {
-	Total       uint64
-	Used        uint64
-	Free        uint64
-	UsedPercent float64
-}
-
-// NativePartitionInfo represents a mounted partition
-type NativePartitionInfo struct {
-	Device     string
-	Mountpoint string
-	Fstype     string
-}
-
 // GetNativeUptime returns the system uptime in seconds
 func GetNativeUptime() (uint64, error) {
 	var boottime C.struct_timeval
@@ -1000,17 +979,8 @@ func GetNativeDiskUsage(path string) (NativeDiskUsage, error) {
 	}, nil
 }
 
-// NativeNetMetric represents network interface statistics
-type NativeNetMetric struct {
-	Name        string
-	BytesSent   uint64
-	BytesRecv   uint64
-	PacketsSent uint64
-	PacketsRecv uint64
-}
-
-// GetNativeNetworkMetrics returns network statistics for all interfaces
-func GetNativeNetworkMetrics() (map[string]NativeNetMetric, error) {
+// getIfaddrsNetworkMetrics returns network statistics for all interfaces
+func getIfaddrsNetworkMetrics() (map[string]NativeNetMetric, error) {
 	var ifap *C.struct_ifaddrs
 	if C.getifaddrs(&ifap) != 0 {
 		return nil, fmt.Errorf("getifaddrs failed")
@@ -1056,19 +1026,8 @@ func GetNativeNetworkMetrics() (map[string]NativeNetMetric, error) {
 	return metrics, nil
 }
 
-// NativeDiskMetric represents disk I/O statistics
-type NativeDiskMetric struct {
-	Name       string
-	ReadBytes  uint64
-	WriteBytes uint64
-	ReadOps    uint64
-	WriteOps   uint64
-	ReadTime   uint64
-	WriteTime  uint64
-}
-
-// GetNativeDiskMetrics returns disk I/O statistics
-func GetNativeDiskMetrics() (map[string]NativeDiskMetric, error) {
+// getIOKitDiskMetrics returns disk I/O statistics
+func getIOKitDiskMetrics() (map[string]NativeDiskMetric, error) {
 	maxStats := 32 // Reasonable limit for internal disks
 	stats := make([]C.disk_stat_t, maxStats)
 
@@ -1098,15 +1057,6 @@ func GetNativeDiskMetrics() (map[string]NativeDiskMetric, error) {
 	return result, nil
 }
 
-// NativeHostInfo represents host information
-type NativeHostInfo struct {
-	Hostname      string
-	OSVersion     string
-	KernelVersion string
-	Uptime        uint64
-	BootTime      uint64
-}
-
 func getSysctlString(name string) (string, error) {
 	cName := C.CString(name)
 	defer C.free(unsafe.Pointer(cName))
@@ -1147,23 +1097,6 @@ func GetNativeHostInfo() (NativeHostInfo, error) {
 	}, nil
 }
 
-// CoreType represents the type of CPU core
-type CoreType int
-
-const (
-	CoreTypeUnknown CoreType = 0
-	CoreTypeE       CoreType = 1 // Efficiency core
-	CoreTypeP       CoreType = 2 // Performance core
-	CoreTypeS       CoreType = 3 // Super core (M5+)
-	CoreTypeM       CoreType = 4 // Medium core (M5+ IORegistry "M" type = Performance tier)
-)
-
-// CoreTopologyEntry represents a single CPU core's topology information
-type CoreTopologyEntry struct {
-	CPUID    int
-	CoreType CoreType
-}
-
 // GetCoreTopology returns the core topology detected from IORegistry.
 // This is the authoritative source for E-core vs P-core identification
 // and works across all M-series chips without hardcoding.
@@ -1286,12 +1219,6 @@ func BuildCoreLabels() ([]string, int, int, int, []int) {
 	return labels, len(eCores), len(pCores), len(sCores), cpuIndexMap
 }
 
-// GPUProcessStat represents per-process GPU usage
-type GPUProcessStat struct {
-	PID       int
-	GPUTimeNs uint64 // accumulated GPU time in nanoseconds
-}
-
 // GetGPUProcessStats returns per-process GPU statistics from IOKit AGXDeviceUserClient
 func GetGPUProcessStats() map[int]uint64 {
 	maxStats := 256
@@ -1321,22 +1248,6 @@ func GetMaxGPUFrequency() int {
 	return int(C.get_max_gpu_freq())
 }
 
-type ThunderboltSwitchInfo struct {
-	UID                uint64
-	ParentUID          uint64
-	RouterID           int
-	VendorID           int
-	DeviceID           int
-	VendorName         string
-	DeviceName         string
-	PortCount          int
-	Depth              int
-	ThunderboltVersion int
-	LinkSpeed          uint64 // Supported Link Speed (capability)
-	CurrentSpeed       uint64 // Current Link Speed (negotiated)
-	LinkWidth          int
-}
-
 func GetThunderboltSwitchesIOKit() []ThunderboltSwitchInfo {
 	maxSwitches := 32
 	switches := make([]C.tb_switch_info_t, maxSwitches)
@@ -1366,15 +1277,6 @@ func GetThunderboltSwitchesIOKit() []ThunderboltSwitchInfo {
 	return result
 }
 
-type USBDeviceInfo struct {
-	VendorID    int
-	ProductID   int
-	LocationID  uint32
-	VendorName  string
-	ProductName string
-	Serial      string
-}
-
 func GetUSBDevicesIOKit() []USBDeviceInfo {
 	maxDevices := 64
 	devices := make([]C.usb_device_info_t, maxDevices)
@@ -1397,16 +1299,6 @@ func GetUSBDevicesIOKit() []USBDeviceInfo {
 	return result
 }
 
-type StorageDeviceInfo struct {
-	Name       string
-	BSDName    string
-	Protocol   string
-	MediumType string
-	IsInternal bool
-	IsWhole    bool
-	SizeBytes  uint64
-}
-
 func GetStorageDevicesIOKit() []StorageDeviceInfo {
 	maxDevices := 32
 	devices := make([]C.storage_device_info_t, maxDevices)
@@ -1430,14 +1322,6 @@ func GetStorageDevicesIOKit() []StorageDeviceInfo {
 	return result
 }
 
-// EthernetLinkInfo represents Ethernet interface link information
-type EthernetLinkInfo struct {
-	Name          string // Interface name (en0, en1, etc.)
-	LinkUp        bool   // True if link is up
-	LinkSpeedMbps uint64 // Negotiated speed in Mbps
-	MediaType     string // Raw media type string (e.g., "1000baseT")
-}
-
 // GetEthernetLinkInfo returns link information for all Ethernet interfaces
 func GetEthernetLinkInfo() []EthernetLinkInfo {
 	maxInfos := 16
@@ -1458,20 +1342,3 @@ func GetEthernetLinkInfo() []EthernetLinkInfo {
 	}
 	return result
 }
-
-// FormatLinkSpeed formats Mbps to human-readable string (e.g., "1GbE", "100Mbps")
-func FormatLinkSpeed(mbps uint64) string {
-	switch {
-	case mbps >= 10000:
-		return fmt.Sprintf("%.0fGbE", float64(mbps)/1000)
-	case mbps >= 1000:
-		if mbps%1000 == 0 {
-			return fmt.Sprintf("%dGbE", mbps/1000)
-		}
-		return fmt.Sprintf("%.1fGbE", float64(mbps)/1000)
-	case mbps > 0:
-		return fmt.Sprintf("%dMbps", mbps)
-	default:
-		return "--"
-	}
-}
@@ -0,0 +1,201 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// native_types.go - Platform-neutral types filled in by the native collectors
+package app
+
+import "fmt"
+
+// FanInfo represents a single system fan's state
+type FanInfo struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	ActualRPM int    `json:"actual_rpm"`
+	MinRPM    int    `json:"min_rpm"`
+	MaxRPM    int    `json:"max_rpm"`
+	TargetRPM int    `json:"target_rpm"`
+	Mode      int    `json:"mode"` // 0=auto, 1=forced
+}
+
+// TempSensor represents a single temperature sensor reading
+type TempSensor struct {
+	Key   string  `json:"key"`
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+}
+
+type SocMetrics struct {
+	CPUPower        float64      `json:"cpu_power"`
+	ECPUPower       float64      `json:"-" yaml:"-" xml:"-" toon:"-"` // E-cluster part of CPUPower, where the Energy Model has one
+	PCPUPower       float64      `json:"-" yaml:"-" xml:"-" toon:"-"` // P-cluster part, super cores included
+	GPUPower        float64      `json:"gpu_power"`
+	ANEPower        float64      `json:"ane_power"`
+	DRAMPower       float64      `json:"dram_power"`
+	GPUSRAMPower    float64      `json:"gpu_sram_power"`
+	SystemPower     float64      `json:"system_power"`
+	TotalPower      float64      `json:"total_power"`
+	GPUFreqMHz      int32        `json:"gpu_freq_mhz"`
+	GPUActive       float64      `json:"gpu_active"`
+	EClusterActive  float64      `json:"e_cluster_active"`
+	PClusterActive  float64      `json:"p_cluster_active"`
+	SClusterActive  float64      `json:"s_cluster_active,omitempty"`
+	EClusterFreqMHz int32        `json:"e_cluster_freq_mhz"`
+	PClusterFreqMHz int32        `json:"p_cluster_freq_mhz"`
+	SClusterFreqMHz int32        `json:"s_cluster_freq_mhz,omitempty"`
+	SocTemp         float32      `json:"soc_temp"`
+	CPUTemp         float32      `json:"cpu_temp"`
+	GPUTemp         float32      `json:"gpu_temp"`
+	DRAMReadBW      float64      `json:"dram_read_bw_gbs"`
+	DRAMWriteBW     float64      `json:"dram_write_bw_gbs"`
+	DRAMBWCombined  float64      `json:"dram_bw_combined_gbs"`
+	Fans            []FanInfo    `json:"-"`
+	TempSensors     []TempSensor `json:"-"`
+}
+
+type NativeMemoryMetrics struct {
+	Total     uint64
+	Used      uint64
+	Available uint64
+	SwapTotal uint64
+	SwapUsed  uint64
+}
+
+// NativeDiskUsage represents filesystem usage
+type NativeDiskUsage struct {
+	Total       uint64
+	Used        uint64
+	Free        uint64
+	UsedPercent float64
+}
+
+// NativePartitionInfo represents a mounted partition
+type NativePartitionInfo struct {
+	Device     string
+	Mountpoint string
+	Fstype     string
+}
+
+// NativeNetMetric represents network interface statistics
+type NativeNetMetric struct {
+	Name        string
+	BytesSent   uint64
+	BytesRecv   uint64
+	PacketsSent uint64
+	PacketsRecv uint64
+}
+
+// NativeDiskMetric represents disk I/O statistics
+type NativeDiskMetric struct {
+	Name       string
+	ReadBytes  uint64
+	WriteBytes uint64
+	ReadOps    uint64
+	WriteOps   uint64
+	ReadTime   uint64
+	WriteTime  uint64
+}
+
+// NativeHostInfo represents host information
+type NativeHostInfo struct {
+	Hostname      string
+	OSVersion     string
+	KernelVersion string
+	Uptime        uint64
+	BootTime      uint64
+}
+
+// CoreType represents the type of CPU core
+type CoreType int
+
+const (
+	CoreTypeUnknown CoreType = 0
+	CoreTypeE       CoreType = 1 // Efficiency core
+	CoreTypeP       CoreType = 2 // Performance core
+	CoreTypeS       CoreType = 3 // Super core (M5+)
+	CoreTypeM       CoreType = 4 // Medium core (M5+ IORegistry "M" type = Performance tier)
+)
+
+// CoreTopologyEntry represents a single CPU core's topology information
+type CoreTopologyEntry struct {
+	CPUID    int
+	CoreType CoreType
+}
+
+// GPUProcessStat represents per-process GPU usage
+type GPUProcessStat struct {
+	PID       int
+	GPUTimeNs uint64 // accumulated GPU time in nanoseconds
+}
+
+type ThunderboltSwitchInfo struct {
+	UID                uint64
+	ParentUID          uint64
+	RouterID           int
+	VendorID           int
+	DeviceID           int
+	VendorName         string
+	DeviceName         string
+	PortCount          int
+	Depth              int
+	ThunderboltVersion int
+	LinkSpeed          uint64 // Supported Link Speed (capability)
+	CurrentSpeed       uint64 // Current Link Speed (negotiated)
+	LinkWidth          int
+}
+
+type USBDeviceInfo struct {
+	VendorID    int
+	ProductID   int
+	LocationID  uint32
+	VendorName  string
+	ProductName string
+	Serial      string
+}
+
+type StorageDeviceInfo struct {
+	Name       string
+	BSDName    string
+	Protocol   string
+	MediumType string
+	IsInternal bool
+	IsWhole    bool
+	SizeBytes  uint64
+}
+
+// EthernetLinkInfo represents Ethernet interface link information
+type EthernetLinkInfo struct {
+	Name          string // Interface name (en0, en1, etc.)
+	LinkUp        bool   // True if link is up
+	LinkSpeedMbps uint64 // Negotiated speed in Mbps
+	MediaType     string // Raw media type string (e.g., "1000baseT")
+}
+
+// WiFiLinkInfo represents Wi-Fi interface link information
+type WiFiLinkInfo struct {
+	InterfaceName  string // Interface name (en0, en1, etc.)
+	PHYMode        string // "802.11n", "802.11ac", "802.11ax", etc.
+	WiFiGeneration string // "Wi-Fi 4", "Wi-Fi 5", "Wi-Fi 6", etc.
+	TxRateMbps     int    // Current transmit rate in Mbps
+	IsConnected    bool   // True if associated to a network
+}
+
+// DisplayFPSMetrics holds the current display FPS and frame interval
+type DisplayFPSMetrics struct {
+	FPS             uint32  // Current display FPS
+	FrameIntervalMs float64 // Average frame interval in milliseconds
+}
+
+// FormatLinkSpeed formats Mbps to human-readable string (e.g., "1GbE", "100Mbps")
+func FormatLinkSpeed(mbps uint64) string {
+	switch {
+	case mbps >= 10000:
+		return fmt.Sprintf("%.0fGbE", float64(mbps)/1000)
+	case mbps >= 1000:
+		if mbps%1000 == 0 {
+			return fmt.Sprintf("%dGbE", mbps/1000)
+		}
+		return fmt.Sprintf("%.1fGbE", float64(mbps)/1000)
+	case mbps > 0:
+		return fmt.Sprintf("%dMbps", mbps)
+	default:
+		return "--"
+	}
+}
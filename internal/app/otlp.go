@@ -0,0 +1,373 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// otlp.go - --otlp-endpoint: pushes every sample to an OpenTelemetry collector over OTLP/HTTP
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const otlpTimeout = 10 * time.Second
+
+// otlpExporter posts OTLP/HTTP JSON metric batches from a single goroutine.
+// A batch that can't be sent before the next one is ready is dropped, so a
+// slow or unreachable collector never holds up sampling.
+type otlpExporter struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	queue   chan []byte
+	failing bool
+}
+
+var activeOTLP *otlpExporter
+
+// setupOTLP starts the exporter when --otlp-endpoint is set. Headers from
+// OTEL_EXPORTER_OTLP_HEADERS (e.g. an API key) are sent with every request.
+func setupOTLP() {
+	if otlpEndpoint == "" {
+		return
+	}
+	e, err := newOTLPExporter(otlpEndpoint, os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"))
+	if err != nil {
+		stderrLogger.Fatalf("invalid --otlp-endpoint: %v", err)
+	}
+	activeOTLP = e
+	go e.run()
+}
+
+func newOTLPExporter(endpoint, headers string) (*otlpExporter, error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	// Like the OTel SDKs, treat a bare collector address as its base URL.
+	if !strings.HasSuffix(u.Path, "/v1/metrics") {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/v1/metrics"
+	}
+	e := &otlpExporter{
+		url:     u.String(),
+		headers: make(map[string]string),
+		client:  &http.Client{Timeout: otlpTimeout},
+		queue:   make(chan []byte, 1),
+	}
+	for pair := range strings.SplitSeq(headers, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		if v, err := url.QueryUnescape(strings.TrimSpace(v)); err == nil {
+			e.headers[strings.TrimSpace(k)] = v
+		}
+	}
+	return e, nil
+}
+
+// exportOTLP queues a sample for the active exporter, if any.
+func exportOTLP(output HeadlessOutput, s headlessSample) {
+	if activeOTLP == nil {
+		return
+	}
+	body, err := json.Marshal(buildOTLPRequest(output, s, time.Now()))
+	if err != nil {
+		stderrLogger.Printf("otlp: %v\n", err)
+		return
+	}
+	select {
+	case activeOTLP.queue <- body:
+	default:
+		// The previous batch is still waiting; replace it with the newer one.
+		select {
+		case <-activeOTLP.queue:
+		default:
+		}
+		select {
+		case activeOTLP.queue <- body:
+		default:
+		}
+	}
+}
+
+func (e *otlpExporter) run() {
+	for body := range e.queue {
+		logExportTransition(&e.failing, e.post(body), "otlp", e.url)
+	}
+}
+
+// logExportTransition logs when an exporter starts failing and when it
+// recovers, not on every interval. failing is the exporter's state, kept
+// between calls; name prefixes the message and target is where it writes.
+func logExportTransition(failing *bool, err error, name, target string) {
+	if (err != nil) == *failing {
+		return
+	}
+	*failing = err != nil
+	if err != nil {
+		stderrLogger.Printf("%s: %v\n", name, err)
+	} else {
+		stderrLogger.Printf("%s: writing to %s recovered\n", name, target)
+	}
+}
+
+func (e *otlpExporter) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("POST %s: %s", e.url, resp.Status)
+	}
+	return nil
+}
+
+// The types below are the OTLP/HTTP JSON encoding of ExportMetricsServiceRequest.
+
+type otlpRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type otlpMetric struct {
+	Name  string    `json:"name"`
+	Unit  string    `json:"unit"`
+	Gauge otlpGauge `json:"gauge"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpDataPoint struct {
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+	TimeUnixNano string         `json:"timeUnixNano"`
+	AsDouble     float64        `json:"asDouble"`
+}
+
+type otlpKeyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+func otlpString(k, v string) otlpKeyValue {
+	return otlpKeyValue{Key: k, Value: map[string]any{"stringValue": v}}
+}
+
+// otlpInt encodes an int attribute; OTLP JSON carries 64-bit ints as strings.
+func otlpInt(k string, v int) otlpKeyValue {
+	return otlpKeyValue{Key: k, Value: map[string]any{"intValue": strconv.Itoa(v)}}
+}
+
+// otlpBatch collects gauge points, one metric per name.
+type otlpBatch struct {
+	ts      string
+	metrics []otlpMetric
+	index   map[string]int
+}
+
+// gauge adds a point to the named metric; attrs are key/value pairs.
+func (b *otlpBatch) gauge(name, unit string, v float64, attrs ...string) {
+	i, ok := b.index[name]
+	if !ok {
+		i = len(b.metrics)
+		b.index[name] = i
+		b.metrics = append(b.metrics, otlpMetric{Name: name, Unit: unit})
+	}
+	p := otlpDataPoint{TimeUnixNano: b.ts, AsDouble: v}
+	for j := 0; j+1 < len(attrs); j += 2 {
+		p.Attributes = append(p.Attributes, otlpString(attrs[j], attrs[j+1]))
+	}
+	b.metrics[i].Gauge.DataPoints = append(b.metrics[i].Gauge.DataPoints, p)
+}
+
+func buildOTLPRequest(output HeadlessOutput, s headlessSample, now time.Time) otlpRequest {
+	b := &otlpBatch{ts: strconv.FormatInt(now.UnixNano(), 10), index: make(map[string]int)}
+	addOTLPCompute(b, output)
+	addOTLPPower(b, output)
+	addOTLPThermals(b, output, s)
+	addOTLPIO(b, output)
+	addOTLPLinks(b, output)
+	addOTLPProcesses(b, output.Processes)
+
+	host, _ := os.Hostname()
+	si := output.SystemInfo
+	return otlpRequest{ResourceMetrics: []otlpResourceMetrics{{
+		Resource: otlpResource{Attributes: []otlpKeyValue{
+			otlpString("service.name", "mactop"),
+			otlpString("service.version", version),
+			otlpString("host.name", host),
+			otlpString("host.model", si.Name),
+			otlpInt("mactop.cpu.cores", si.CoreCount),
+			otlpInt("mactop.cpu.e_cores", si.ECoreCount),
+			otlpInt("mactop.cpu.p_cores", si.PCoreCount),
+			otlpInt("mactop.cpu.s_cores", si.SCoreCount),
+			otlpInt("mactop.gpu.cores", si.GPUCoreCount),
+		}},
+		ScopeMetrics: []otlpScopeMetrics{{
+			Scope:   otlpScope{Name: "github.com/metaspartan/mactop", Version: version},
+			Metrics: b.metrics,
+		}},
+	}}}
+}
+
+func addOTLPCompute(b *otlpBatch, o HeadlessOutput) {
+	b.gauge("mactop.cpu.usage", "%", o.CPUUsage)
+	for _, c := range []struct {
+		name  string
+		usage []float64
+	}{{"e", o.ECPUUsage}, {"p", o.PCPUUsage}, {"s", o.SCPUUsage}} {
+		if len(c.usage) == 2 {
+			b.gauge("mactop.cpu.cluster.usage", "%", c.usage[1], "cluster", c.name)
+			b.gauge("mactop.cpu.cluster.frequency", "MHz", c.usage[0], "cluster", c.name)
+		}
+	}
+	for i, u := range o.CoreUsages {
+		b.gauge("mactop.cpu.core.usage", "%", u, "core", strconv.Itoa(i))
+	}
+	b.gauge("mactop.gpu.usage", "%", o.GPUUsage)
+	b.gauge("mactop.gpu.frequency", "MHz", float64(o.GPUMetrics.FreqMHz))
+	// Peak throughput, as mactop_gpu_tflops, and the share of it in use.
+	b.gauge("mactop.gpu.tflops", "TFLOPS", o.TFLOPsFP32, "precision", "fp32")
+	b.gauge("mactop.gpu.tflops", "TFLOPS", o.TFLOPsFP16, "precision", "fp16")
+	b.gauge("mactop.gpu.tflops.used", "TFLOPS", o.TFLOPsFP32*o.GPUUsage/100, "precision", "fp32")
+	b.gauge("mactop.display.fps", "{frame}/s", float64(o.DisplayFPS))
+	b.gauge("mactop.display.frame_interval", "ms", o.FrameIntervalMs)
+	// Same scale as the ANE gauge: 8 W is full load.
+	b.gauge("mactop.ane.usage", "%", o.SocMetrics.ANEPower/8.0*100)
+}
+
+func addOTLPPower(b *otlpBatch, o HeadlessOutput) {
+	m := o.SocMetrics
+	for _, p := range []struct {
+		component string
+		watts     float64
+	}{
+		{"cpu", m.CPUPower}, {"gpu", m.GPUPower}, {"ane", m.ANEPower}, {"dram", m.DRAMPower},
+		{"gpu_sram", m.GPUSRAMPower}, {"system", m.SystemPower}, {"total", m.TotalPower},
+	} {
+		b.gauge("mactop.power", "W", p.watts, "component", p.component)
+	}
+	b.gauge("mactop.dram.bandwidth", "GBy/s", m.DRAMReadBW, "direction", "read")
+	b.gauge("mactop.dram.bandwidth", "GBy/s", m.DRAMWriteBW, "direction", "write")
+	b.gauge("mactop.dram.bandwidth", "GBy/s", m.DRAMBWCombined, "direction", "combined")
+}
+
+func addOTLPThermals(b *otlpBatch, o HeadlessOutput, s headlessSample) {
+	m := o.SocMetrics
+	b.gauge("mactop.temperature", "Cel", float64(m.SocTemp), "sensor", "soc")
+	b.gauge("mactop.temperature", "Cel", float64(m.CPUTemp), "sensor", "cpu")
+	b.gauge("mactop.temperature", "Cel", float64(m.GPUTemp), "sensor", "gpu")
+	for _, t := range s.soc.TempSensors {
+		b.gauge("mactop.temperature.sensor", "Cel", t.Value, "key", t.Key, "name", t.Name)
+	}
+	for _, f := range s.soc.Fans {
+		b.gauge("mactop.fan.speed", "{rpm}", float64(f.ActualRPM), "fan_id", strconv.Itoa(f.ID), "fan_name", f.Name)
+	}
+	b.gauge("mactop.thermal.state", "1", float64(s.thermal))
+}
+
+func addOTLPIO(b *otlpBatch, o HeadlessOutput) {
+	mem := o.Memory
+	b.gauge("mactop.memory.usage", "By", float64(mem.Used), "state", "used")
+	b.gauge("mactop.memory.usage", "By", float64(mem.Available), "state", "available")
+	b.gauge("mactop.memory.limit", "By", float64(mem.Total))
+	b.gauge("mactop.swap.usage", "By", float64(mem.SwapUsed))
+	b.gauge("mactop.swap.limit", "By", float64(mem.SwapTotal))
+
+	nd := o.NetDisk
+	b.gauge("mactop.network.io", "By/s", nd.InBytesPerSec, "direction", "receive")
+	b.gauge("mactop.network.io", "By/s", nd.OutBytesPerSec, "direction", "transmit")
+	b.gauge("mactop.network.packets", "{packet}/s", nd.InPacketsPerSec, "direction", "receive")
+	b.gauge("mactop.network.packets", "{packet}/s", nd.OutPacketsPerSec, "direction", "transmit")
+	b.gauge("mactop.disk.io", "By/s", nd.ReadKBytesPerSec*1024, "direction", "read")
+	b.gauge("mactop.disk.io", "By/s", nd.WriteKBytesPerSec*1024, "direction", "write")
+	b.gauge("mactop.disk.operations", "{operation}/s", nd.ReadOpsPerSec, "direction", "read")
+	b.gauge("mactop.disk.operations", "{operation}/s", nd.WriteOpsPerSec, "direction", "write")
+	for _, v := range o.Volumes {
+		b.gauge("mactop.volume.size", "GBy", v.TotalGB, "volume", v.Name, "state", "total")
+		b.gauge("mactop.volume.size", "GBy", v.UsedGB, "volume", v.Name, "state", "used")
+		b.gauge("mactop.volume.utilization", "%", v.UsedPct, "volume", v.Name)
+	}
+}
+
+// addOTLPLinks covers the network links, Thunderbolt buses and RDMA
+// devices, with the same series as promNetworkGauges and
+// promThunderboltGauges.
+func addOTLPLinks(b *otlpBatch, o HeadlessOutput) {
+	for _, eth := range o.NetworkLinks.Ethernet {
+		b.gauge("mactop.network.link.speed", "Mbit/s", float64(eth.SpeedMbps), "interface", eth.Name)
+		b.gauge("mactop.network.link.up", "1", boolGauge(eth.LinkUp), "interface", eth.Name)
+	}
+	if wifi := o.NetworkLinks.WiFi; wifi != nil {
+		rate := 0.0
+		if wifi.Connected {
+			rate = float64(wifi.TxRateMbps)
+		}
+		b.gauge("mactop.wifi.tx_rate", "Mbit/s", rate, "interface", wifi.Interface, "phy_mode", wifi.PHYMode)
+	}
+
+	b.gauge("mactop.thunderbolt.network.io", "By/s", o.TBNetTotalBytesInSec, "direction", "receive")
+	b.gauge("mactop.thunderbolt.network.io", "By/s", o.TBNetTotalBytesOutSec, "direction", "transmit")
+	if o.ThunderboltInfo != nil {
+		for _, bus := range o.ThunderboltInfo.Buses {
+			active := strings.HasPrefix(bus.Status, "Active")
+			b.gauge("mactop.thunderbolt.bus.active", "1", boolGauge(active), "bus", bus.Name, "speed", bus.Speed)
+			b.gauge("mactop.thunderbolt.bus.devices", "{device}", float64(len(bus.Devices)), "bus", bus.Name)
+		}
+	}
+
+	b.gauge("mactop.rdma.available", "1", boolGauge(o.RDMAStatus.Available))
+	for _, d := range o.RDMAStatus.Devices {
+		b.gauge("mactop.rdma.device.active_mtu", "By", float64(d.ActiveMTU),
+			"device", d.Name, "interface", d.Interface, "transport", d.Transport, "port_state", d.PortState, "link_layer", d.LinkLayer)
+	}
+}
+
+// addOTLPProcesses exports the same busiest commands as Prometheus.
+func addOTLPProcesses(b *otlpBatch, processes []HeadlessProcess) {
+	for _, t := range busiestCommands(processes) {
+		b.gauge("mactop.process.cpu.usage", "%", t.CPU, "command", t.Command)
+		b.gauge("mactop.process.gpu.time", "ms/s", t.GPU, "command", t.Command)
+		b.gauge("mactop.process.memory.utilization", "%", t.Memory, "command", t.Command)
+		b.gauge("mactop.process.memory.usage", "By", float64(t.RSS)*1024, "command", t.Command)
+		b.gauge("mactop.process.count", "{process}", float64(t.Count), "command", t.Command)
+		b.gauge("mactop.process.power", "W", t.Watts, "command", t.Command)
+		b.gauge("mactop.process.energy", "J", t.Joules, "command", t.Command)
+	}
+}
@@ -0,0 +1,164 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewOTLPExporter(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+	}{
+		{"localhost:4318", "http://localhost:4318/v1/metrics"},
+		{"https://otel.example.com/", "https://otel.example.com/v1/metrics"},
+		{"https://otel.example.com/otlp", "https://otel.example.com/otlp/v1/metrics"},
+		{"http://collector:4318/v1/metrics", "http://collector:4318/v1/metrics"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			e, err := newOTLPExporter(tt.endpoint, "x-api-key=abc%3D, bad")
+			if err != nil {
+				t.Fatalf("newOTLPExporter(%q) error = %v", tt.endpoint, err)
+			}
+			if e.url != tt.want {
+				t.Errorf("url = %q, want %q", e.url, tt.want)
+			}
+			if got := e.headers["x-api-key"]; got != "abc=" {
+				t.Errorf("x-api-key header = %q, want %q", got, "abc=")
+			}
+		})
+	}
+}
+
+// exportToTestCollector runs one export against a test collector and
+// returns the request it received.
+func exportToTestCollector(t *testing.T, output HeadlessOutput, s headlessSample) otlpRequest {
+	t.Helper()
+	got := make(chan otlpRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/metrics" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("request = %s %s, want a JSON POST to /v1/metrics", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req otlpRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		got <- req
+	}))
+	defer srv.Close()
+
+	e, err := newOTLPExporter(srv.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := activeOTLP
+	activeOTLP = e
+	t.Cleanup(func() { activeOTLP = prev })
+	go e.run()
+	defer close(e.queue)
+
+	exportOTLP(output, s)
+
+	select {
+	case req := <-got:
+		return req
+	case <-time.After(5 * time.Second):
+		t.Fatal("no export received")
+	}
+	return otlpRequest{}
+}
+
+func TestOTLPExportPostsMetrics(t *testing.T) {
+	output := HeadlessOutput{CPUUsage: 42, CoreUsages: []float64{10, 20}, SystemInfo: SystemInfo{Name: "Apple M4 Pro", CoreCount: 14}}
+	output.SocMetrics.TotalPower = 23.5
+	req := exportToTestCollector(t, output, headlessSample{soc: SocMetrics{Fans: []FanInfo{{ID: 0, Name: "Left", ActualRPM: 2400}}}})
+
+	rm := req.ResourceMetrics[0]
+	attrs := map[string]any{}
+	for _, kv := range rm.Resource.Attributes {
+		for _, v := range kv.Value {
+			attrs[kv.Key] = v
+		}
+	}
+	if attrs["host.model"] != "Apple M4 Pro" || attrs["mactop.cpu.cores"] != "14" {
+		t.Errorf("resource attributes = %v, want the model and core count", attrs)
+	}
+
+	points := map[string][]otlpDataPoint{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		points[m.Name] = m.Gauge.DataPoints
+	}
+	if p := points["mactop.cpu.usage"]; len(p) != 1 || p[0].AsDouble != 42 {
+		t.Errorf("mactop.cpu.usage = %+v, want one point of 42", p)
+	}
+	if p := points["mactop.cpu.core.usage"]; len(p) != 2 || p[1].AsDouble != 20 {
+		t.Errorf("mactop.cpu.core.usage = %+v, want a point per core", p)
+	}
+	if p := points["mactop.fan.speed"]; len(p) != 1 || p[0].AsDouble != 2400 {
+		t.Errorf("mactop.fan.speed = %+v, want the fan's RPM", p)
+	}
+	var total float64
+	for _, p := range points["mactop.power"] {
+		if p.Attributes[0].Value["stringValue"] == "total" {
+			total = p.AsDouble
+		}
+	}
+	if total != 23.5 {
+		t.Errorf("mactop.power{component=total} = %v, want 23.5", total)
+	}
+}
+
+func TestOTLPExportMatchesPrometheus(t *testing.T) {
+	output := HeadlessOutput{GPUUsage: 50, TFLOPsFP32: 4, DisplayFPS: 120}
+	output.Volumes = []HeadlessVolume{{Name: "Macintosh HD", UsedPct: 41.5}}
+	output.Processes = []HeadlessProcess{{Command: "clang", CPU: 30}, {Command: "clang", CPU: 20}, {Command: "Xcode", CPU: 10}}
+	req := buildOTLPRequest(output, headlessSample{}, time.Unix(0, 0))
+
+	points := map[string][]otlpDataPoint{}
+	for _, m := range req.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		points[m.Name] = m.Gauge.DataPoints
+	}
+	for name, want := range map[string]float64{
+		"mactop.gpu.tflops":         4,
+		"mactop.gpu.tflops.used":    2,
+		"mactop.display.fps":        120,
+		"mactop.volume.utilization": 41.5,
+		"mactop.process.cpu.usage":  50,
+	} {
+		if p := points[name]; len(p) == 0 || p[0].AsDouble != want {
+			t.Errorf("%s = %+v, want %v first", name, p, want)
+		}
+	}
+	if p := points["mactop.process.count"]; len(p) != 2 || p[0].AsDouble != 2 {
+		t.Errorf("mactop.process.count = %+v, want a point per command", p)
+	}
+}
+
+func TestLogExportTransition(t *testing.T) {
+	var buf bytes.Buffer
+	prev := stderrLogger.Writer()
+	stderrLogger.SetOutput(&buf)
+	t.Cleanup(func() { stderrLogger.SetOutput(prev) })
+
+	var failing bool
+	down := errors.New("connection refused")
+	for _, err := range []error{nil, down, down, nil, nil} {
+		logExportTransition(&failing, err, "influx", "http://influx:8086")
+	}
+	want := "influx: connection refused\ninflux: writing to http://influx:8086 recovered\n"
+	if got := buf.String(); got != want {
+		t.Errorf("logged %q, want %q", got, want)
+	}
+	if failing {
+		t.Error("failing still set after recovering")
+	}
+}
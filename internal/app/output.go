@@ -0,0 +1,329 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// output.go - --output: headless records written to a rotating, optionally compressed file
+package app
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// rotatedLayout timestamps rotated files with the time they were closed.
+const rotatedLayout = "20060102-150405"
+
+// rotatingFile is the --output file. Once it is due it is closed, renamed
+// to stem.<time>.ext and replaced by an empty file; the renamed file is
+// then compressed and the oldest rotated files beyond keep are removed in
+// the background, so sampling never waits on gzip.
+type rotatingFile struct {
+	path     string
+	maxSize  int64         // 0 for no size limit
+	every    time.Duration // 0 for no time limit
+	compress string        // "", "gzip" or "zstd"
+	keep     int           // Rotated files kept; 0 keeps them all
+
+	f        *os.File
+	size     int64
+	deadline time.Time
+	rotated  *regexp.Regexp // Matches the names of this file's rotations
+	pending  sync.WaitGroup // Compress-and-prune passes still running
+	mu       sync.Mutex     // Serialises those passes
+}
+
+var activeOutput *rotatingFile
+
+// setupHeadlessOutput opens --output and checks the rotation flags before
+// sampling starts.
+func setupHeadlessOutput() {
+	if outputPath == "" {
+		if rotateSize != "" || rotateEvery != "" || rotateCompress != "" || rotateKeep != 0 {
+			stderrLogger.Fatalf("invalid --rotate-*: needs --output")
+		}
+		return
+	}
+	var maxSize int64
+	if rotateSize != "" {
+		n, err := parseByteSize(rotateSize)
+		if err != nil {
+			stderrLogger.Fatalf("invalid --rotate-size: %v", err)
+		}
+		maxSize = n
+	}
+	var every time.Duration
+	if rotateEvery != "" {
+		d, err := time.ParseDuration(rotateEvery)
+		if err != nil || d <= 0 {
+			stderrLogger.Fatalf("invalid --rotate-every: %q", rotateEvery)
+		}
+		every = d
+	}
+	if rotateKeep < 0 {
+		stderrLogger.Fatalf("invalid --rotate-keep: %d", rotateKeep)
+	}
+	r, err := newRotatingFile(outputPath, maxSize, every, rotateCompress, rotateKeep, time.Now())
+	if err != nil {
+		stderrLogger.Fatalf("invalid --output: %v", err)
+	}
+	activeOutput = r
+}
+
+// newRotatingFile opens path for writing. A file already there is rotated
+// out first rather than appended to, since a second JSON array or XML root
+// would leave it malformed.
+func newRotatingFile(path string, maxSize int64, every time.Duration, compress string, keep int, now time.Time) (*rotatingFile, error) {
+	switch compress {
+	case "", "gzip", "zstd":
+	default:
+		return nil, fmt.Errorf("unknown compression %q, use gzip or zstd", compress)
+	}
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(path, ext)
+	r := &rotatingFile{
+		path:     path,
+		maxSize:  maxSize,
+		every:    every,
+		compress: compress,
+		keep:     keep,
+		rotated: regexp.MustCompile(`^` + regexp.QuoteMeta(filepath.Base(stem)) +
+			`\.(\d{8}-\d{6})(?:-(\d+))?` + regexp.QuoteMeta(ext) + `(?:\.gz|\.zst)?$`),
+	}
+	if fi, err := os.Stat(path); err == nil && fi.Size() > 0 {
+		if err := r.archive(now); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.open(now); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open(now time.Time) error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	r.f, r.size = f, 0
+	if r.every > 0 {
+		// Periods start on multiples of their length, so hourly files
+		// start on the hour.
+		r.deadline = now.Truncate(r.every).Add(r.every)
+	}
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// due reports whether the file has reached --rotate-size or its
+// --rotate-every period has ended. It is checked between records, so a
+// file may overshoot the size limit by up to one record.
+func (r *rotatingFile) due(now time.Time) bool {
+	return (r.maxSize > 0 && r.size >= r.maxSize) || (r.every > 0 && !now.Before(r.deadline))
+}
+
+// rotate closes the current file, moves it aside and starts a new one.
+func (r *rotatingFile) rotate(now time.Time) error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	if err := r.archive(now); err != nil {
+		return err
+	}
+	return r.open(now)
+}
+
+// close closes the current file and waits for rotated files to be
+// compressed.
+func (r *rotatingFile) close() error {
+	err := r.f.Close()
+	r.pending.Wait()
+	return err
+}
+
+// archive renames the file at path to its rotated name, then compresses
+// and prunes in the background.
+func (r *rotatingFile) archive(now time.Time) error {
+	name := r.rotatedName(now)
+	if err := os.Rename(r.path, name); err != nil {
+		return err
+	}
+	r.pending.Add(1)
+	go func() {
+		defer r.pending.Done()
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		// A later rotation's prune may already have removed this one.
+		if r.compress != "" {
+			if err := compressFile(name, r.compress); err != nil && !errors.Is(err, os.ErrNotExist) {
+				stderrLogger.Printf("output: compressing %s: %v\n", name, err)
+			}
+		}
+		if err := r.prune(); err != nil {
+			stderrLogger.Printf("output: removing old files: %v\n", err)
+		}
+	}()
+	return nil
+}
+
+// rotatedName is stem.<time>.ext, with a counter added when files are
+// rotated more than once a second.
+func (r *rotatingFile) rotatedName(now time.Time) string {
+	ext := filepath.Ext(r.path)
+	base := strings.TrimSuffix(r.path, ext) + "." + now.Format(rotatedLayout)
+	name := base + ext
+	for n := 2; rotatedExists(name); n++ {
+		name = base + "-" + strconv.Itoa(n) + ext
+	}
+	return name
+}
+
+func rotatedExists(name string) bool {
+	for _, suffix := range []string{"", ".gz", ".zst"} {
+		if _, err := os.Stat(name + suffix); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// prune removes the oldest rotated files beyond --rotate-keep.
+func (r *rotatingFile) prune() error {
+	if r.keep == 0 {
+		return nil
+	}
+	files, err := r.rotatedFiles()
+	if err != nil {
+		return err
+	}
+	for len(files) > r.keep {
+		if err := os.Remove(files[0]); err != nil {
+			return err
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+// rotatedFiles lists this file's rotations, oldest first.
+func (r *rotatingFile) rotatedFiles() ([]string, error) {
+	dir := filepath.Dir(r.path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type rotation struct {
+		name  string
+		stamp string
+		n     int
+	}
+	var found []rotation
+	for _, e := range entries {
+		m := r.rotated.FindStringSubmatch(e.Name())
+		if m == nil || e.IsDir() {
+			continue
+		}
+		n, _ := strconv.Atoi(m[2])
+		found = append(found, rotation{filepath.Join(dir, e.Name()), m[1], n})
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].stamp != found[j].stamp {
+			return found[i].stamp < found[j].stamp
+		}
+		return found[i].n < found[j].n
+	})
+	names := make([]string, len(found))
+	for i, f := range found {
+		names[i] = f.name
+	}
+	return names, nil
+}
+
+// compressFile replaces name with name.gz or name.zst. The compressed file
+// is written under a temporary name first, so a crash never leaves a
+// truncated archive next to a deleted original.
+func compressFile(name, method string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	target := name + ".gz"
+	if method == "zstd" {
+		target = name + ".zst"
+	}
+	tmp := target + ".tmp"
+	dst, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := writeCompressed(dst, src, method); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+func writeCompressed(dst io.Writer, src io.Reader, method string) error {
+	var w io.WriteCloser
+	if method == "zstd" {
+		zw, err := zstd.NewWriter(dst)
+		if err != nil {
+			return err
+		}
+		w = zw
+	} else {
+		w = gzip.NewWriter(dst)
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+var byteSizeUnits = map[string]int64{
+	"": 1, "b": 1,
+	"k": 1 << 10, "kb": 1 << 10, "kib": 1 << 10,
+	"m": 1 << 20, "mb": 1 << 20, "mib": 1 << 20,
+	"g": 1 << 30, "gb": 1 << 30, "gib": 1 << 30,
+}
+
+// parseByteSize reads sizes such as 512KB, 100MB or 1.5G. Units are
+// binary, as in --unit-disk.
+func parseByteSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	unit, ok := byteSizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if err != nil || !ok || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(unit)), nil
+}
@@ -0,0 +1,156 @@
+package app
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestParseByteSize(t *testing.T) {
+	cases := map[string]int64{
+		"100MB":  100 << 20,
+		"512k":   512 << 10,
+		"1.5G":   3 << 29,
+		"2048":   2048,
+		"10 KiB": 10 << 10,
+	}
+	for in, want := range cases {
+		if got, err := parseByteSize(in); err != nil || got != want {
+			t.Errorf("parseByteSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "MB", "10TB", "-1MB", "0"} {
+		if _, err := parseByteSize(in); err == nil {
+			t.Errorf("parseByteSize(%q) should fail", in)
+		}
+	}
+}
+
+func readRotated(t *testing.T, name string) string {
+	t.Helper()
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	switch filepath.Ext(name) {
+	case ".gz":
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = gz
+	case ".zst":
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRotatingFileSize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mactop.json")
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	r, err := newRotatingFile(path, 6, 0, "gzip", 2, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.due(now) {
+		t.Error("an empty file should not be due")
+	}
+	for _, rec := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if r.due(now) {
+			if err := r.rotate(now); err != nil {
+				t.Fatal(err)
+			}
+		}
+		r.Write([]byte(rec))
+	}
+	if err := r.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Three rotations within the same second; only the newest two are kept.
+	files, err := r.rotatedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"mactop.20260301-120000-2.json.gz", "mactop.20260301-120000-3.json.gz"}
+	if len(files) != len(want) {
+		t.Fatalf("rotated files = %v, want %v", files, want)
+	}
+	for i, name := range want {
+		if filepath.Base(files[i]) != name {
+			t.Errorf("rotated file %d = %s, want %s", i, filepath.Base(files[i]), name)
+		}
+	}
+	if got := readRotated(t, files[1]); got != "third\n" {
+		t.Errorf("newest rotated file holds %q", got)
+	}
+	if got := readRotated(t, path); got != "fourth\n" {
+		t.Errorf("current file holds %q", got)
+	}
+}
+
+func TestRotatingFileEvery(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mactop.csv")
+	if err := os.WriteFile(path, []byte("from an earlier run\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 3, 1, 12, 40, 0, 0, time.UTC)
+	r, err := newRotatingFile(path, 0, time.Hour, "zstd", 0, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Write([]byte("a,b\n"))
+	if r.due(start.Add(19 * time.Minute)) {
+		t.Error("hourly files should not rotate before the hour")
+	}
+	if !r.due(start.Add(20 * time.Minute)) {
+		t.Error("hourly files should rotate on the hour")
+	}
+	if err := r.rotate(start.Add(20 * time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if r.due(start.Add(79 * time.Minute)) {
+		t.Error("the next file should last until 14:00")
+	}
+	if err := r.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := r.rotatedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || filepath.Base(files[1]) != "mactop.20260301-130000.csv.zst" {
+		t.Fatalf("rotated files = %v", files)
+	}
+	if got := readRotated(t, files[0]); got != "from an earlier run\n" {
+		t.Errorf("an existing file should be rotated out, not appended to: %q", got)
+	}
+	if got := readRotated(t, files[1]); got != "a,b\n" {
+		t.Errorf("rotated file holds %q", got)
+	}
+}
+
+func TestNewRotatingFileRejectsCompression(t *testing.T) {
+	if _, err := newRotatingFile(filepath.Join(t.TempDir(), "out.json"), 0, 0, "bzip2", 0, time.Now()); err == nil {
+		t.Error("unknown compression should be rejected")
+	}
+}
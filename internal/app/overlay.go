@@ -1,3 +1,5 @@
+//go:build darwin
+
 // Copyright (c) 2024-2026 Carsen Klock under MIT License
 // overlay.go - Go wrappers for native macOS floating overlay HUD
 package app
@@ -0,0 +1,14 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// platform_darwin.go - Small macOS-specific helpers outside the backend
+package app
+
+import (
+	"os"
+	"syscall"
+)
+
+const rootVolumeName = "Mac HD"
+
+func StderrToLogfile(logfile *os.File) {
+	syscall.Dup2(int(logfile.Fd()), 2)
+}
@@ -0,0 +1,219 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// platform_linux.go - Linux counterparts of the macOS sysctl/IOKit helpers
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const rootVolumeName = "Root"
+
+func StderrToLogfile(logfile *os.File) {
+	syscall.Dup3(int(logfile.Fd()), 2, 0)
+}
+
+// lookupUsername resolves a uid through the user database, falling back to the numeric id.
+func lookupUsername(uid uint32) string {
+	id := strconv.FormatUint(uint64(uid), 10)
+	if u, err := user.LookupId(id); err == nil {
+		return u.Username
+	}
+	return id
+}
+
+// linuxSysctlAliases maps the Darwin sysctl names used by the UI onto procfs.
+var linuxSysctlAliases = map[string]string{
+	"kern.osrelease": "kernel/osrelease",
+	"kern.hostname":  "kernel/hostname",
+}
+
+// sysctlStringByName emulates sysctlbyname(3) via /proc/sys. The macOS
+// product version is answered from /etc/os-release.
+func sysctlStringByName(name string) (string, error) {
+	if name == "kern.osproductversion" {
+		return readOSReleaseField("VERSION_ID")
+	}
+	path, ok := linuxSysctlAliases[name]
+	if !ok {
+		path = strings.ReplaceAll(name, ".", "/")
+	}
+	data, err := os.ReadFile(filepath.Join(procRoot, "sys", path))
+	if err != nil {
+		return "", fmt.Errorf("sysctl %s: %w", name, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func readOSReleaseField(key string) (string, error) {
+	f, err := os.Open("/etc/os-release")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), "=")
+		if ok && k == key {
+			return strings.Trim(v, `"`), nil
+		}
+	}
+	return "", fmt.Errorf("%s not found in /etc/os-release", key)
+}
+
+// GetNativeUptime returns the system uptime in seconds
+func GetNativeUptime() (uint64, error) {
+	data, err := os.ReadFile(filepath.Join(procRoot, "uptime"))
+	if err != nil {
+		return 0, fmt.Errorf("read /proc/uptime: %w", err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("empty /proc/uptime")
+	}
+	secs, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse /proc/uptime: %w", err)
+	}
+	return uint64(secs), nil
+}
+
+// GetNativePartitions returns a list of mounted partitions
+func GetNativePartitions(all bool) ([]NativePartitionInfo, error) {
+	f, err := os.Open(filepath.Join(procRoot, "self", "mounts"))
+	if err != nil {
+		return nil, fmt.Errorf("read mounts: %w", err)
+	}
+	defer f.Close()
+
+	var partitions []NativePartitionInfo
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		if !all && !strings.HasPrefix(fields[0], "/dev/") {
+			continue
+		}
+		partitions = append(partitions, NativePartitionInfo{
+			Device:     fields[0],
+			Mountpoint: fields[1],
+			Fstype:     fields[2],
+		})
+	}
+	return partitions, nil
+}
+
+// GetNativeDiskUsage returns usage stats for a specific path
+func GetNativeDiskUsage(path string) (NativeDiskUsage, error) {
+	var buf syscall.Statfs_t
+	if err := syscall.Statfs(path, &buf); err != nil {
+		return NativeDiskUsage{}, fmt.Errorf("statfs %s: %w", path, err)
+	}
+
+	total := buf.Blocks * uint64(buf.Bsize)
+	free := buf.Bfree * uint64(buf.Bsize)
+	avail := buf.Bavail * uint64(buf.Bsize)
+	used := total - free
+
+	var usedPercent float64
+	if total > 0 {
+		usedPercent = float64(used) / float64(total) * 100.0
+	}
+
+	return NativeDiskUsage{
+		Total:       total,
+		Used:        used,
+		Free:        avail,
+		UsedPercent: usedPercent,
+	}, nil
+}
+
+// BuildCoreLabels labels E-cores first, then P-cores, and maps each display
+// index to the kernel CPU id used to index /proc/stat.
+func BuildCoreLabels() ([]string, int, int, int, []int) {
+	b, ok := activeBackend.(*linuxBackend)
+	if !ok {
+		return nil, 0, 0, 0, nil
+	}
+	b.discover()
+	t := b.topology
+	labels := make([]string, 0, len(t.eCPUs)+len(t.pCPUs))
+	cpuIndexMap := make([]int, 0, len(t.eCPUs)+len(t.pCPUs))
+	for i, cpu := range t.eCPUs {
+		labels = append(labels, fmt.Sprintf("E%d", i))
+		cpuIndexMap = append(cpuIndexMap, cpu)
+	}
+	for i, cpu := range t.pCPUs {
+		labels = append(labels, fmt.Sprintf("P%d", i))
+		cpuIndexMap = append(cpuIndexMap, cpu)
+	}
+	return labels, len(t.eCPUs), len(t.pCPUs), 0, cpuIndexMap
+}
+
+// GetGPUProcessStats has no portable per-process GPU time source on Linux.
+func GetGPUProcessStats() map[int]uint64 {
+	return nil
+}
+
+func GetGPUCoreCountFast() int {
+	return 0
+}
+
+// GetMaxGPUFrequency returns the maximum GPU frequency in MHz reported by DRM
+func GetMaxGPUFrequency() int {
+	cards, _ := filepath.Glob(filepath.Join(sysRoot, "class", "drm", "card[0-9]*", "gt_max_freq_mhz"))
+	for _, path := range cards {
+		if mhz, ok := readSysfsInt(path); ok {
+			return int(mhz)
+		}
+	}
+	return 0
+}
+
+// GetEthernetLinkInfo returns link information for all wired interfaces
+func GetEthernetLinkInfo() []EthernetLinkInfo {
+	netDir := filepath.Join(sysRoot, "class", "net")
+	entries, err := os.ReadDir(netDir)
+	if err != nil {
+		return nil
+	}
+
+	var result []EthernetLinkInfo
+	for _, e := range entries {
+		dir := filepath.Join(netDir, e.Name())
+		// type 1 is ARPHRD_ETHER; wireless interfaces also carry a wireless/ dir.
+		if readSysfsString(filepath.Join(dir, "type")) != "1" {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, "wireless")); err == nil {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, "device")); err != nil {
+			continue
+		}
+		// speed reads -1 (or fails) while the link is down.
+		speed, _ := readSysfsInt(filepath.Join(dir, "speed"))
+		if speed < 0 {
+			speed = 0
+		}
+		result = append(result, EthernetLinkInfo{
+			Name:          e.Name(),
+			LinkUp:        readSysfsString(filepath.Join(dir, "operstate")) == "up",
+			LinkSpeedMbps: uint64(speed),
+		})
+	}
+	return result
+}
+
+// GetWiFiLinkInfo returns nil; Wi-Fi PHY details come from CoreWLAN on macOS only.
+func GetWiFiLinkInfo() *WiFiLinkInfo {
+	return nil
+}
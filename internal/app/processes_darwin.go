@@ -0,0 +1,278 @@
+package app
+
+/*
+#include <sys/sysctl.h>
+#include <sys/resource.h>
+#include <pwd.h>
+#include <unistd.h>
+#include <libproc.h>
+#include <mach/mach_host.h>
+#include <mach/processor_info.h>
+#include <mach/mach_init.h>
+#include <mach/mach_time.h>
+
+static inline time_t get_proc_starttime(struct kinfo_proc *kp) {
+    return kp->kp_proc.p_un.__p_starttime.tv_sec;
+}
+
+// get_proc_ptime reads a process's CPU time and the part of it that ran on
+// performance cores, in the same units.
+static inline int get_proc_ptime(int pid, uint64_t *total, uint64_t *ptime) {
+    struct rusage_info_v6 ri;
+    if (proc_pid_rusage(pid, RUSAGE_INFO_V6, (rusage_info_t *)&ri) != 0) {
+        return -1;
+    }
+    *total = ri.ri_user_time + ri.ri_system_time;
+    *ptime = ri.ri_user_ptime + ri.ri_system_ptime;
+    return 0;
+}
+
+extern kern_return_t vm_deallocate(vm_map_t target_task, vm_address_t address, vm_size_t size);
+*/
+import "C"
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// lookupUsername resolves a uid through getpwuid, falling back to the numeric id.
+func lookupUsername(uid uint32) string {
+	pwd := C.getpwuid(C.uid_t(uid))
+	if pwd != nil {
+		return C.GoString(pwd.pw_name)
+	}
+	return fmt.Sprintf("%d", uid)
+}
+
+var timebaseInfo C.mach_timebase_info_data_t
+var timebaseOnce sync.Once
+
+func getTimebase() {
+	C.mach_timebase_info(&timebaseInfo)
+}
+
+func processOsProc(kp C.struct_kinfo_proc, now time.Time, prevProcessTimes map[int]ProcessTimeState, totalMem uint64, numer, denom uint64) (ProcessMetrics, int, ProcessTimeState, bool) {
+	pid := int(kp.kp_proc.p_pid)
+	if pid == 0 {
+		return ProcessMetrics{}, 0, ProcessTimeState{}, false
+	}
+
+	comm := C.GoString(&kp.kp_proc.p_comm[0])
+	createSec := int64(C.get_proc_starttime(&kp))
+
+	// Fast path: reuse full command name to avoid heavy proc_pidpath syscall on every tick.
+	// p_comm is a 16-byte truncation of the full name, so check if cached command starts with it.
+	if prevState, ok := prevProcessTimes[pid]; ok && prevState.CreateSec == createSec && prevState.Command != "" && strings.HasPrefix(prevState.Command, comm) {
+		comm = prevState.Command
+	} else {
+		var pathBuf [C.PROC_PIDPATHINFO_MAXSIZE]C.char
+		if C.proc_pidpath(C.int(pid), unsafe.Pointer(&pathBuf), C.PROC_PIDPATHINFO_MAXSIZE) > 0 {
+			fullPath := C.GoString(&pathBuf[0])
+			comm = filepath.Base(fullPath)
+		}
+	}
+
+	rssBytes := int64(0)
+	vszBytes := int64(0)
+	totalTimeNs := uint64(0)
+
+	var taskInfo C.struct_proc_taskinfo
+	ret := C.proc_pidinfo(C.int(pid), C.PROC_PIDTASKINFO, 0, unsafe.Pointer(&taskInfo), C.int(C.sizeof_struct_proc_taskinfo))
+	if ret == C.int(C.sizeof_struct_proc_taskinfo) {
+		rssBytes = int64(taskInfo.pti_resident_size)
+		vszBytes = int64(taskInfo.pti_virtual_size)
+		rawTime := uint64(taskInfo.pti_total_user) + uint64(taskInfo.pti_total_system)
+		totalTimeNs = (rawTime * numer) / denom
+	}
+
+	cpuPercent := 0.0
+	if prevState, ok := prevProcessTimes[pid]; ok {
+		timeDelta := totalTimeNs - prevState.Time
+		wallDelta := now.Sub(prevState.Timestamp).Nanoseconds()
+		if wallDelta > 0 && timeDelta > 0 {
+			cpuPercent = (float64(timeDelta) / float64(wallDelta)) * 100.0
+		}
+	}
+
+	newState := ProcessTimeState{
+		Time:      totalTimeNs,
+		Timestamp: now,
+		Command:   comm,
+		CreateSec: createSec,
+	}
+	prevState, hasPrev := prevProcessTimes[pid]
+	pCoreCPU, coreSplit := processPCoreCPU(pid, cpuPercent, prevState, hasPrev, &newState)
+
+	memPercent := 0.0
+	if totalMem > 0 {
+		memPercent = (float64(rssBytes) / float64(totalMem)) * 100.0
+	}
+
+	state := processStateString(kp.kp_proc.p_stat)
+
+	uid := uint32(kp.kp_eproc.e_ucred.cr_uid)
+	user := getUsername(uid)
+
+	totalSeconds := float64(totalTimeNs) / 1e9
+	timeStr := formatTime(totalSeconds)
+
+	pm := ProcessMetrics{
+		PID:         pid,
+		PPID:        int(kp.kp_eproc.e_ppid),
+		User:        user,
+		CPU:         cpuPercent,
+		PCoreCPU:    pCoreCPU,
+		CoreSplit:   coreSplit,
+		Memory:      memPercent,
+		VSZ:         vszBytes / 1024,
+		RSS:         rssBytes / 1024,
+		Command:     comm,
+		State:       state,
+		Started:     "",
+		Time:        timeStr,
+		LastUpdated: now,
+	}
+	return pm, pid, newState, true
+}
+
+// processPCoreCPU returns the part of cpuPercent that ran on P-cores, from
+// the rusage times since the previous sample, which it records in state.
+// It reports false when the kernel gives no times, as for other users'
+// processes without root, or there is no previous sample to compare with.
+func processPCoreCPU(pid int, cpuPercent float64, prev ProcessTimeState, hasPrev bool, state *ProcessTimeState) (float64, bool) {
+	var run, pcore C.uint64_t
+	if C.get_proc_ptime(C.int(pid), &run, &pcore) != 0 {
+		return 0, false
+	}
+	state.RunTime, state.PCoreTime = uint64(run), uint64(pcore)
+	if !hasPrev || prev.RunTime == 0 || state.RunTime < prev.RunTime || state.PCoreTime < prev.PCoreTime {
+		return 0, false
+	}
+	ran := state.RunTime - prev.RunTime
+	if ran == 0 {
+		return 0, true
+	}
+	return min(cpuPercent*float64(state.PCoreTime-prev.PCoreTime)/float64(ran), cpuPercent), true
+}
+
+func processStateString(stat C.char) string {
+	switch stat {
+	case C.SIDL:
+		return "I"
+	case C.SRUN:
+		return "R"
+	case C.SSLEEP:
+		return "S"
+	case C.SSTOP:
+		return "T"
+	case C.SZOMB:
+		return "Z"
+	default:
+		return "?"
+	}
+}
+
+func getSysctlProcessList(systemGpuPercent float64) ([]ProcessMetrics, error) {
+	var mib []C.int
+	var mibLen C.u_int
+
+	if filterPID > 0 {
+		// Fetch only the specific process by PID
+		mib = []C.int{C.CTL_KERN, C.KERN_PROC, C.KERN_PROC_PID, C.int(filterPID)}
+		mibLen = 4
+	} else {
+		// Fetch all processes
+		mib = []C.int{C.CTL_KERN, C.KERN_PROC, C.KERN_PROC_ALL}
+		mibLen = 3
+	}
+	var size C.size_t
+
+	if _, err := C.sysctl(&mib[0], mibLen, nil, &size, nil, 0); err != nil {
+		return nil, fmt.Errorf("sysctl size check failed: %v", err)
+	}
+
+	if size == 0 {
+		// PID not found or no processes
+		return nil, nil
+	}
+
+	buf := make([]byte, size)
+	if _, err := C.sysctl(&mib[0], mibLen, unsafe.Pointer(&buf[0]), &size, nil, 0); err != nil {
+		return nil, fmt.Errorf("sysctl fetch failed: %v", err)
+	}
+
+	count := int(size) / int(C.sizeof_struct_kinfo_proc)
+	kprocs := (*[1 << 30]C.struct_kinfo_proc)(unsafe.Pointer(&buf[0]))[:count:count]
+
+	var processes []ProcessMetrics
+	now := time.Now()
+
+	prevProcessTimesMutex.Lock()
+	defer prevProcessTimesMutex.Unlock()
+
+	nextProcessTimes := make(map[int]ProcessTimeState)
+
+	mibMem := []C.int{6, 24}
+	var memSize C.uint64_t
+	memLen := C.size_t(unsafe.Sizeof(memSize))
+	totalMem := uint64(0)
+	if _, err := C.sysctl(&mibMem[0], 2, unsafe.Pointer(&memSize), &memLen, nil, 0); err == nil {
+		totalMem = uint64(memSize)
+	}
+
+	timebaseOnce.Do(getTimebase)
+	numer := uint64(timebaseInfo.numer)
+	denom := uint64(timebaseInfo.denom)
+	if denom == 0 {
+		denom = 1
+	}
+
+	for _, kp := range kprocs {
+		pm, pid, ns, ok := processOsProc(kp, now, prevProcessTimes, totalMem, numer, denom)
+		if ok {
+			processes = append(processes, pm)
+			nextProcessTimes[pid] = ns
+		}
+	}
+
+	prevProcessTimes = nextProcessTimes
+
+	return finalizeProcessList(processes, now, systemGpuPercent), nil
+}
+
+func getHostCPUUsage() ([]CPUUsage, error) {
+	var numCPUs C.natural_t
+	var cpuLoad *C.processor_cpu_load_info_data_t
+	var cpuMsgCount C.mach_msg_type_number_t
+	host := C.mach_host_self()
+	kernReturn := C.host_processor_info(
+		host,
+		C.PROCESSOR_CPU_LOAD_INFO,
+		&numCPUs,
+		(*C.processor_info_array_t)(unsafe.Pointer(&cpuLoad)),
+		&cpuMsgCount,
+	)
+	if kernReturn != C.KERN_SUCCESS {
+		return nil, fmt.Errorf("error getting CPU info: %d", kernReturn)
+	}
+	defer C.vm_deallocate(
+		C.mach_task_self_,
+		(C.vm_address_t)(uintptr(unsafe.Pointer(cpuLoad))),
+		C.vm_size_t(cpuMsgCount)*C.sizeof_processor_cpu_load_info_data_t,
+	)
+	cpuLoadInfo := (*[1 << 30]C.processor_cpu_load_info_data_t)(unsafe.Pointer(cpuLoad))[:numCPUs:numCPUs]
+	cpuUsage := make([]CPUUsage, numCPUs)
+	for i := 0; i < int(numCPUs); i++ {
+		cpuUsage[i] = CPUUsage{
+			User:   float64(cpuLoadInfo[i].cpu_ticks[C.CPU_STATE_USER]),
+			System: float64(cpuLoadInfo[i].cpu_ticks[C.CPU_STATE_SYSTEM]),
+			Idle:   float64(cpuLoadInfo[i].cpu_ticks[C.CPU_STATE_IDLE]),
+			Nice:   float64(cpuLoadInfo[i].cpu_ticks[C.CPU_STATE_NICE]),
+		}
+	}
+	return cpuUsage, nil
+}
@@ -98,6 +98,10 @@ func getProcessListTitle() (string, ui.Style) {
 }
 
 func attemptKillProcess() {
+	// Replayed and remote PIDs belong to another session or machine.
+	if !readsThisHost() {
+		return
+	}
 	var currentViewProcesses []ProcessMetrics
 
 	// If search criteria exists, use that (even if nil/empty), otherwise use full list
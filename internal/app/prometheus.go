@@ -0,0 +1,308 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// prometheus.go - the mactop_* registry shared by --prometheus and --prometheus-textfile
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/metaspartan/mactop/v2/internal/i18n"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// promProcessLimit caps the commands exported as process series, so a busy
+// machine can't grow the label set without bound.
+const promProcessLimit = 10
+
+// promRegistry holds every mactop gauge. The /metrics endpoint and
+// --prometheus-textfile both read from it, in the TUI, headless and serve
+// modes alike; updatePrometheusFromSample is the only place that sets it.
+var promRegistry = newPromRegistry()
+
+// promLiveSeries holds the label sets each pruned vector was given by the
+// last sample.
+var promLiveSeries = map[*prometheus.GaugeVec]map[string]prometheus.Labels{}
+
+// promSeries collects the label sets one sample gives vectors whose series
+// come and go: fans, sensors, volumes, interfaces, buses and commands.
+type promSeries map[*prometheus.GaugeVec]map[string]prometheus.Labels
+
+func (p promSeries) set(vec *prometheus.GaugeVec, labels prometheus.Labels, v float64) {
+	vec.With(labels).Set(v)
+	if p[vec] == nil {
+		p[vec] = map[string]prometheus.Labels{}
+	}
+	p[vec][fmt.Sprint(labels)] = labels
+}
+
+// prune deletes the series the last sample set on vecs that this one didn't.
+// Resetting the vectors instead would let a scrape between the reset and
+// the refill see none of them.
+func (p promSeries) prune(vecs ...*prometheus.GaugeVec) {
+	for _, vec := range vecs {
+		for key, labels := range promLiveSeries[vec] {
+			if _, ok := p[vec][key]; !ok {
+				vec.Delete(labels)
+			}
+		}
+		promLiveSeries[vec] = p[vec]
+	}
+}
+
+func newPromRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		cpuUsage, ecoreUsage, pcoreUsage, scoreUsage, cpuCoreUsage, cpuClusterFreqMHz, cpuClusterActive,
+		gpuUsage, gpuFreqMHz, gpuTFLOPs, displayFPS, displayFrameTimeMs,
+		powerUsage, socTemp, gpuTemp, thermalState, fanRPM, tempSensorGauge, dramBandwidth,
+		memoryUsage, networkSpeed, diskIOSpeed, diskIOPS, volumeGB, volumeUsedPercent,
+		linkSpeedMbps, linkUp, wifiTxRateMbps,
+		tbNetworkSpeed, tbBusActive, tbBusDevices, rdmaAvailable, rdmaDeviceMTU,
+		processCPU, processGPU, processMemory, processRSS, processCount, processWatts, processEnergy,
+		systemInfoGauge,
+	)
+	registry.MustRegister(energyCounters()...)
+	return registry
+}
+
+func startPrometheusServer(port string) {
+	handler := promhttp.HandlerFor(promRegistry, promhttp.HandlerOpts{})
+
+	http.Handle("/metrics", handler)
+	go func() {
+		err := http.ListenAndServe(prometheusListenAddr(port), nil)
+		if err != nil {
+			stderrLogger.Printf(i18n.T("Headless_ErrorPrometheusServer")+"\n", err)
+		}
+	}()
+}
+
+// prometheusListenAddr accepts both "9090" and ":9090" (or "host:9090").
+func prometheusListenAddr(port string) string {
+	if strings.Contains(port, ":") {
+		return port
+	}
+	return ":" + port
+}
+
+// thermalStateValue maps a thermal state onto mactop_thermal_state.
+func thermalStateValue(level thermalStateLevel) float64 {
+	switch level {
+	case thermalStateFair:
+		return 1
+	case thermalStateSerious:
+		return 2
+	case thermalStateCritical:
+		return 3
+	}
+	return 0
+}
+
+// promCoreType labels core i given the E- and P-core counts; cores are
+// numbered E first, then P, then S.
+func promCoreType(i, eCoreCount, pCoreCount int) string {
+	switch {
+	case i < eCoreCount:
+		return "e"
+	case i < eCoreCount+pCoreCount:
+		return "p"
+	}
+	return "s"
+}
+
+func boolGauge(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// updatePrometheusFromSample sets every gauge from one sample, so the
+// registry carries the same data set as headless output whichever mode
+// produced it.
+func updatePrometheusFromSample(o HeadlessOutput, s headlessSample) {
+	promCPUGauges(o)
+	promGPUGauges(o)
+	promPowerThermalGauges(o, s)
+	promMemoryDiskGauges(o)
+	promNetworkGauges(o)
+	promThunderboltGauges(o)
+	promProcessGauges(o.Processes)
+
+	info := o.SystemInfo
+	systemInfoGauge.With(prometheus.Labels{
+		"model":          info.Name,
+		"core_count":     fmt.Sprintf("%d", info.CoreCount),
+		"e_core_count":   fmt.Sprintf("%d", info.ECoreCount),
+		"p_core_count":   fmt.Sprintf("%d", info.PCoreCount),
+		"s_core_count":   fmt.Sprintf("%d", info.SCoreCount),
+		"gpu_core_count": fmt.Sprintf("%d", info.GPUCoreCount),
+	}).Set(1)
+}
+
+func promCPUGauges(o HeadlessOutput) {
+	info := o.SystemInfo
+	var sums [3]float64
+	var counts [3]int
+	for i, usage := range o.CoreUsages {
+		coreType := promCoreType(i, info.ECoreCount, info.PCoreCount)
+		cpuCoreUsage.With(prometheus.Labels{"core": fmt.Sprintf("%d", i), "type": coreType}).Set(usage)
+		idx := strings.Index("eps", coreType)
+		sums[idx] += usage
+		counts[idx]++
+	}
+	for idx, g := range []prometheus.Gauge{ecoreUsage, pcoreUsage, scoreUsage} {
+		if counts[idx] > 0 {
+			g.Set(sums[idx] / float64(counts[idx]))
+		}
+	}
+	cpuUsage.Set(o.CPUUsage)
+
+	for _, cl := range []struct {
+		name  string
+		usage []float64
+	}{{"e", o.ECPUUsage}, {"p", o.PCPUUsage}, {"s", o.SCPUUsage}} {
+		if len(cl.usage) == 2 {
+			cpuClusterFreqMHz.With(prometheus.Labels{"cluster": cl.name}).Set(cl.usage[0])
+			cpuClusterActive.With(prometheus.Labels{"cluster": cl.name}).Set(cl.usage[1])
+		}
+	}
+}
+
+func promGPUGauges(o HeadlessOutput) {
+	gpuUsage.Set(o.GPUMetrics.ActivePercent)
+	gpuFreqMHz.Set(float64(o.GPUMetrics.FreqMHz))
+	gpuTFLOPs.With(prometheus.Labels{"precision": "fp32"}).Set(o.TFLOPsFP32)
+	gpuTFLOPs.With(prometheus.Labels{"precision": "fp16"}).Set(o.TFLOPsFP16)
+	displayFPS.Set(float64(o.DisplayFPS))
+	displayFrameTimeMs.Set(o.FrameIntervalMs)
+}
+
+func promPowerThermalGauges(o HeadlessOutput, s headlessSample) {
+	m := o.SocMetrics
+	for _, p := range []struct {
+		component string
+		watts     float64
+	}{
+		{"cpu", m.CPUPower}, {"gpu", m.GPUPower}, {"ane", m.ANEPower}, {"dram", m.DRAMPower},
+		{"gpu_sram", m.GPUSRAMPower}, {"system", m.SystemPower}, {"total", m.TotalPower},
+	} {
+		powerUsage.With(prometheus.Labels{"component": p.component}).Set(p.watts)
+	}
+	socTemp.Set(float64(m.CPUTemp))
+	gpuTemp.Set(float64(m.GPUTemp))
+	thermalState.Set(thermalStateValue(s.thermal))
+	// Fans and sensors can drop out of a reading, so only keep the ones in
+	// this sample.
+	series := promSeries{}
+	for _, fan := range s.soc.Fans {
+		series.set(fanRPM, prometheus.Labels{"fan_id": fmt.Sprintf("%d", fan.ID), "fan_name": fan.Name}, float64(fan.ActualRPM))
+	}
+	for _, sensor := range s.soc.TempSensors {
+		series.set(tempSensorGauge, prometheus.Labels{"key": sensor.Key, "name": sensor.Name}, sensor.Value)
+	}
+	series.prune(fanRPM, tempSensorGauge)
+
+	dramBandwidth.With(prometheus.Labels{"direction": "read"}).Set(m.DRAMReadBW)
+	dramBandwidth.With(prometheus.Labels{"direction": "write"}).Set(m.DRAMWriteBW)
+	dramBandwidth.With(prometheus.Labels{"direction": "combined"}).Set(m.DRAMBWCombined)
+}
+
+func promMemoryDiskGauges(o HeadlessOutput) {
+	const gb = 1024 * 1024 * 1024
+	mem := o.Memory
+	memoryUsage.With(prometheus.Labels{"type": "used"}).Set(float64(mem.Used) / gb)
+	memoryUsage.With(prometheus.Labels{"type": "available"}).Set(float64(mem.Available) / gb)
+	memoryUsage.With(prometheus.Labels{"type": "total"}).Set(float64(mem.Total) / gb)
+	memoryUsage.With(prometheus.Labels{"type": "swap_used"}).Set(float64(mem.SwapUsed) / gb)
+	memoryUsage.With(prometheus.Labels{"type": "swap_total"}).Set(float64(mem.SwapTotal) / gb)
+
+	nd := o.NetDisk
+	networkSpeed.With(prometheus.Labels{"direction": "upload"}).Set(nd.OutBytesPerSec)
+	networkSpeed.With(prometheus.Labels{"direction": "download"}).Set(nd.InBytesPerSec)
+	diskIOSpeed.With(prometheus.Labels{"operation": "read"}).Set(nd.ReadKBytesPerSec * 1024)
+	diskIOSpeed.With(prometheus.Labels{"operation": "write"}).Set(nd.WriteKBytesPerSec * 1024)
+	diskIOPS.With(prometheus.Labels{"operation": "read"}).Set(nd.ReadOpsPerSec)
+	diskIOPS.With(prometheus.Labels{"operation": "write"}).Set(nd.WriteOpsPerSec)
+
+	// Volumes come and go, so drop series for unmounted ones.
+	series := promSeries{}
+	for _, v := range o.Volumes {
+		series.set(volumeGB, prometheus.Labels{"volume": v.Name, "type": "total"}, v.TotalGB)
+		series.set(volumeGB, prometheus.Labels{"volume": v.Name, "type": "used"}, v.UsedGB)
+		series.set(volumeUsedPercent, prometheus.Labels{"volume": v.Name}, v.UsedPct)
+	}
+	series.prune(volumeGB, volumeUsedPercent)
+}
+
+func promNetworkGauges(o HeadlessOutput) {
+	links := o.NetworkLinks
+	series := promSeries{}
+	for _, eth := range links.Ethernet {
+		series.set(linkSpeedMbps, prometheus.Labels{"interface": eth.Name}, float64(eth.SpeedMbps))
+		series.set(linkUp, prometheus.Labels{"interface": eth.Name}, boolGauge(eth.LinkUp))
+	}
+	if wifi := links.WiFi; wifi != nil {
+		rate := 0.0
+		if wifi.Connected {
+			rate = float64(wifi.TxRateMbps)
+		}
+		series.set(wifiTxRateMbps, prometheus.Labels{"interface": wifi.Interface, "phy_mode": wifi.PHYMode}, rate)
+	}
+	series.prune(linkSpeedMbps, linkUp, wifiTxRateMbps)
+}
+
+func promThunderboltGauges(o HeadlessOutput) {
+	tbNetworkSpeed.With(prometheus.Labels{"direction": "download"}).Set(o.TBNetTotalBytesInSec)
+	tbNetworkSpeed.With(prometheus.Labels{"direction": "upload"}).Set(o.TBNetTotalBytesOutSec)
+	series := promSeries{}
+	if o.ThunderboltInfo != nil {
+		for _, bus := range o.ThunderboltInfo.Buses {
+			active := strings.HasPrefix(bus.Status, "Active")
+			series.set(tbBusActive, prometheus.Labels{"bus": bus.Name, "speed": bus.Speed}, boolGauge(active))
+			series.set(tbBusDevices, prometheus.Labels{"bus": bus.Name}, float64(len(bus.Devices)))
+		}
+	}
+
+	rdmaAvailable.Set(boolGauge(o.RDMAStatus.Available))
+	for _, d := range o.RDMAStatus.Devices {
+		series.set(rdmaDeviceMTU, prometheus.Labels{
+			"device":     d.Name,
+			"interface":  d.Interface,
+			"transport":  d.Transport,
+			"port_state": d.PortState,
+			"link_layer": d.LinkLayer,
+		}, float64(d.ActiveMTU))
+	}
+	series.prune(tbBusActive, tbBusDevices, rdmaDeviceMTU)
+}
+
+// busiestCommands is the busiest commands by CPU, summed across their
+// processes and capped at promProcessLimit. OTLP exports the same set.
+func busiestCommands(processes []HeadlessProcess) []processTotal {
+	totals := sumProcessesByCommand(processes)
+	sort.SliceStable(totals, func(i, j int) bool { return totals[i].CPU > totals[j].CPU })
+	if len(totals) > promProcessLimit {
+		totals = totals[:promProcessLimit]
+	}
+	return totals
+}
+
+// promProcessGauges exports the busiest commands.
+func promProcessGauges(processes []HeadlessProcess) {
+	series := promSeries{}
+	for _, t := range busiestCommands(processes) {
+		labels := prometheus.Labels{"command": t.Command}
+		series.set(processCPU, labels, t.CPU)
+		series.set(processGPU, labels, t.GPU)
+		series.set(processMemory, labels, t.Memory)
+		series.set(processRSS, labels, float64(t.RSS)*1024)
+		series.set(processCount, labels, float64(t.Count))
+		series.set(processWatts, labels, t.Watts)
+		series.set(processEnergy, labels, t.Joules)
+	}
+	series.prune(processCPU, processGPU, processMemory, processRSS, processCount, processWatts, processEnergy)
+}
@@ -0,0 +1,129 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// gatherGauges returns every series in promRegistry keyed as
+// name,label=value,... with labels in sorted order.
+func gatherGauges(t *testing.T) map[string]float64 {
+	t.Helper()
+	families, err := promRegistry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]float64{}
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			key := mf.GetName()
+			for _, l := range m.GetLabel() {
+				key += fmt.Sprintf(",%s=%s", l.GetName(), l.GetValue())
+			}
+			got[key] = m.GetGauge().GetValue()
+		}
+	}
+	return got
+}
+
+func TestUpdatePrometheusFromSample(t *testing.T) {
+	output := HeadlessOutput{
+		CPUUsage:   30,
+		ECPUUsage:  []float64{1020, 15},
+		PCPUUsage:  []float64{4512, 60},
+		CoreUsages: []float64{10, 20, 50, 70},
+		SystemInfo: SystemInfo{Name: "Apple M4", CoreCount: 4, ECoreCount: 2, PCoreCount: 2, GPUCoreCount: 10},
+		TFLOPsFP32: 4.26,
+		DisplayFPS: 120,
+		Volumes:    []HeadlessVolume{{Name: "Macintosh HD", TotalGB: 994, UsedGB: 500, UsedPct: 50.3}},
+		NetworkLinks: HeadlessNetworkLinks{
+			WiFi: &HeadlessWiFiLink{Interface: "en0", PHYMode: "802.11ax", TxRateMbps: 1200, Connected: true},
+		},
+		ThunderboltInfo: &ThunderboltOutput{Buses: []ThunderboltBusOutput{{Name: "TB4 Bus 0", Status: "Active", Speed: "40 Gb/s", Devices: []ThunderboltDeviceOutput{{Name: "Dock"}}}}},
+		RDMAStatus:      RDMAStatus{Available: true, Devices: []RDMADevice{{Name: "rdma_en2", Interface: "en2", Transport: "IB", PortState: "PORT_ACTIVE", LinkLayer: "Ethernet", ActiveMTU: 4096}}},
+	}
+	output.SocMetrics.ANEPower = 1.5
+	for i := range promProcessLimit + 5 {
+		output.Processes = append(output.Processes, HeadlessProcess{PID: i, Command: fmt.Sprintf("proc%d", i), CPU: float64(i), RSS: 1})
+	}
+	output.Processes = append(output.Processes, HeadlessProcess{PID: 99, Command: "proc0", CPU: 100, RSS: 2})
+
+	soc := SocMetrics{Fans: []FanInfo{{ID: 0, Name: "Left", ActualRPM: 2400}}, TempSensors: []TempSensor{{Key: "Tp01", Name: "CPU", Value: 61}}}
+	updatePrometheusFromSample(output, headlessSample{soc: soc, thermal: thermalStateSerious})
+	got := gatherGauges(t)
+
+	for key, want := range map[string]float64{
+		"mactop_ecore_usage_percent":                                15,
+		"mactop_pcore_usage_percent":                                60,
+		"mactop_cpu_core_usage_percent,core=2,type=p":               50,
+		"mactop_cpu_cluster_freq_mhz,cluster=p":                     4512,
+		"mactop_cpu_cluster_active_percent,cluster=e":               15,
+		"mactop_power_watts,component=ane":                          1.5,
+		"mactop_thermal_state":                                      2,
+		"mactop_fan_rpm,fan_id=0,fan_name=Left":                     2400,
+		"mactop_temp_sensor_celsius,key=Tp01,name=CPU":              61,
+		"mactop_gpu_tflops,precision=fp32":                          4.26,
+		"mactop_display_fps":                                        120,
+		"mactop_volume_gb,type=used,volume=Macintosh HD":            500,
+		"mactop_wifi_tx_rate_mbps,interface=en0,phy_mode=802.11ax":  1200,
+		"mactop_thunderbolt_bus_active,bus=TB4 Bus 0,speed=40 Gb/s": 1,
+		"mactop_thunderbolt_bus_devices,bus=TB4 Bus 0":              1,
+		"mactop_process_cpu_percent,command=proc0":                  100,
+		"mactop_process_count,command=proc0":                        2,
+		"mactop_process_rss_bytes,command=proc0":                    3 * 1024,
+		"mactop_rdma_available":                                     1,
+		"mactop_system_info,core_count=4,e_core_count=2,gpu_core_count=10,model=Apple M4,p_core_count=2,s_core_count=0":       1,
+		"mactop_rdma_device_active_mtu,device=rdma_en2,interface=en2,link_layer=Ethernet,port_state=PORT_ACTIVE,transport=IB": 4096,
+	} {
+		if v, ok := got[key]; !ok || v != want {
+			t.Errorf("%s = %v (present %v), want %v", key, v, ok, want)
+		}
+	}
+
+	processes := 0
+	for key := range got {
+		if strings.HasPrefix(key, "mactop_process_cpu_percent,") {
+			processes++
+		}
+	}
+	if processes != promProcessLimit {
+		t.Errorf("exported %d process commands, want the cap of %d", processes, promProcessLimit)
+	}
+	if _, ok := got["mactop_process_cpu_percent,command=proc1"]; ok {
+		t.Error("proc1 exported although it is not among the busiest commands")
+	}
+
+	// A volume, fan, sensor and the Thunderbolt info all go away.
+	output.Volumes = nil
+	output.ThunderboltInfo = nil
+	updatePrometheusFromSample(output, headlessSample{})
+	got = gatherGauges(t)
+	for _, key := range []string{
+		"mactop_volume_gb,type=used,volume=Macintosh HD",
+		"mactop_fan_rpm,fan_id=0,fan_name=Left",
+		"mactop_temp_sensor_celsius,key=Tp01,name=CPU",
+		"mactop_thunderbolt_bus_active,bus=TB4 Bus 0,speed=40 Gb/s",
+		"mactop_thunderbolt_bus_devices,bus=TB4 Bus 0",
+	} {
+		if _, ok := got[key]; ok {
+			t.Errorf("%s kept after it went away", key)
+		}
+	}
+	for _, key := range []string{
+		"mactop_wifi_tx_rate_mbps,interface=en0,phy_mode=802.11ax",
+		"mactop_process_cpu_percent,command=proc0",
+	} {
+		if _, ok := got[key]; !ok {
+			t.Errorf("%s dropped although it is still in the sample", key)
+		}
+	}
+}
+
+func TestPrometheusListenAddr(t *testing.T) {
+	for port, want := range map[string]string{"2112": ":2112", ":9090": ":9090", "127.0.0.1:9090": "127.0.0.1:9090"} {
+		if got := prometheusListenAddr(port); got != want {
+			t.Errorf("prometheusListenAddr(%q) = %q, want %q", port, got, want)
+		}
+	}
+}
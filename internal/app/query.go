@@ -0,0 +1,313 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// query.go - `mactop query`: reads a metric back from the --history store
+package app
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	toon "github.com/toon-format/toon-go"
+	"gopkg.in/yaml.v3"
+)
+
+// HistoryPoint is one metric over one step, as printed by `mactop query`.
+type HistoryPoint struct {
+	Timestamp string  `json:"timestamp" yaml:"timestamp" xml:"Timestamp" toon:"timestamp"`
+	Metric    string  `json:"metric" yaml:"metric" xml:"Metric" toon:"metric"`
+	Agg       string  `json:"agg" yaml:"agg" xml:"Agg" toon:"agg"`
+	Value     float64 `json:"value" yaml:"value" xml:"Value" toon:"value"`
+	Samples   int     `json:"samples" yaml:"samples" xml:"Samples" toon:"samples"`
+}
+
+// historyQuery is a parsed `mactop query`.
+type historyQuery struct {
+	metrics []string
+	since   time.Time
+	until   time.Time
+	step    time.Duration
+	agg     string
+	tier    historyTier
+}
+
+// historyBucket merges the rows of one step. Raw samples count as rollups
+// of one sample.
+type historyBucket struct {
+	n             int
+	min, max, sum map[string]float64
+}
+
+// runQuery is `mactop query`. It exits 2 for a bad query and 1 when the
+// store can't be read.
+func runQuery() {
+	q, err := newHistoryQuery(queryMetric, querySince, queryStep, queryAgg, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	format := strings.ToLower(headlessFormat)
+	if format == "template" {
+		setupHeadlessTemplate()
+	}
+	dir, err := historyDir()
+	if err == nil {
+		var points []HistoryPoint
+		if points, err = q.run(dir); err == nil {
+			err = writeHistoryPoints(os.Stdout, format, points)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func newHistoryQuery(metrics, since, step, agg string, now time.Time) (*historyQuery, error) {
+	if metrics == "" {
+		return nil, errors.New("mactop query requires --metric, e.g. --metric package_w")
+	}
+	q := &historyQuery{until: now, agg: agg}
+	known := historyKnownFields()
+	for _, name := range strings.Split(metrics, ",") {
+		field, err := resolveAlertField(strings.TrimSpace(name), false, known)
+		if err != nil {
+			return nil, fmt.Errorf("--metric: %w", err)
+		}
+		q.metrics = append(q.metrics, field)
+	}
+	back, err := parseHistoryDuration(since)
+	if err != nil {
+		return nil, fmt.Errorf("--since: %w", err)
+	}
+	q.since = now.Add(-back)
+	if q.step, err = parseHistoryDuration(step); err != nil {
+		return nil, fmt.Errorf("--step: %w", err)
+	}
+	switch agg {
+	case "avg", "min", "max":
+	default:
+		return nil, fmt.Errorf("--agg: unknown aggregate %q, use avg, min or max", agg)
+	}
+	if q.tier, err = historyTierFor(q.step, q.since, now); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// parseHistoryDuration is time.ParseDuration plus whole days and weeks,
+// such as 7d or 2w.
+func parseHistoryDuration(s string) (time.Duration, error) {
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+	var d time.Duration
+	var err error
+	if unit > 0 {
+		var n float64
+		n, err = strconv.ParseFloat(s[:len(s)-1], 64)
+		d = time.Duration(n * float64(unit))
+	} else {
+		d, err = time.ParseDuration(s)
+	}
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// historyTierFor picks the coarsest tier whose steps add up to step and
+// that still reaches back to since.
+func historyTierFor(step time.Duration, since, now time.Time) (historyTier, error) {
+	for i := len(historyTiers) - 1; i >= 0; i-- {
+		tier := historyTiers[i]
+		if tier.step > 0 && step%tier.step != 0 {
+			continue
+		}
+		if !since.Before(now.Add(-tier.retention)) {
+			return tier, nil
+		}
+	}
+	return historyTier{}, fmt.Errorf("no history for %s steps that far back: samples are kept for 24h, 1m and 1h rollups for 90 days", step)
+}
+
+// run reads the tier's segments that overlap the query and returns a point
+// per step and metric, skipping steps without samples.
+func (q *historyQuery) run(dir string) ([]HistoryPoint, error) {
+	segs, err := historySegments(dir, q.tier)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no history in %s; run mactop with --history to start one", dir)
+		}
+		return nil, err
+	}
+	buckets := make(map[int64]*historyBucket)
+	for _, seg := range segs {
+		if !seg.start.Before(q.until) || !q.tier.next(seg.start).After(q.since) {
+			continue
+		}
+		if err := readHistorySegment(seg, func(row historyRow) { q.add(buckets, row) }); err != nil {
+			return nil, err
+		}
+	}
+	return q.points(buckets), nil
+}
+
+func (q *historyQuery) add(buckets map[int64]*historyBucket, row historyRow) {
+	t := time.UnixMilli(row.T)
+	if t.Before(q.since) || t.After(q.until) {
+		return
+	}
+	key := t.Truncate(q.step).UnixMilli()
+	b := buckets[key]
+	if b == nil {
+		b = &historyBucket{min: make(map[string]float64), max: make(map[string]float64), sum: make(map[string]float64)}
+		buckets[key] = b
+	}
+	n, lo, hi, sum := 1, row.V, row.V, row.V
+	if row.N > 0 {
+		n, lo, hi, sum = row.N, row.Min, row.Max, row.Sum
+	}
+	for _, m := range q.metrics {
+		if b.n == 0 || lo[m] < b.min[m] {
+			b.min[m] = lo[m]
+		}
+		if b.n == 0 || hi[m] > b.max[m] {
+			b.max[m] = hi[m]
+		}
+		b.sum[m] += sum[m]
+	}
+	b.n += n
+}
+
+func (q *historyQuery) points(buckets map[int64]*historyBucket) []HistoryPoint {
+	keys := make([]int64, 0, len(buckets))
+	for k := range buckets {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	var points []HistoryPoint
+	for _, k := range keys {
+		b := buckets[k]
+		for _, m := range q.metrics {
+			p := HistoryPoint{
+				Timestamp: time.UnixMilli(k).Format(time.RFC3339),
+				Metric:    m,
+				Agg:       q.agg,
+				Samples:   b.n,
+			}
+			switch q.agg {
+			case "min":
+				p.Value = b.min[m]
+			case "max":
+				p.Value = b.max[m]
+			default:
+				p.Value = historyRound(b.sum[m] / float64(b.n))
+			}
+			points = append(points, p)
+		}
+	}
+	return points
+}
+
+// historyPointList is the XML root of a query result.
+type historyPointList struct {
+	XMLName xml.Name       `xml:"MactopHistory"`
+	Points  []HistoryPoint `xml:"Point"`
+}
+
+// writeHistoryPoints prints a query result in a headless format. The status
+// bar formats show a single live sample and have no use here.
+func writeHistoryPoints(w io.Writer, format string, points []HistoryPoint) error {
+	var data []byte
+	var err error
+	switch format {
+	case "json":
+		if points == nil {
+			points = []HistoryPoint{}
+		}
+		if headlessPretty {
+			data, err = json.MarshalIndent(points, "", "  ")
+		} else {
+			data, err = json.Marshal(points)
+		}
+	case "yaml":
+		data, err = yaml.Marshal(points)
+	case "xml":
+		if headlessPretty {
+			data, err = xml.MarshalIndent(historyPointList{Points: points}, "", "  ")
+		} else {
+			data, err = xml.Marshal(historyPointList{Points: points})
+		}
+	case "toon":
+		data, err = toon.Marshal(points)
+	case "csv":
+		return writeHistoryCSV(w, points)
+	case "influx":
+		return writeHistoryInflux(w, points)
+	case "table":
+		return writeHistoryTable(w, points)
+	case "template":
+		for _, p := range points {
+			if err := writeTemplateSample(w, activeTemplate, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("--format %s is not supported by mactop query", format)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, strings.TrimSuffix(string(data), "\n"))
+	return err
+}
+
+func writeHistoryCSV(w io.Writer, points []HistoryPoint) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"Timestamp", "Metric", "Agg", "Value", "Samples"})
+	for _, p := range points {
+		writer.Write([]string{p.Timestamp, p.Metric, p.Agg, strconv.FormatFloat(p.Value, 'f', -1, 64), strconv.Itoa(p.Samples)})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeHistoryInflux writes a history measurement with the metric as the
+// field and the aggregate as a tag, stamped with the step start.
+func writeHistoryInflux(w io.Writer, points []HistoryPoint) error {
+	host, _ := os.Hostname()
+	var buf bytes.Buffer
+	for _, p := range points {
+		t, err := time.Parse(time.RFC3339, p.Timestamp)
+		if err != nil {
+			return err
+		}
+		newInfluxLine("history", "agg", p.Agg).float(p.Metric, p.Value).write(&buf, host, t.UnixNano())
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeHistoryTable(w io.Writer, points []HistoryPoint) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tMETRIC\tAGG\tVALUE\tSAMPLES")
+	for _, p := range points {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\n", p.Timestamp, p.Metric, p.Agg, strconv.FormatFloat(p.Value, 'f', -1, 64), p.Samples)
+	}
+	return tw.Flush()
+}
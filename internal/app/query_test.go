@@ -0,0 +1,138 @@
+package app
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHistoryDuration(t *testing.T) {
+	cases := map[string]time.Duration{
+		"7d":   7 * 24 * time.Hour,
+		"2w":   14 * 24 * time.Hour,
+		"90m":  90 * time.Minute,
+		"1.5d": 36 * time.Hour,
+	}
+	for in, want := range cases {
+		if got, err := parseHistoryDuration(in); err != nil || got != want {
+			t.Errorf("parseHistoryDuration(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "d", "-1d", "soon"} {
+		if _, err := parseHistoryDuration(in); err == nil {
+			t.Errorf("parseHistoryDuration(%q) should fail", in)
+		}
+	}
+}
+
+func TestHistoryTierFor(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		step  time.Duration
+		since time.Duration
+		want  string
+	}{
+		{10 * time.Second, 2 * time.Hour, "raw"},
+		{5 * time.Minute, 7 * 24 * time.Hour, "1m"},
+		{time.Hour, 7 * 24 * time.Hour, "1h"},
+		{24 * time.Hour, 90 * 24 * time.Hour, "1h"},
+		{10 * time.Second, 2 * 24 * time.Hour, ""},
+		{time.Hour, 100 * 24 * time.Hour, ""},
+	}
+	for _, c := range cases {
+		tier, err := historyTierFor(c.step, now.Add(-c.since), now)
+		if (err != nil) != (c.want == "") || tier.name != c.want {
+			t.Errorf("historyTierFor(%v, %v ago) = %q, %v; want %q", c.step, c.since, tier.name, err, c.want)
+		}
+	}
+}
+
+func queryHistory(t *testing.T, dir, step, agg string) []HistoryPoint {
+	t.Helper()
+	q, err := newHistoryQuery("package_w,gpu_temp", "2h", step, agg, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	points, err := q.run(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return points
+}
+
+func TestHistoryQuery(t *testing.T) {
+	dir, start := fillHistoryStore(t)
+
+	// A point per step and metric, in the order asked for.
+	points := queryHistory(t, dir, "1m", "avg")
+	if len(points) != 4 || points[0].Metric != "package_w" || points[1].Metric != "soc_metrics.gpu_temp" {
+		t.Fatalf("1m points = %+v", points)
+	}
+	if points[0].Value != 20 || points[0].Samples != 3 || points[2].Value != 40 || points[2].Samples != 1 {
+		t.Errorf("1m averages = %+v", points)
+	}
+	if points[0].Timestamp != start.Format(time.RFC3339) {
+		t.Errorf("first step at %s, want %s", points[0].Timestamp, start.Format(time.RFC3339))
+	}
+}
+
+func TestHistoryQueryTiers(t *testing.T) {
+	dir, _ := fillHistoryStore(t)
+	if points := queryHistory(t, dir, "1h", "max"); len(points) != 2 || points[0].Value != 40 || points[1].Value != 90 {
+		t.Errorf("1h max = %+v", points)
+	}
+	if points := queryHistory(t, dir, "20s", "min"); len(points) != 8 || points[2].Value != 20 {
+		t.Errorf("raw points = %+v", points)
+	}
+}
+
+func TestNewHistoryQueryErrors(t *testing.T) {
+	now := time.Now()
+	for _, args := range [][4]string{
+		{"", "1h", "1m", "avg"},
+		{"no_such_metric", "1h", "1m", "avg"},
+		{"processes[0].cpu_percent", "1h", "1m", "avg"},
+		{"package_w", "forever", "1m", "avg"},
+		{"package_w", "1h", "1m", "p95"},
+		{"package_w", "7d", "10s", "avg"},
+	} {
+		if _, err := newHistoryQuery(args[0], args[1], args[2], args[3], now); err == nil {
+			t.Errorf("newHistoryQuery%q should fail", args)
+		}
+	}
+}
+
+func TestNewHistoryQueryListMetrics(t *testing.T) {
+	q, err := newHistoryQuery("temperatures[group=GPU].max_celsius,fans.0.rpm,display_fps", "1h", "1m", "max", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"temperatures[group=GPU].max_celsius", "fans[0].rpm", "display_fps"}
+	if !slices.Equal(q.metrics, want) {
+		t.Errorf("metrics = %v, want %v", q.metrics, want)
+	}
+}
+
+func TestWriteHistoryPoints(t *testing.T) {
+	points := []HistoryPoint{{Timestamp: "2026-03-01T12:00:00Z", Metric: "package_w", Agg: "avg", Value: 12.5, Samples: 60}}
+	want := map[string]string{
+		"csv":    "Timestamp,Metric,Agg,Value,Samples\n2026-03-01T12:00:00Z,package_w,avg,12.5,60\n",
+		"json":   `[{"timestamp":"2026-03-01T12:00:00Z","metric":"package_w","agg":"avg","value":12.5,"samples":60}]` + "\n",
+		"xml":    "<MactopHistory><Point><Timestamp>2026-03-01T12:00:00Z</Timestamp><Metric>package_w</Metric><Agg>avg</Agg><Value>12.5</Value><Samples>60</Samples></Point></MactopHistory>\n",
+		"influx": "history,agg=avg,host=",
+	}
+	for format, w := range want {
+		var buf bytes.Buffer
+		if err := writeHistoryPoints(&buf, format, points); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(buf.String(), w) {
+			t.Errorf("%s:\n got %q\nwant %q", format, buf.String(), w)
+		}
+	}
+	if err := writeHistoryPoints(&bytes.Buffer{}, "waybar", points); err == nil {
+		t.Error("status bar formats should be rejected")
+	}
+}
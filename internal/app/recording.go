@@ -0,0 +1,402 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// recording.go - Append-only session recordings written by --record and read by --replay
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	recordingFormat  = "mactop-recording"
+	recordingVersion = 1
+	// recordedProcessLimit caps the per-sample process table; the TUI never
+	// shows more rows than this on a single screen.
+	recordedProcessLimit = 100
+)
+
+// RecordingHeader opens every recording session. A file may hold several
+// sessions back to back when --record is pointed at an existing recording.
+type RecordingHeader struct {
+	Format        string     `json:"format"`
+	Version       int        `json:"version"`
+	MactopVersion string     `json:"mactop_version"`
+	Backend       string     `json:"backend"`
+	Started       string     `json:"started"`
+	SystemInfo    SystemInfo `json:"system_info"`
+	CoreLabels    []string   `json:"core_labels"`
+	CoreIndexMap  []int      `json:"core_index_map"`
+	ECores        int        `json:"e_cores"`
+	PCores        int        `json:"p_cores"`
+	SCores        int        `json:"s_cores"`
+}
+
+// RecordedProcess is the subset of ProcessMetrics shown in the process list.
+type RecordedProcess struct {
+	PID     int     `json:"pid"`
+	User    string  `json:"user"`
+	CPU     float64 `json:"cpu"`
+	GPU     float64 `json:"gpu"`
+	Memory  float64 `json:"mem"`
+	VSZ     int64   `json:"vsz"`
+	RSS     int64   `json:"rss"`
+	State   string  `json:"state,omitempty"`
+	Time    string  `json:"time"`
+	Command string  `json:"cmd"`
+}
+
+// RecordedSample is one line of a recording: the headless snapshot plus the
+// raw fan, sensor and process detail the TUI needs to redraw every layout.
+type RecordedSample struct {
+	UnixMilli  int64 `json:"t"`
+	IntervalMs int   `json:"interval_ms"`
+	HeadlessOutput
+	ThermalLevel thermalStateLevel `json:"thermal_level"`
+	FanDetail    []FanInfo         `json:"fan_detail,omitempty"`
+	TempSensors  []TempSensor      `json:"temp_sensors,omitempty"`
+	ProcessList  []RecordedProcess `json:"process_list,omitempty"`
+}
+
+// Recording is a fully loaded recording file.
+type Recording struct {
+	Header  RecordingHeader
+	Samples []RecordedSample
+	// offsets[i] is the playback position of Samples[i]; gaps between
+	// sessions are collapsed to a single interval.
+	offsets []time.Duration
+}
+
+type sessionRecorder struct {
+	mu  sync.Mutex
+	f   *os.File
+	gz  *gzip.Writer
+	enc *json.Encoder
+}
+
+var activeRecorder *sessionRecorder
+
+// startRecording opens path for appending and writes a session header.
+// Each sample is flushed as it is written, so a recording cut short by a
+// crash still replays up to its last complete sample, along with any
+// session appended after it.
+func startRecording(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("open recording: %w", err)
+	}
+	gz := gzip.NewWriter(f)
+	r := &sessionRecorder{f: f, gz: gz, enc: json.NewEncoder(gz)}
+
+	if err := r.write(newRecordingHeader()); err != nil {
+		f.Close()
+		return err
+	}
+	activeRecorder = r
+	return nil
+}
+
+// newRecordingHeader describes the active backend's machine.
+func newRecordingHeader() RecordingHeader {
+	labels, eCount, pCount, sCount, cpuIndexMap := getCoreLabels()
+	return RecordingHeader{
+		Format:        recordingFormat,
+		Version:       recordingVersion,
+		MactopVersion: version,
+		Backend:       activeBackend.Name(),
+		Started:       time.Now().Format(time.RFC3339),
+		SystemInfo:    getSOCInfo(),
+		CoreLabels:    labels,
+		CoreIndexMap:  cpuIndexMap,
+		ECores:        eCount,
+		PCores:        pCount,
+		SCores:        sCount,
+	}
+}
+
+func (r *sessionRecorder) write(v any) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(v); err != nil {
+		return fmt.Errorf("write recording: %w", err)
+	}
+	if err := r.gz.Flush(); err != nil {
+		return fmt.Errorf("flush recording: %w", err)
+	}
+	return nil
+}
+
+func (r *sessionRecorder) close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.gz.Close(); err != nil {
+		r.f.Close()
+		return fmt.Errorf("close recording: %w", err)
+	}
+	return r.f.Close()
+}
+
+// stopRecording finishes the active recording, if any.
+func stopRecording() {
+	if activeRecorder == nil {
+		return
+	}
+	if err := activeRecorder.close(); err != nil {
+		stderrLogger.Printf("%v\n", err)
+	}
+	activeRecorder = nil
+}
+
+// recordSample appends a snapshot to the active recording, if any.
+func recordSample(output HeadlessOutput, s headlessSample) {
+	if activeRecorder == nil {
+		return
+	}
+	if err := activeRecorder.write(newRecordedSample(output, s)); err != nil {
+		stderrLogger.Printf("%v\n", err)
+	}
+}
+
+func newRecordedSample(output HeadlessOutput, s headlessSample) RecordedSample {
+	rs := RecordedSample{
+		UnixMilli:      time.Now().UnixMilli(),
+		IntervalMs:     updateInterval,
+		HeadlessOutput: output,
+		ThermalLevel:   s.thermal,
+		FanDetail:      s.soc.Fans,
+		TempSensors:    s.soc.TempSensors,
+	}
+	limit := min(len(s.processes), recordedProcessLimit)
+	rs.ProcessList = make([]RecordedProcess, 0, limit)
+	for _, p := range s.processes[:limit] {
+		rs.ProcessList = append(rs.ProcessList, RecordedProcess{
+			PID:     p.PID,
+			User:    p.User,
+			CPU:     p.CPU,
+			GPU:     p.GPU,
+			Memory:  p.Memory,
+			VSZ:     p.VSZ,
+			RSS:     p.RSS,
+			State:   p.State,
+			Time:    p.Time,
+			Command: p.Command,
+		})
+	}
+	return rs
+}
+
+func (h RecordingHeader) coreLabels() ([]string, int, int, int, []int) {
+	return h.CoreLabels, h.ECores, h.PCores, h.SCores, h.CoreIndexMap
+}
+
+// socMetrics returns the sample's SoC metrics as a backend would report them.
+func (s *RecordedSample) socMetrics() SocMetrics {
+	m := s.SocMetrics
+	// Recorded power is already split into total and residual system power;
+	// undo that so the collectors derive the same split again.
+	m.TotalPower, m.SystemPower = s.SocMetrics.TotalPower-s.SocMetrics.SystemPower, s.SocMetrics.TotalPower
+	m.Fans = s.FanDetail
+	m.TempSensors = s.TempSensors
+	return m
+}
+
+func (s *RecordedSample) memory() NativeMemoryMetrics {
+	return NativeMemoryMetrics{
+		Total:     s.Memory.Total,
+		Used:      s.Memory.Used,
+		Available: s.Memory.Available,
+		SwapTotal: s.Memory.SwapTotal,
+		SwapUsed:  s.Memory.SwapUsed,
+	}
+}
+
+func (s *RecordedSample) processes() []ProcessMetrics {
+	processes := make([]ProcessMetrics, 0, len(s.ProcessList))
+	for _, p := range s.ProcessList {
+		processes = append(processes, ProcessMetrics{
+			PID:     p.PID,
+			User:    p.User,
+			CPU:     p.CPU,
+			GPU:     p.GPU,
+			Memory:  p.Memory,
+			VSZ:     p.VSZ,
+			RSS:     p.RSS,
+			State:   p.State,
+			Time:    p.Time,
+			Command: p.Command,
+		})
+	}
+	return processes
+}
+
+func (s *RecordedSample) volumes() []VolumeInfo {
+	volumes := make([]VolumeInfo, 0, len(s.Volumes))
+	for _, v := range s.Volumes {
+		volumes = append(volumes, VolumeInfo{
+			Name:      v.Name,
+			Total:     v.TotalGB,
+			Used:      v.UsedGB,
+			Available: v.TotalGB - v.UsedGB,
+			UsedPct:   v.UsedPct,
+		})
+	}
+	return volumes
+}
+
+// accumulateTicks adds one sample's per-core usage to synthetic tick
+// counters, so the deltas GetCPUPercentages takes reproduce that usage.
+func accumulateTicks(ticks []CPUUsage, usages []float64) []CPUUsage {
+	if len(ticks) != len(usages) {
+		ticks = make([]CPUUsage, len(usages))
+	}
+	for i, pct := range usages {
+		ticks[i].User += pct
+		ticks[i].Idle += 100 - pct
+	}
+	return ticks
+}
+
+// loadRecording reads a recording written by --record. Files may be plain
+// JSON Lines or gzip-compressed; a truncated tail is tolerated.
+func loadRecording(path string) (*Recording, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open recording: %w", err)
+	}
+	defer f.Close()
+	return readRecording(f)
+}
+
+func readRecording(r io.Reader) (*Recording, error) {
+	br := bufio.NewReader(r)
+	var src io.Reader = br
+	if magic, _ := br.Peek(2); bytes.Equal(magic, gzipMemberMagic[:2]) {
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return nil, fmt.Errorf("read recording: %w", err)
+		}
+		src = bytes.NewReader(gunzipMembers(data))
+	}
+
+	rec := &Recording{}
+	var haveHeader bool
+	scanner := bufio.NewScanner(src)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var probe struct {
+			Format string `json:"format"`
+		}
+		if err := json.Unmarshal(line, &probe); err != nil {
+			// A line cut short by a crash is skipped.
+			continue
+		}
+		if probe.Format == recordingFormat {
+			var h RecordingHeader
+			if err := json.Unmarshal(line, &h); err != nil {
+				return nil, fmt.Errorf("read recording header: %w", err)
+			}
+			if h.Version > recordingVersion {
+				return nil, fmt.Errorf("recording version %d is newer than supported version %d", h.Version, recordingVersion)
+			}
+			if !haveHeader {
+				rec.Header = h
+				haveHeader = true
+			}
+			continue
+		}
+		if !haveHeader {
+			return nil, errors.New("not a mactop recording")
+		}
+		var s RecordedSample
+		if err := json.Unmarshal(line, &s); err != nil {
+			continue
+		}
+		rec.Samples = append(rec.Samples, s)
+	}
+	if err := scanner.Err(); err != nil && len(rec.Samples) == 0 {
+		return nil, fmt.Errorf("read recording: %w", err)
+	}
+	if len(rec.Samples) == 0 {
+		return nil, errors.New("recording contains no samples")
+	}
+	rec.buildOffsets()
+	return rec, nil
+}
+
+// gzipMemberMagic starts every gzip member: the ID bytes and deflate.
+var gzipMemberMagic = []byte{0x1f, 0x8b, 0x08}
+
+// gunzipMembers decompresses the gzip members of a recording, one per
+// session. A session cut off by a crash or SIGKILL leaves a member without
+// an end; its complete lines are kept and reading resumes at the next member
+// header, so sessions appended after the crash still replay.
+func gunzipMembers(data []byte) []byte {
+	var out bytes.Buffer
+	for len(data) > 0 {
+		r := bytes.NewReader(data)
+		if err := gunzipMember(r, &out); err == nil {
+			data = data[len(data)-r.Len():]
+			continue
+		}
+		// Members end on a newline, so this only drops the cut-off line.
+		if i := bytes.LastIndexByte(out.Bytes(), '\n'); i >= 0 {
+			out.Truncate(i + 1)
+		} else {
+			out.Reset()
+		}
+		next := bytes.Index(data[1:], gzipMemberMagic)
+		if next < 0 {
+			break
+		}
+		data = data[next+1:]
+	}
+	return out.Bytes()
+}
+
+// gunzipMember decompresses the member at the start of r, leaving r at the
+// byte after it.
+func gunzipMember(r *bytes.Reader, out *bytes.Buffer) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	gz.Multistream(false)
+	_, err = io.Copy(out, gz)
+	return err
+}
+
+func (rec *Recording) buildOffsets() {
+	rec.offsets = make([]time.Duration, len(rec.Samples))
+	for i := 1; i < len(rec.Samples); i++ {
+		interval := rec.interval(i)
+		delta := time.Duration(rec.Samples[i].UnixMilli-rec.Samples[i-1].UnixMilli) * time.Millisecond
+		if delta <= 0 || delta > 2*interval {
+			delta = interval
+		}
+		rec.offsets[i] = rec.offsets[i-1] + delta
+	}
+}
+
+// interval returns the sampling interval in effect when sample i was taken.
+func (rec *Recording) interval(i int) time.Duration {
+	if ms := rec.Samples[i].IntervalMs; ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return time.Second
+}
+
+// Duration is the playback length of the recording.
+func (rec *Recording) Duration() time.Duration {
+	return rec.offsets[len(rec.offsets)-1]
+}
@@ -0,0 +1,164 @@
+package app
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func writeRecordingSession(t *testing.T, buf *bytes.Buffer, samples ...RecordedSample) {
+	t.Helper()
+	gz := gzip.NewWriter(buf)
+	enc := json.NewEncoder(gz)
+	if err := enc.Encode(RecordingHeader{Format: recordingFormat, Version: recordingVersion, SystemInfo: SystemInfo{Name: "Apple M4", CoreCount: 10}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range samples {
+		if err := enc.Encode(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testSample(unixMilli int64, cpuPower float64) RecordedSample {
+	s := RecordedSample{UnixMilli: unixMilli, IntervalMs: 1000}
+	s.SocMetrics.CPUPower = cpuPower
+	return s
+}
+
+func TestReadRecording(t *testing.T) {
+	var buf bytes.Buffer
+	writeRecordingSession(t, &buf, testSample(1000, 1), testSample(2000, 2))
+	// A second session appended an hour later.
+	writeRecordingSession(t, &buf, testSample(3_602_000, 3))
+
+	rec, err := readRecording(&buf)
+	if err != nil {
+		t.Fatalf("readRecording() error = %v", err)
+	}
+	if rec.Header.SystemInfo.Name != "Apple M4" {
+		t.Errorf("Header.SystemInfo.Name = %q, want %q", rec.Header.SystemInfo.Name, "Apple M4")
+	}
+	if len(rec.Samples) != 3 {
+		t.Fatalf("len(Samples) = %d, want 3", len(rec.Samples))
+	}
+	if got := rec.Samples[2].SocMetrics.CPUPower; got != 3 {
+		t.Errorf("Samples[2].SocMetrics.CPUPower = %v, want 3", got)
+	}
+	if got, want := rec.Duration(), 2*time.Second; got != want {
+		t.Errorf("Duration() = %v, want %v (gap between sessions collapsed)", got, want)
+	}
+}
+
+func TestReadRecordingTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	writeRecordingSession(t, &buf, testSample(1000, 1), testSample(2000, 2))
+	data := buf.Bytes()[:buf.Len()-12]
+
+	rec, err := readRecording(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("readRecording() error = %v", err)
+	}
+	if len(rec.Samples) == 0 {
+		t.Error("expected samples before the truncation point")
+	}
+}
+
+func TestReadRecordingAfterCrash(t *testing.T) {
+	// A session killed mid-sample: its member is flushed but never closed,
+	// and the last line is cut short.
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(gz)
+	for _, v := range []any{RecordingHeader{Format: recordingFormat, Version: recordingVersion}, testSample(1000, 1)} {
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gz.Write([]byte(`{"t":2000,"interval`))
+	if err := gz.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// The next --record to the same file appends a new session.
+	writeRecordingSession(t, &buf, testSample(5000, 2), testSample(6000, 3))
+
+	rec, err := readRecording(&buf)
+	if err != nil {
+		t.Fatalf("readRecording() error = %v", err)
+	}
+	var powers []float64
+	for _, s := range rec.Samples {
+		powers = append(powers, s.SocMetrics.CPUPower)
+	}
+	if len(powers) != 3 || powers[0] != 1 || powers[2] != 3 {
+		t.Errorf("sample CPU power = %v, want 1, 2 and 3", powers)
+	}
+}
+
+func TestReadRecordingRejectsOtherFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"Headless JSON", `{"timestamp":"2026-01-01T00:00:00Z"}` + "\n"},
+		{"Header only", `{"format":"mactop-recording","version":1}` + "\n"},
+		{"Newer version", `{"format":"mactop-recording","version":99}` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := readRecording(bytes.NewBufferString(tt.data)); err == nil {
+				t.Error("readRecording() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestReplayBackendSeek(t *testing.T) {
+	rec := &Recording{}
+	for i := range 5 {
+		rec.Samples = append(rec.Samples, testSample(int64(i)*1000, float64(i)))
+	}
+	rec.buildOffsets()
+	b := newReplayBackend(rec)
+
+	tests := []struct {
+		name  string
+		delta time.Duration
+		want  int
+	}{
+		{"Forward", 2 * time.Second, 2},
+		{"Past the end", replaySeekStep, 4},
+		{"Back before the start", -replaySeekStep, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b.seek(tt.delta)
+			b.togglePause()
+			m := b.SampleSoc(0)
+			b.togglePause()
+			if b.cursor != tt.want || m.CPUPower != float64(tt.want) {
+				t.Errorf("after seek(%v) cursor = %d (CPUPower %v), want %d", tt.delta, b.cursor, m.CPUPower, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplayBackendRestoresRawPower(t *testing.T) {
+	s := testSample(0, 10)
+	s.SocMetrics.TotalPower = 20
+	s.SocMetrics.SystemPower = 5
+	rec := &Recording{Samples: []RecordedSample{s}}
+	rec.buildOffsets()
+
+	m := newReplayBackend(rec).SampleSoc(0)
+	if m.TotalPower != 15 || m.SystemPower != 20 {
+		t.Errorf("SampleSoc() power = total %v system %v, want 15 20", m.TotalPower, m.SystemPower)
+	}
+}
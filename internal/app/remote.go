@@ -0,0 +1,281 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// remote.go - --connect: draws the TUI from a `mactop serve` instance
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	remoteRetryMin = time.Second
+	remoteRetryMax = 30 * time.Second
+	// remoteIdleTimeout drops a stream that has gone quiet for longer than a
+	// couple of server keep-alives, e.g. after the network path went away.
+	remoteIdleTimeout = 3 * streamKeepAlive
+)
+
+// remoteBackend mirrors a remote `mactop serve` instance. It follows the
+// server's detailed stream and hands each sample to the regular collectors,
+// so every layout, the process list and the history charts work unchanged.
+// The TUI keeps running while the connection is down: SampleSoc waits for
+// the next sample, the charts hold their history, and the stream is
+// re-established in the background.
+type remoteBackend struct {
+	addr    string
+	baseURL string
+	header  RecordingHeader
+	client  *http.Client
+	cancel  context.CancelFunc
+
+	mu        sync.Mutex
+	fresh     *sync.Cond
+	current   *RecordedSample
+	received  uint64
+	taken     uint64
+	connected bool
+	retryAt   time.Time
+	cpuTicks  []CPUUsage
+}
+
+// startRemote fetches the server's system description and current sample,
+// then makes the remote the active backend and starts following its stream.
+func startRemote(addr string) error {
+	baseURL := strings.TrimSuffix(addr, "/")
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+	b := &remoteBackend{
+		addr:    addr,
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+	b.fresh = sync.NewCond(&b.mu)
+
+	if err := b.getJSON(infoPath, &b.header); err != nil {
+		return err
+	}
+	if b.header.Format != recordingFormat {
+		return fmt.Errorf("%s is not a mactop server", addr)
+	}
+	var first RecordedSample
+	if err := b.getJSON(snapshotPath+"?detail=1", &first); err == nil {
+		b.deliver(&first)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+	b.connected = true
+	go b.follow(ctx)
+	activeBackend = b
+	return nil
+}
+
+func (b *remoteBackend) getJSON(path string, v any) error {
+	resp, err := b.client.Get(b.baseURL + path)
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", b.addr, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", path, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode %s: %w", path, err)
+	}
+	return nil
+}
+
+// follow keeps a stream open until ctx is cancelled, retrying with
+// exponential backoff whenever it drops.
+func (b *remoteBackend) follow(ctx context.Context) {
+	delay := remoteRetryMin
+	for {
+		gotSamples, err := b.readStream(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if gotSamples {
+			delay = remoteRetryMin
+		}
+		if err != nil {
+			stderrLogger.Printf("remote %s: %v\n", b.addr, err)
+		}
+		b.mu.Lock()
+		b.connected = false
+		b.retryAt = time.Now().Add(delay)
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, remoteRetryMax)
+	}
+}
+
+// readStream consumes one stream connection and reports whether it carried
+// any samples before it ended.
+func (b *remoteBackend) readStream(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	idle := time.AfterFunc(remoteIdleTimeout, cancel)
+	defer idle.Stop()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.baseURL+streamPath+"?detail=1", nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("GET %s: %s", streamPath, resp.Status)
+	}
+
+	b.mu.Lock()
+	b.connected = true
+	b.mu.Unlock()
+
+	var gotSamples bool
+	var data bytes.Buffer
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		idle.Reset(remoteIdleTimeout)
+		line := scanner.Bytes()
+		if len(line) > 0 {
+			if payload, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+				data.Write(bytes.TrimPrefix(payload, []byte(" ")))
+			}
+			continue
+		}
+		// A blank line ends the event.
+		if data.Len() == 0 {
+			continue
+		}
+		s := &RecordedSample{}
+		if err := json.Unmarshal(data.Bytes(), s); err != nil {
+			return gotSamples, fmt.Errorf("decode sample: %w", err)
+		}
+		data.Reset()
+		b.deliver(s)
+		gotSamples = true
+	}
+	if err := scanner.Err(); err != nil {
+		return gotSamples, err
+	}
+	return gotSamples, errors.New("stream closed by server")
+}
+
+func (b *remoteBackend) deliver(s *RecordedSample) {
+	b.mu.Lock()
+	b.current = s
+	b.received++
+	b.mu.Unlock()
+	b.fresh.Broadcast()
+}
+
+func (b *remoteBackend) Name() string { return "remote" }
+
+func (b *remoteBackend) Init() error { return nil }
+
+func (b *remoteBackend) Close() {
+	if b.cancel != nil {
+		b.cancel()
+	}
+}
+
+func (b *remoteBackend) SystemInfo() SystemInfo {
+	return b.header.SystemInfo
+}
+
+// SampleSoc waits for a sample newer than the one it last returned, which
+// paces the collectors to the server's interval.
+func (b *remoteBackend) SampleSoc(durationMs int) SocMetrics {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.received == b.taken {
+		b.fresh.Wait()
+	}
+	b.taken = b.received
+	return b.current.socMetrics()
+}
+
+func (b *remoteBackend) ThermalState() thermalStateLevel {
+	return b.sample().ThermalLevel
+}
+
+func (b *remoteBackend) CPUUsage() ([]CPUUsage, error) {
+	s := b.sample()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cpuTicks = accumulateTicks(b.cpuTicks, s.CoreUsages)
+	return append([]CPUUsage(nil), b.cpuTicks...), nil
+}
+
+func (b *remoteBackend) Memory() (NativeMemoryMetrics, error) {
+	return b.sample().memory(), nil
+}
+
+func (b *remoteBackend) Network() (map[string]NativeNetMetric, error) {
+	return map[string]NativeNetMetric{}, nil
+}
+
+func (b *remoteBackend) Disk() (map[string]NativeDiskMetric, error) {
+	return map[string]NativeDiskMetric{}, nil
+}
+
+func (b *remoteBackend) Processes(systemGpuPercent float64) ([]ProcessMetrics, error) {
+	return b.sample().processes(), nil
+}
+
+func (b *remoteBackend) NetDisk() NetDiskMetrics {
+	return b.sample().NetDisk
+}
+
+func (b *remoteBackend) Volumes() []VolumeInfo {
+	return b.sample().volumes()
+}
+
+func (b *remoteBackend) CoreLabels() ([]string, int, int, int, []int) {
+	return b.header.coreLabels()
+}
+
+// sample returns the latest sample, or an empty one before the first arrives.
+func (b *remoteBackend) sample() *RecordedSample {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.current == nil {
+		return &RecordedSample{}
+	}
+	return b.current
+}
+
+// intervalMs returns the server's sampling interval, 0 if not yet known.
+func (b *remoteBackend) intervalMs() int {
+	return b.sample().IntervalMs
+}
+
+// statusText renders the connection state for the main block's bottom-right title.
+func (b *remoteBackend) statusText() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.connected {
+		return fmt.Sprintf(" ⇄ %s ", b.addr)
+	}
+	wait := max(int(time.Until(b.retryAt).Round(time.Second)/time.Second), 0)
+	return fmt.Sprintf(" ⚠ %s reconnecting in %ds ", b.addr, wait)
+}
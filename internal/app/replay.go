@@ -0,0 +1,280 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// replay.go - Plays a --record file back through the TUI collectors
+package app
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// replaySpeeds are the playback rates selectable with [ and ].
+var replaySpeeds = []float64{0.5, 1, 2, 4, 8}
+
+const replaySeekStep = 10 * time.Second
+
+// replayBackend serves samples from a Recording in place of the host. The
+// regular collectors keep running; each SampleSoc call moves the playhead by
+// the wall time since the previous call scaled by the playback speed.
+type replayBackend struct {
+	rec *Recording
+	// name is reported as the backend name; label, when set, prefixes the
+	// status text. loop wraps playback around instead of holding the last frame.
+	name  string
+	label string
+	loop  bool
+
+	mu       sync.Mutex
+	resume   *sync.Cond
+	pos      time.Duration
+	lastTick time.Time
+	cursor   int
+	speedIdx int
+	paused   bool
+	stepOnce bool
+	cpuTicks []CPUUsage
+}
+
+func newReplayBackend(rec *Recording) *replayBackend {
+	b := &replayBackend{rec: rec, name: "replay", speedIdx: 1}
+	b.resume = sync.NewCond(&b.mu)
+	return b
+}
+
+// startReplay loads path and makes it the active backend.
+func startReplay(path string) error {
+	rec, err := loadRecording(path)
+	if err != nil {
+		return err
+	}
+	activeBackend = newReplayBackend(rec)
+	return nil
+}
+
+// setupRecordReplay applies --connect, --replay, --demo and --record before
+// any mode starts sampling: a remote server, replay or demo replaces the
+// backend, and the recording captures whatever the active backend produces.
+func setupRecordReplay() {
+	if connectAddr != "" {
+		if err := startRemote(connectAddr); err != nil {
+			stderrLogger.Fatalf("failed to connect: %v", err)
+		}
+	}
+	if demoName != "" {
+		if err := startDemo(demoName); err != nil {
+			stderrLogger.Fatalf("failed to start demo: %v", err)
+		}
+	}
+	if replayPath != "" {
+		if err := startReplay(replayPath); err != nil {
+			stderrLogger.Fatalf("failed to load replay: %v", err)
+		}
+	}
+	if !readsThisHost() {
+		// The fans on screen belong to another machine or to nobody.
+		fanControl = false
+	}
+	if recordPath != "" {
+		if err := startRecording(recordPath); err != nil {
+			stderrLogger.Fatalf("failed to start recording: %v", err)
+		}
+	}
+}
+
+// activeReplay returns the replay backend when --replay is in effect.
+func activeReplay() *replayBackend {
+	b, _ := activeBackend.(*replayBackend)
+	return b
+}
+
+func (b *replayBackend) Name() string { return b.name }
+
+func (b *replayBackend) Init() error { return nil }
+
+func (b *replayBackend) Close() {}
+
+func (b *replayBackend) SystemInfo() SystemInfo {
+	return b.rec.Header.SystemInfo
+}
+
+// SampleSoc advances the playhead and returns the SoC metrics of the sample
+// under it. While paused it blocks until playback resumes or a seek asks for
+// a single frame, so the charts hold still.
+func (b *replayBackend) SampleSoc(durationMs int) SocMetrics {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.paused && !b.stepOnce {
+		b.resume.Wait()
+	}
+	b.stepOnce = false
+	b.advance(time.Now())
+
+	return b.rec.Samples[b.cursor].socMetrics()
+}
+
+func (b *replayBackend) ThermalState() thermalStateLevel {
+	return b.current().ThermalLevel
+}
+
+// CPUUsage returns tick counters whose deltas reproduce the recorded
+// per-core usage.
+func (b *replayBackend) CPUUsage() ([]CPUUsage, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cpuTicks = accumulateTicks(b.cpuTicks, b.rec.Samples[b.cursor].CoreUsages)
+	return append([]CPUUsage(nil), b.cpuTicks...), nil
+}
+
+func (b *replayBackend) Memory() (NativeMemoryMetrics, error) {
+	return b.current().memory(), nil
+}
+
+func (b *replayBackend) Network() (map[string]NativeNetMetric, error) {
+	return map[string]NativeNetMetric{}, nil
+}
+
+func (b *replayBackend) Disk() (map[string]NativeDiskMetric, error) {
+	return map[string]NativeDiskMetric{}, nil
+}
+
+func (b *replayBackend) Processes(systemGpuPercent float64) ([]ProcessMetrics, error) {
+	return b.current().processes(), nil
+}
+
+func (b *replayBackend) NetDisk() NetDiskMetrics {
+	return b.current().NetDisk
+}
+
+func (b *replayBackend) Volumes() []VolumeInfo {
+	return b.current().volumes()
+}
+
+func (b *replayBackend) CoreLabels() ([]string, int, int, int, []int) {
+	return b.rec.Header.coreLabels()
+}
+
+func (b *replayBackend) current() *RecordedSample {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return &b.rec.Samples[b.cursor]
+}
+
+// advance moves the playhead by the scaled wall time since the last call and
+// selects the sample under it. Samples within half an interval of the
+// playhead count as reached, which absorbs timer jitter at 1x.
+func (b *replayBackend) advance(now time.Time) {
+	if !b.paused && !b.lastTick.IsZero() {
+		b.pos += time.Duration(float64(now.Sub(b.lastTick)) * replaySpeeds[b.speedIdx])
+	}
+	b.lastTick = now
+	b.clampPosition()
+
+	slack := b.rec.interval(b.cursor) / 2
+	offsets := b.rec.offsets
+	b.cursor = sort.Search(len(offsets), func(i int) bool { return offsets[i] > b.pos+slack }) - 1
+	if b.cursor < 0 {
+		b.cursor = 0
+	}
+}
+
+func (b *replayBackend) clampPosition() {
+	if b.loop {
+		// One interval past the last sample comes the first one again.
+		period := b.rec.Duration() + b.rec.interval(len(b.rec.Samples)-1)
+		b.pos %= period
+		if b.pos < 0 {
+			b.pos += period
+		}
+		return
+	}
+	if b.pos < 0 {
+		b.pos = 0
+	}
+	if end := b.rec.Duration(); b.pos > end {
+		b.pos = end
+	}
+}
+
+func (b *replayBackend) togglePause() {
+	b.mu.Lock()
+	b.paused = !b.paused
+	b.lastTick = time.Now()
+	b.mu.Unlock()
+	b.resume.Broadcast()
+}
+
+// seek moves the playhead and lets one frame through even while paused.
+func (b *replayBackend) seek(delta time.Duration) {
+	b.mu.Lock()
+	b.pos += delta
+	b.clampPosition()
+	b.stepOnce = true
+	b.mu.Unlock()
+	b.resume.Broadcast()
+	select {
+	case interruptChan <- struct{}{}:
+	default:
+	}
+}
+
+func (b *replayBackend) changeSpeed(step int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.speedIdx += step
+	if b.speedIdx < 0 {
+		b.speedIdx = 0
+	}
+	if b.speedIdx >= len(replaySpeeds) {
+		b.speedIdx = len(replaySpeeds) - 1
+	}
+}
+
+// statusText renders the playback state for the main block's bottom-right title.
+func (b *replayBackend) statusText() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state := "▶"
+	if b.paused {
+		state = "⏸"
+	}
+	status := fmt.Sprintf(" %s %gx %s/%s ", state, replaySpeeds[b.speedIdx],
+		formatReplayClock(b.pos), formatReplayClock(b.rec.Duration()))
+	if b.label != "" {
+		status = " " + b.label + status
+	}
+	return status
+}
+
+func formatReplayClock(d time.Duration) string {
+	secs := int(d / time.Second)
+	if secs >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", secs/3600, secs/60%60, secs%60)
+	}
+	return fmt.Sprintf("%02d:%02d", secs/60, secs%60)
+}
+
+// handleReplayKeys handles the playback controls: s pauses, , and . seek,
+// [ and ] change speed.
+func handleReplayKeys(key string) {
+	b := activeReplay()
+	if b == nil {
+		return
+	}
+	switch key {
+	case "s":
+		b.togglePause()
+	case ",":
+		b.seek(-replaySeekStep)
+	case ".":
+		b.seek(replaySeekStep)
+	case "[":
+		b.changeSpeed(-1)
+	case "]":
+		b.changeSpeed(1)
+	}
+	renderMutex.Lock()
+	updateIntervalText()
+	renderMutex.Unlock()
+	renderUI()
+}
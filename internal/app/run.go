@@ -0,0 +1,342 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// run.go - `mactop run -- <command>`: profiles power and thermals of one command, like time(1)
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// runIntervalMs is how often `mactop run` samples unless --interval is
+// given; short jobs need far more than one sample a second.
+const runIntervalMs = 100
+
+// runSample is what `mactop run` reads from the collectors each interval.
+type runSample struct {
+	soc       SocMetrics
+	cpu       float64 // Average across all cores
+	thermal   thermalStateLevel
+	processes []ProcessMetrics
+}
+
+// runUtilization is the mean and highest reading of one utilization.
+type runUtilization struct {
+	Avg  float64 `json:"avg_percent"`
+	Peak float64 `json:"peak_percent"`
+	sum  float64
+}
+
+func (u *runUtilization) add(v float64) {
+	u.sum += v
+	u.Peak = math.Max(u.Peak, v)
+}
+
+// runPeakTemps are the hottest readings seen, in Celsius.
+type runPeakTemps struct {
+	CPU float64 `json:"cpu_celsius"`
+	GPU float64 `json:"gpu_celsius"`
+	SoC float64 `json:"soc_celsius"`
+}
+
+// runProcessTree is the command and every process it started. CPU time
+// comes from the kernel when the command exits and covers descendants it
+// waited for; GPU time is integrated from samples, so very short-lived
+// processes between two samples are missed.
+type runProcessTree struct {
+	Processes     int     `json:"processes"`
+	CPUSeconds    float64 `json:"cpu_seconds"`
+	UserSeconds   float64 `json:"user_seconds"`
+	SystemSeconds float64 `json:"system_seconds"`
+	GPUSeconds    float64 `json:"gpu_seconds"`
+}
+
+// runSummary is printed when the command exits.
+type runSummary struct {
+	Command          []string       `json:"command"`
+	ExitCode         int            `json:"exit_code"`
+	WallSeconds      float64        `json:"wall_seconds"`
+	Samples          int            `json:"samples"`
+	IntervalMs       int            `json:"interval_ms"`
+	CPU              runUtilization `json:"cpu"`
+	GPU              runUtilization `json:"gpu"`
+	ANE              runUtilization `json:"ane"`
+	Energy           HeadlessEnergy `json:"energy"`
+	PeakTemps        runPeakTemps   `json:"peak_temps"`
+	ThrottledSeconds float64        `json:"throttled_seconds"`
+	FanPeakRPM       int            `json:"fan_peak_rpm"`
+	ProcessTree      runProcessTree `json:"process_tree"`
+}
+
+// runProfile accumulates samples for the lifetime of the command rooted at
+// pid. Samples only come from the sampling loop, so it needs no locking.
+type runProfile struct {
+	root    int
+	last    time.Time
+	energy  energyMeter
+	seen    map[int]bool
+	summary runSummary
+}
+
+// newRunProfile starts a profile at start, when the command was launched;
+// the first sample's power then counts from there.
+func newRunProfile(root int, start time.Time) *runProfile {
+	p := &runProfile{root: root, last: start, seen: make(map[int]bool)}
+	p.energy.start, p.energy.last = start, start
+	return p
+}
+
+func (p *runProfile) add(s runSample, now time.Time) {
+	dt := now.Sub(p.last).Seconds()
+	p.last = now
+	p.energy.add(s.soc, now)
+
+	r := &p.summary
+	r.Samples++
+	r.CPU.add(s.cpu)
+	r.GPU.add(s.soc.GPUActive)
+	// Same scale as the ANE gauge: 8 W is treated as full load.
+	r.ANE.add(s.soc.ANEPower / 8.0 * 100)
+	r.PeakTemps.CPU = math.Max(r.PeakTemps.CPU, float64(s.soc.CPUTemp))
+	r.PeakTemps.GPU = math.Max(r.PeakTemps.GPU, float64(s.soc.GPUTemp))
+	r.PeakTemps.SoC = math.Max(r.PeakTemps.SoC, float64(s.soc.SocTemp))
+	for _, fan := range s.soc.Fans {
+		r.FanPeakRPM = max(r.FanPeakRPM, fan.ActualRPM)
+	}
+	// macOS throttles from Serious pressure onwards.
+	if s.thermal >= thermalStateSerious {
+		r.ThrottledSeconds += dt
+	}
+
+	parents := make(map[int]int, len(s.processes))
+	for _, proc := range s.processes {
+		parents[proc.PID] = proc.PPID
+	}
+	for _, proc := range s.processes {
+		if !p.inTree(proc.PID, parents) {
+			continue
+		}
+		p.seen[proc.PID] = true
+		r.ProcessTree.GPUSeconds += proc.GPU / 1000 * dt
+	}
+}
+
+// inTree reports whether pid is the profiled command or descends from it.
+func (p *runProfile) inTree(pid int, parents map[int]int) bool {
+	// The depth bound guards against a PPID cycle from a racy snapshot.
+	for range 64 {
+		if pid == p.root {
+			return true
+		}
+		ppid, ok := parents[pid]
+		if !ok || ppid <= 1 {
+			return false
+		}
+		pid = ppid
+	}
+	return false
+}
+
+// finish completes the summary once the command has exited.
+func (p *runProfile) finish(command []string, start, end time.Time, state *os.ProcessState) runSummary {
+	r := p.summary
+	r.Command = command
+	r.WallSeconds = end.Sub(start).Seconds()
+	r.Energy = p.energy.snapshot()
+	if r.Samples > 0 {
+		n := float64(r.Samples)
+		r.CPU.Avg, r.GPU.Avg, r.ANE.Avg = r.CPU.sum/n, r.GPU.sum/n, r.ANE.sum/n
+	}
+	r.ProcessTree.Processes = max(len(p.seen), 1)
+	if state != nil {
+		r.ExitCode = state.ExitCode()
+		r.ProcessTree.UserSeconds = state.UserTime().Seconds()
+		r.ProcessTree.SystemSeconds = state.SystemTime().Seconds()
+		r.ProcessTree.CPUSeconds = r.ProcessTree.UserSeconds + r.ProcessTree.SystemSeconds
+	}
+	return r
+}
+
+// runCommand is `mactop run`. It starts the command given after the flags,
+// samples until it exits, prints the summary to stderr as time(1) does and
+// exits with the command's status.
+func runCommand() {
+	command := flag.Args()
+	if len(command) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: mactop run requires a command, e.g. mactop run -- make -j8")
+		os.Exit(2)
+	}
+	asJSON := flagPassed("format")
+	if asJSON && strings.ToLower(headlessFormat) != "json" {
+		fmt.Fprintf(os.Stderr, "Error: mactop run prints text, or JSON with --format json; got %q\n", headlessFormat)
+		os.Exit(2)
+	}
+	interval := runSampleInterval()
+
+	if err := initSocMetrics(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to initialize metrics: %v\n", err)
+		os.Exit(1)
+	}
+	// Prime the CPU and process deltas so the first sample is meaningful.
+	GetCPUPercentages()
+	getProcessList(0)
+
+	var profile *runProfile
+	res, err := superviseCommand(command,
+		func(pid int, start time.Time) { profile = newRunProfile(pid, start) },
+		func() { profile.add(takeRunSample(interval), time.Now()) })
+	cleanupSocMetrics()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if res.state == nil {
+			os.Exit(127)
+		}
+	}
+
+	summary := profile.finish(command, res.start, res.end, res.state)
+	summary.IntervalMs = interval
+	if err := writeRunSummary(os.Stderr, summary, asJSON); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	os.Exit(runExitCode(res.state))
+}
+
+// runSampleInterval is --interval when given, else runIntervalMs.
+func runSampleInterval() int {
+	if flagPassed("interval") || flagPassed("i") {
+		return updateInterval
+	}
+	return runIntervalMs
+}
+
+// commandResult is when a supervised command ran and how it ended.
+type commandResult struct {
+	start, end time.Time
+	state      *os.ProcessState
+}
+
+// superviseCommand starts command on mactop's stdio, calls started with its
+// PID, then calls sample back to back until it exits; sample is expected to
+// block for one interval. The error is nil when the command ran, whatever
+// its exit status; a nil state means it never started.
+func superviseCommand(command []string, started func(pid int, start time.Time), sample func()) (commandResult, error) {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	// Ctrl-C reaches the command through the terminal; mactop stays up to
+	// report on it. SIGTERM is only sent to mactop, so pass it on.
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	res := commandResult{start: time.Now()}
+	if err := cmd.Start(); err != nil {
+		return res, err
+	}
+	started(cmd.Process.Pid, res.start)
+
+	done := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		res.end = time.Now()
+		done <- err
+	}()
+
+	for {
+		select {
+		case err := <-done:
+			res.state = cmd.ProcessState
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				err = nil
+			}
+			return res, err
+		case sig := <-sigChan:
+			if sig == syscall.SIGTERM {
+				cmd.Process.Signal(sig)
+			}
+		default:
+		}
+		sample()
+	}
+}
+
+// runExitCode passes the command's status on, using the shell's 128+n
+// convention when a signal killed it.
+func runExitCode(state *os.ProcessState) int {
+	if state == nil {
+		return 1
+	}
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return state.ExitCode()
+}
+
+// takeRunSample blocks for intervalMs while the power sample is taken.
+func takeRunSample(intervalMs int) runSample {
+	s := runSample{soc: sampleSocMetrics(intervalMs), thermal: getThermalStateLevel()}
+	if cores, err := GetCPUPercentages(); err == nil && len(cores) > 0 {
+		for _, c := range cores {
+			s.cpu += c
+		}
+		s.cpu /= float64(len(cores))
+	}
+	if procs, err := getProcessList(s.soc.GPUActive); err == nil {
+		s.processes = procs
+	}
+	return s
+}
+
+func writeRunSummary(w io.Writer, r runSummary, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	e := r.Energy
+	tree := r.ProcessTree
+	lines := []string{
+		fmt.Sprintf("mactop run: %s", strings.Join(r.Command, " ")),
+		fmt.Sprintf("  exit status   %d", r.ExitCode),
+		fmt.Sprintf("  wall time     %.2fs (%d samples every %dms)", r.WallSeconds, r.Samples, r.IntervalMs),
+		fmt.Sprintf("  CPU           avg %5.1f%%  peak %5.1f%%", r.CPU.Avg, r.CPU.Peak),
+		fmt.Sprintf("  GPU           avg %5.1f%%  peak %5.1f%%", r.GPU.Avg, r.GPU.Peak),
+		fmt.Sprintf("  ANE           avg %5.1f%%  peak %5.1f%%", r.ANE.Avg, r.ANE.Peak),
+		fmt.Sprintf("  energy        %.1f J system (%.3f Wh), package %.1f J", e.SystemJoules, e.SessionWh, e.PackageJoules),
+		fmt.Sprintf("                cpu %.1f J, gpu %.1f J, ane %.1f J, dram %.1f J", e.CPUJoules, e.GPUJoules, e.ANEJoules, e.DRAMJoules),
+	}
+	if e.Cost > 0 {
+		lines = append(lines, fmt.Sprintf("  cost          %.4f", e.Cost))
+	}
+	lines = append(lines,
+		fmt.Sprintf("  peak temp     CPU %s  GPU %s  SoC %s",
+			formatTemp(r.PeakTemps.CPU), formatTemp(r.PeakTemps.GPU), formatTemp(r.PeakTemps.SoC)),
+		fmt.Sprintf("  throttled     %.1fs", r.ThrottledSeconds),
+		fmt.Sprintf("  fan peak      %d RPM", r.FanPeakRPM),
+		fmt.Sprintf("  process tree  %d processes, CPU %.2fs (user %.2fs, sys %.2fs), GPU %.2fs",
+			tree.Processes, tree.CPUSeconds, tree.UserSeconds, tree.SystemSeconds, tree.GPUSeconds),
+	)
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+// flagPassed reports whether the named flag was given on the command line,
+// as opposed to holding its default.
+func flagPassed(name string) bool {
+	passed := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			passed = true
+		}
+	})
+	return passed
+}
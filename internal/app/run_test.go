@@ -0,0 +1,76 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunProfile(t *testing.T) {
+	start := time.Unix(1000, 0)
+	p := newRunProfile(100, start)
+	procs := []ProcessMetrics{
+		{PID: 100, PPID: 50, GPU: 0},
+		{PID: 101, PPID: 100, GPU: 400},
+		{PID: 102, PPID: 101, GPU: 100},
+		{PID: 200, PPID: 1, GPU: 900}, // Not ours
+	}
+	p.add(runSample{
+		soc:       SocMetrics{CPUPower: 10, TotalPower: 10, GPUActive: 20, ANEPower: 2, CPUTemp: 70, Fans: []FanInfo{{ActualRPM: 1800}}},
+		cpu:       40,
+		processes: procs,
+	}, start.Add(time.Second))
+	p.add(runSample{
+		soc:       SocMetrics{CPUPower: 20, TotalPower: 20, GPUActive: 60, CPUTemp: 95, Fans: []FanInfo{{ActualRPM: 3600}}},
+		cpu:       80,
+		thermal:   thermalStateSerious,
+		processes: procs[:2],
+	}, start.Add(3*time.Second))
+
+	r := p.finish([]string{"make"}, start, start.Add(3*time.Second), nil)
+	for name, tc := range map[string]struct{ got, want float64 }{
+		"wall":      {r.WallSeconds, 3},
+		"cpu avg":   {r.CPU.Avg, 60},
+		"cpu peak":  {r.CPU.Peak, 80},
+		"gpu avg":   {r.GPU.Avg, 40},
+		"ane peak":  {r.ANE.Peak, 25},
+		"cpu temp":  {r.PeakTemps.CPU, 95},
+		"throttled": {r.ThrottledSeconds, 2},
+		"cpu J":     {r.Energy.CPUJoules, 10*1 + 20*2},
+		// 0.5 s/s for 1s, then 0.4 s/s for 2s; PID 200 is not in the tree.
+		"gpu seconds": {r.ProcessTree.GPUSeconds, 0.5 + 0.8},
+	} {
+		if math.Abs(tc.got-tc.want) > 1e-9 {
+			t.Errorf("%s = %v, want %v", name, tc.got, tc.want)
+		}
+	}
+	if r.FanPeakRPM != 3600 {
+		t.Errorf("FanPeakRPM = %d, want 3600", r.FanPeakRPM)
+	}
+	if r.ProcessTree.Processes != 3 {
+		t.Errorf("Processes = %d, want 3", r.ProcessTree.Processes)
+	}
+
+	var text, js bytes.Buffer
+	if err := writeRunSummary(&text, r, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"mactop run: make", "avg  60.0%  peak  80.0%", "throttled     2.0s", "fan peak      3600 RPM"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text summary missing %q:\n%s", want, text.String())
+		}
+	}
+	if err := writeRunSummary(&js, r, true); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded["throttled_seconds"] != 2.0 {
+		t.Errorf("JSON throttled_seconds = %v, want 2", decoded["throttled_seconds"])
+	}
+}
@@ -0,0 +1,467 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// schema.go - `mactop schema`: the headless output contract as JSON Schema, XSD or CSV columns
+package app
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// headlessSchemaVersion is written into every headless sample as
+// schema_version. It goes up whenever a field is added, renamed, retyped or
+// removed, so parsers can pin the contract they were written against.
+//
+// Fields on their way out carry a `deprecated:"..."` tag saying what
+// replaces them. They keep being written, marked as deprecated in every
+// generated schema, for at least one release before they are removed.
+const headlessSchemaVersion = 1
+
+// csvColumn is one column of the default or --flat CSV output.
+type csvColumn struct {
+	name   string
+	source string // --fields path of the value in the JSON output
+	kind   string // Set when the cell isn't the source value itself
+}
+
+// headlessCSVLeadColumns come before the per-core columns and
+// headlessCSVTrailColumns after them. headlessCSVLeadRecord has the cells
+// of the lead columns in the same order.
+var headlessCSVLeadColumns = []csvColumn{
+	{"Timestamp", "timestamp", ""},
+	{"System_Name", "system_info.name", ""},
+	{"Core_Count", "system_info.core_count", ""},
+	{"E_Core_Count", "system_info.e_core_count", ""},
+	{"P_Core_Count", "system_info.p_core_count", ""},
+	{"S_Core_Count", "system_info.s_core_count", ""},
+	{"GPU_Core_Count", "system_info.gpu_core_count", ""},
+	{"CPU_Usage", "cpu_usage", ""},
+	{"ECPU_Freq_MHz", "ecpu_usage[0]", ""},
+	{"ECPU_Active", "ecpu_usage[1]", ""},
+	{"PCPU_Freq_MHz", "pcpu_usage[0]", ""},
+	{"PCPU_Active", "pcpu_usage[1]", ""},
+	{"SCPU_Freq_MHz", "scpu_usage[0]", ""},
+	{"SCPU_Active", "scpu_usage[1]", ""},
+	{"GPU_Usage", "gpu_usage", ""},
+	{"GPU_Freq_MHz", "gpu_metrics.freq_mhz", ""},
+	{"GPU_Active_Percent", "gpu_metrics.active_percent", ""},
+	{"Display_FPS", "display_fps", ""},
+	{"Frame_Interval_Ms", "frame_interval_ms", ""},
+	{"Mem_Used", "memory.used", ""},
+	{"Mem_Total", "memory.total", ""},
+	{"Swap_Used", "memory.swap_used", ""},
+	{"Disk_Read_KB", "net_disk.read_kbytes_per_sec", ""},
+	{"Disk_Write_KB", "net_disk.write_kbytes_per_sec", ""},
+	{"Net_In_Bytes", "net_disk.in_bytes_per_sec", ""},
+	{"Net_Out_Bytes", "net_disk.out_bytes_per_sec", ""},
+	{"TB_Net_In_Bytes", "tb_net_total_bytes_in_per_sec", ""},
+	{"TB_Net_Out_Bytes", "tb_net_total_bytes_out_per_sec", ""},
+	{"Total_Power", "soc_metrics.total_power", ""},
+	{"System_Power", "soc_metrics.system_power", ""},
+	{"CPU_Temp", "soc_metrics.cpu_temp", ""},
+	{"GPU_Temp", "soc_metrics.gpu_temp", ""},
+	{"Thermal_State", "thermal_state", ""},
+	{"DRAM_Read_BW_GBs", "soc_metrics.dram_read_bw_gbs", ""},
+	{"DRAM_Write_BW_GBs", "soc_metrics.dram_write_bw_gbs", ""},
+	{"DRAM_BW_Combined_GBs", "soc_metrics.dram_bw_combined_gbs", ""},
+	{"RDMA_Available", "rdma_status.available", ""},
+	{"RDMA_Status", "rdma_status.status", ""},
+	{"RDMA_Device_Count", "rdma_status.devices", "integer"},
+}
+
+// Complex nested data goes into JSON blobs.
+var headlessCSVTrailColumns = []csvColumn{
+	{"Thunderbolt_Info_JSON", "thunderbolt_info", ""},
+	{"Processes_JSON", "processes", ""},
+	{"Network_Links_JSON", "network_links", ""},
+	{"Volumes_JSON", "volumes", ""},
+}
+
+// headlessCSVColumns is the default CSV layout for a machine with cores
+// cores, each of which gets a Core_N column.
+func headlessCSVColumns(cores int) []csvColumn {
+	columns := slices.Clone(headlessCSVLeadColumns)
+	for i := 0; i < cores; i++ {
+		columns = append(columns, csvColumn{fmt.Sprintf("Core_%d", i), fmt.Sprintf("core_usages[%d]", i), ""})
+	}
+	return append(columns, headlessCSVTrailColumns...)
+}
+
+// headlessCSVLeadRecord is the cells of headlessCSVLeadColumns.
+func headlessCSVLeadRecord(output HeadlessOutput) []string {
+	return []string{
+		output.Timestamp,
+		output.SystemInfo.Name,
+		fmt.Sprintf("%d", output.SystemInfo.CoreCount),
+		fmt.Sprintf("%d", output.SystemInfo.ECoreCount),
+		fmt.Sprintf("%d", output.SystemInfo.PCoreCount),
+		fmt.Sprintf("%d", output.SystemInfo.SCoreCount),
+		fmt.Sprintf("%d", output.SystemInfo.GPUCoreCount),
+		fmt.Sprintf("%.2f", output.CPUUsage),
+		fmt.Sprintf("%.2f", safeFloat64At(output.ECPUUsage, 0)),
+		fmt.Sprintf("%.2f", safeFloat64At(output.ECPUUsage, 1)),
+		fmt.Sprintf("%.2f", safeFloat64At(output.PCPUUsage, 0)),
+		fmt.Sprintf("%.2f", safeFloat64At(output.PCPUUsage, 1)),
+		fmt.Sprintf("%.2f", safeFloat64At(output.SCPUUsage, 0)),
+		fmt.Sprintf("%.2f", safeFloat64At(output.SCPUUsage, 1)),
+		fmt.Sprintf("%.2f", output.GPUUsage),
+		fmt.Sprintf("%d", output.GPUMetrics.FreqMHz),
+		fmt.Sprintf("%.2f", output.GPUMetrics.ActivePercent),
+		fmt.Sprintf("%d", output.DisplayFPS),
+		fmt.Sprintf("%.2f", output.FrameIntervalMs),
+		fmt.Sprintf("%d", output.Memory.Used),
+		fmt.Sprintf("%d", output.Memory.Total),
+		fmt.Sprintf("%d", output.Memory.SwapUsed),
+		fmt.Sprintf("%.2f", output.NetDisk.ReadKBytesPerSec),
+		fmt.Sprintf("%.2f", output.NetDisk.WriteKBytesPerSec),
+		fmt.Sprintf("%.2f", output.NetDisk.InBytesPerSec),
+		fmt.Sprintf("%.2f", output.NetDisk.OutBytesPerSec),
+		fmt.Sprintf("%.2f", output.TBNetTotalBytesInSec),
+		fmt.Sprintf("%.2f", output.TBNetTotalBytesOutSec),
+		fmt.Sprintf("%.2f", output.SocMetrics.TotalPower),
+		fmt.Sprintf("%.2f", output.SocMetrics.SystemPower),
+		fmt.Sprintf("%.2f", output.SocMetrics.CPUTemp),
+		fmt.Sprintf("%.2f", output.SocMetrics.GPUTemp),
+		output.ThermalState,
+		fmt.Sprintf("%.2f", output.SocMetrics.DRAMReadBW),
+		fmt.Sprintf("%.2f", output.SocMetrics.DRAMWriteBW),
+		fmt.Sprintf("%.2f", output.SocMetrics.DRAMBWCombined),
+		fmt.Sprintf("%t", output.RDMAStatus.Available),
+		output.RDMAStatus.Status,
+		fmt.Sprintf("%d", len(output.RDMAStatus.Devices)),
+	}
+}
+
+// headlessCSVCoreRecord is the cells of the Core_N columns of a machine with
+// cores cores.
+func headlessCSVCoreRecord(output HeadlessOutput, cores int) []string {
+	record := make([]string, cores)
+	for i := range record {
+		val := 0.0
+		if i < len(output.CoreUsages) {
+			val = output.CoreUsages[i]
+		}
+		record[i] = fmt.Sprintf("%.2f", val)
+	}
+	return record
+}
+
+// describe finds the column's source field and returns the type of its
+// cells: string, integer, number, boolean, or json for a blob.
+func (c csvColumn) describe() (kind string, field reflect.StructField, err error) {
+	chain, err := parseFieldPath(c.source)
+	if err != nil {
+		// --flat columns that pick list elements by name, which --fields
+		// paths can't, always have a kind.
+		if c.kind != "" {
+			return c.kind, field, nil
+		}
+		return "", field, err
+	}
+	t := reflect.TypeFor[HeadlessOutput]()
+	for _, s := range chain {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		idx, ok := jsonFieldIndex(t, s.name)
+		if !ok {
+			return "", field, fmt.Errorf("%s: unknown field %q", c.name, c.source)
+		}
+		field = t.Field(idx)
+		t = field.Type
+		if s.index {
+			t = t.Elem()
+		}
+	}
+	if c.kind != "" {
+		return c.kind, field, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string", field, nil
+	case reflect.Bool:
+		return "boolean", field, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer", field, nil
+	case reflect.Float32, reflect.Float64:
+		return "number", field, nil
+	}
+	return "json", field, nil
+}
+
+// runSchema is `mactop schema`. --format picks the contract: json-schema
+// (or json), xsd (or xml) or csv-columns (or csv).
+func runSchema() {
+	var data []byte
+	var err error
+	switch strings.ToLower(headlessFormat) {
+	case "json", "json-schema":
+		data, err = headlessJSONSchema()
+	case "xml", "xsd":
+		data = headlessXSD()
+	case "csv", "csv-columns":
+		data, err = headlessCSVSchema(schemaCSVColumns())
+	default:
+		fmt.Fprintf(os.Stderr, "Error: mactop schema --format must be json-schema, xsd or csv-columns, not %q\n", headlessFormat)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	os.Stdout.Write(data)
+}
+
+// schemaField is a struct field as one encoder sees it.
+type schemaField struct {
+	reflect.StructField
+	name      string
+	omitempty bool
+}
+
+// optional reports whether the encoder can leave the field out: omitempty
+// never drops a struct.
+func (f schemaField) optional() bool {
+	return f.omitempty && f.Type.Kind() != reflect.Struct
+}
+
+// schemaFields lists the fields of struct type t that the encoder reading
+// the key struct tag writes, under the names it writes them.
+func schemaFields(t reflect.Type, key string) []schemaField {
+	var fields []schemaField
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		tag := sf.Tag.Get(key)
+		if !sf.IsExported() || tag == "-" || sf.Type == reflect.TypeFor[xml.Name]() {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, schemaField{sf, name, slices.Contains(strings.Split(opts, ","), "omitempty")})
+	}
+	return fields
+}
+
+// isSchemaVersion reports whether f is HeadlessOutput.SchemaVersion, which
+// the schemas pin to headlessSchemaVersion.
+func isSchemaVersion(t reflect.Type, f schemaField) bool {
+	return t == reflect.TypeFor[HeadlessOutput]() && f.Name == "SchemaVersion"
+}
+
+// jsonSchemaBuilder collects a definition per named struct type.
+type jsonSchemaBuilder struct {
+	defs map[string]any
+}
+
+// headlessJSONSchema is a JSON Schema (draft 2020-12) for one headless JSON
+// sample, from the json tags.
+func headlessJSONSchema() ([]byte, error) {
+	b := &jsonSchemaBuilder{defs: make(map[string]any)}
+	root := b.object(reflect.TypeFor[HeadlessOutput]())
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["title"] = "mactop headless sample"
+	root["description"] = fmt.Sprintf("One sample of mactop --headless --format json, schema_version %d. With --count the samples are wrapped in an array.", headlessSchemaVersion)
+	root["$defs"] = b.defs
+	data, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// object is the schema of struct type t. Fields that are always written
+// are required.
+func (b *jsonSchemaBuilder) object(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	required := []string{}
+	for _, f := range schemaFields(t, "json") {
+		prop := b.value(f.Type, !f.optional())
+		if isSchemaVersion(t, f) {
+			prop["const"] = headlessSchemaVersion
+		}
+		if note, ok := f.Tag.Lookup("deprecated"); ok {
+			prop["deprecated"] = true
+			prop["description"] = "Deprecated: " + note
+		}
+		props[f.name] = prop
+		if !f.optional() {
+			required = append(required, f.name)
+		}
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// value is the schema of a value of type t. A nil slice or pointer is
+// written as null unless the field is left out when empty.
+func (b *jsonSchemaBuilder) value(t reflect.Type, nullable bool) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		inner := b.value(t.Elem(), false)
+		if !nullable {
+			return inner
+		}
+		return map[string]any{"anyOf": []any{inner, map[string]any{"type": "null"}}}
+	case reflect.Slice:
+		s := map[string]any{"type": "array", "items": b.value(t.Elem(), false)}
+		if nullable {
+			s["type"] = []string{"array", "null"}
+		}
+		return s
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.value(t.Elem(), false)}
+	case reflect.Struct:
+		if _, ok := b.defs[t.Name()]; !ok {
+			b.defs[t.Name()] = true // Placeholder while the fields are walked
+			b.defs[t.Name()] = b.object(t)
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+	return map[string]any{}
+}
+
+// xsdScalarTypes are the built-in XSD types of the kinds encoding/xml
+// writes as text.
+var xsdScalarTypes = map[reflect.Kind]string{
+	reflect.String:  "xs:string",
+	reflect.Bool:    "xs:boolean",
+	reflect.Int:     "xs:long",
+	reflect.Int8:    "xs:byte",
+	reflect.Int16:   "xs:short",
+	reflect.Int32:   "xs:int",
+	reflect.Int64:   "xs:long",
+	reflect.Uint:    "xs:unsignedLong",
+	reflect.Uint8:   "xs:unsignedByte",
+	reflect.Uint16:  "xs:unsignedShort",
+	reflect.Uint32:  "xs:unsignedInt",
+	reflect.Uint64:  "xs:unsignedLong",
+	reflect.Float32: "xs:float",
+	reflect.Float64: "xs:double",
+}
+
+// xsdBuilder writes a complex type per struct type, in the order they are
+// first referenced.
+type xsdBuilder struct {
+	queue []reflect.Type
+	seen  map[reflect.Type]bool
+}
+
+// headlessXSD is an XML Schema for headless XML output, from the xml tags.
+// Fields without one are written under their Go name, and encoding/xml
+// ignores json:"-", so some fields only appear in XML.
+func headlessXSD() []byte {
+	b := &xsdBuilder{seen: make(map[reflect.Type]bool)}
+	root := b.typeName(reflect.TypeFor[HeadlessOutput]())
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">` + "\n")
+	fmt.Fprintf(&buf, "  <xs:annotation>\n    <xs:documentation>mactop --headless --format xml, schema_version %d</xs:documentation>\n  </xs:annotation>\n", headlessSchemaVersion)
+	buf.WriteString("  <xs:element name=\"MactopOutputList\">\n    <xs:complexType>\n      <xs:sequence>\n")
+	fmt.Fprintf(&buf, "        <xs:element name=%q type=%q minOccurs=\"0\" maxOccurs=\"unbounded\"/>\n", root, root)
+	buf.WriteString("      </xs:sequence>\n    </xs:complexType>\n  </xs:element>\n")
+	for i := 0; i < len(b.queue); i++ {
+		b.writeType(&buf, b.queue[i])
+	}
+	buf.WriteString("</xs:schema>\n")
+	return buf.Bytes()
+}
+
+// typeName is the XSD type of t, queueing a complex type for structs.
+func (b *xsdBuilder) typeName(t reflect.Type) string {
+	if t.Kind() != reflect.Struct {
+		return xsdScalarTypes[t.Kind()]
+	}
+	if !b.seen[t] {
+		b.seen[t] = true
+		b.queue = append(b.queue, t)
+	}
+	return t.Name()
+}
+
+// writeType writes the complex type of struct type t. A nil pointer or an
+// empty slice writes no element, and every slice element is one element.
+func (b *xsdBuilder) writeType(buf *bytes.Buffer, t reflect.Type) {
+	fmt.Fprintf(buf, "  <xs:complexType name=%q>\n    <xs:sequence>\n", t.Name())
+	for _, f := range schemaFields(t, "xml") {
+		ft := f.Type
+		attrs := ""
+		if ft.Kind() == reflect.Slice {
+			attrs = ` minOccurs="0" maxOccurs="unbounded"`
+			ft = ft.Elem()
+		} else if f.optional() || ft.Kind() == reflect.Pointer {
+			attrs = ` minOccurs="0"`
+		}
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if isSchemaVersion(t, f) {
+			attrs += fmt.Sprintf(` fixed="%d"`, headlessSchemaVersion)
+		}
+		fmt.Fprintf(buf, "      <xs:element name=%q type=%q%s", f.name, b.typeName(ft), attrs)
+		note, ok := f.Tag.Lookup("deprecated")
+		if !ok {
+			buf.WriteString("/>\n")
+			continue
+		}
+		buf.WriteString(">\n        <xs:annotation>\n          <xs:documentation>Deprecated: ")
+		xml.EscapeText(buf, []byte(note))
+		buf.WriteString("</xs:documentation>\n        </xs:annotation>\n      </xs:element>\n")
+	}
+	buf.WriteString("    </xs:sequence>\n  </xs:complexType>\n")
+}
+
+// schemaCSVColumns is the CSV layout on this machine. --flat columns are
+// taken from a sample, like a --headless run does.
+func schemaCSVColumns() []csvColumn {
+	if !flatCSV {
+		return headlessCSVColumns(getSOCInfo().CoreCount)
+	}
+	setupHeadlessFlat("csv")
+	first, err := sampleHeadlessOnce()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return newFlatCSVLayout(first, flatProcesses).allColumns()
+}
+
+// headlessCSVSchema lists CSV columns as CSV: position, header, cell type,
+// source path and a deprecation note.
+func headlessCSVSchema(columns []csvColumn) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"Column", "Name", "Type", "Source", "Deprecated"})
+	for i, c := range columns {
+		kind, field, err := c.describe()
+		if err != nil {
+			return nil, err
+		}
+		w.Write([]string{strconv.Itoa(i + 1), c.name, kind, c.source, field.Tag.Get("deprecated")})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
@@ -0,0 +1,150 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// checkJSONSchema checks a decoded JSON value against the parts of JSON
+// Schema that headlessJSONSchema uses.
+func checkJSONSchema(t *testing.T, path string, schema, defs map[string]any, v any) {
+	t.Helper()
+	if ref, ok := schema["$ref"].(string); ok {
+		schema = defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+	}
+	if anyOf, ok := schema["anyOf"].([]any); ok {
+		if v != nil {
+			checkJSONSchema(t, path, anyOf[0].(map[string]any), defs, v)
+		}
+		return
+	}
+	switch x := v.(type) {
+	case map[string]any:
+		props := schema["properties"].(map[string]any)
+		for _, name := range schema["required"].([]any) {
+			if _, ok := x[name.(string)]; !ok {
+				t.Errorf("%s: required %s missing", path, name)
+			}
+		}
+		for name, child := range x {
+			prop, ok := props[name].(map[string]any)
+			if !ok {
+				t.Errorf("%s: %s is not in the schema", path, name)
+				continue
+			}
+			checkJSONSchema(t, path+"."+name, prop, defs, child)
+		}
+	case []any:
+		for _, elem := range x {
+			checkJSONSchema(t, path+"[]", schema["items"].(map[string]any), defs, elem)
+		}
+	}
+}
+
+func TestHeadlessJSONSchema(t *testing.T) {
+	data, err := headlessJSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	props := schema["properties"].(map[string]any)
+	if v := props["schema_version"].(map[string]any)["const"]; v != float64(headlessSchemaVersion) {
+		t.Errorf("schema_version const = %v", v)
+	}
+	if v := props["scpu_usage"].(map[string]any)["type"]; v != "array" {
+		t.Errorf("omitempty lists can't be null, got type %v", v)
+	}
+	if v := props["pcpu_usage"].(map[string]any)["type"]; !reflect.DeepEqual(v, []any{"array", "null"}) {
+		t.Errorf("nil lists are written as null, got type %v", v)
+	}
+
+	output := fieldsTestOutput()
+	output.SchemaVersion = headlessSchemaVersion
+	output.ThunderboltInfo = &ThunderboltOutput{Buses: []ThunderboltBusOutput{{Name: "Bus 0", NetworkStats: &ThunderboltNetStats{}}}}
+	output.Fans = []HeadlessFan{{ID: 0, Name: "Left", RPM: 1200}}
+	sample, _ := json.Marshal(output)
+	var decoded map[string]any
+	if err := json.Unmarshal(sample, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	checkJSONSchema(t, "", schema, schema["$defs"].(map[string]any), decoded)
+	var empty map[string]any
+	sample, _ = json.Marshal(HeadlessOutput{})
+	json.Unmarshal(sample, &empty)
+	checkJSONSchema(t, "", schema, schema["$defs"].(map[string]any), empty)
+}
+
+func TestHeadlessXSD(t *testing.T) {
+	data := headlessXSD()
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("not well-formed: %v", err)
+		}
+	}
+	for _, want := range []string{
+		`<xs:element name="HeadlessOutput" type="HeadlessOutput" minOccurs="0" maxOccurs="unbounded"/>`,
+		`<xs:element name="SchemaVersion" type="xs:long" fixed="1"/>`,
+		`<xs:element name="ThunderboltInfo" type="ThunderboltOutput" minOccurs="0"/>`,
+		`<xs:element name="DisplayFPS" type="xs:unsignedInt" minOccurs="0"/>`,
+		`<xs:element name="CoreUsages" type="xs:double" minOccurs="0" maxOccurs="unbounded"/>`,
+		// json:"-" doesn't keep fields out of XML.
+		`<xs:element name="Fans" type="FanInfo" minOccurs="0" maxOccurs="unbounded"/>`,
+		`<xs:complexType name="FanInfo">`,
+	} {
+		if !bytes.Contains(data, []byte(want)) {
+			t.Errorf("XSD lacks %s", want)
+		}
+	}
+}
+
+func TestHeadlessCSVSchema(t *testing.T) {
+	data, err := headlessCSVSchema(headlessCSVColumns(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1+len(headlessCSVLeadColumns)+2+len(headlessCSVTrailColumns) {
+		t.Fatalf("got %d lines", len(lines))
+	}
+	for _, want := range []string{
+		"Column,Name,Type,Source,Deprecated",
+		"9,ECPU_Freq_MHz,number,ecpu_usage[0],",
+		"33,Thermal_State,string,thermal_state,",
+		"37,RDMA_Available,boolean,rdma_status.available,",
+		"39,RDMA_Device_Count,integer,rdma_status.devices,",
+		"41,Core_1,number,core_usages[1],",
+		"43,Processes_JSON,json,processes,",
+	} {
+		if !strings.Contains(string(data), want+"\n") {
+			t.Errorf("CSV columns lack %s", want)
+		}
+	}
+}
+
+func TestSchemaDeprecatedFields(t *testing.T) {
+	type sample struct {
+		Old int `json:"old" xml:"Old" deprecated:"use new, removed in schema_version 3"`
+		New int `json:"new" xml:"New"`
+	}
+	typ := reflect.TypeFor[sample]()
+	prop := (&jsonSchemaBuilder{defs: make(map[string]any)}).object(typ)["properties"].(map[string]any)["old"].(map[string]any)
+	if prop["deprecated"] != true || prop["description"] != "Deprecated: use new, removed in schema_version 3" {
+		t.Errorf("JSON Schema property = %v", prop)
+	}
+	var buf bytes.Buffer
+	(&xsdBuilder{seen: make(map[reflect.Type]bool)}).writeType(&buf, typ)
+	if !strings.Contains(buf.String(), "<xs:documentation>Deprecated: use new, removed in schema_version 3</xs:documentation>") {
+		t.Errorf("XSD:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,403 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// sensors_linux.go - sysfs readers for topology, RAPL, cpufreq, DRM and hwmon
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// linuxCoreTopology splits logical CPUs into efficiency and performance
+// tiers. Hybrid Intel parts expose cpu_atom/cpu_core PMUs; big.LITTLE ARM
+// parts expose cpu_capacity. Everything else is treated as all P-cores.
+type linuxCoreTopology struct {
+	eCPUs []int
+	pCPUs []int
+}
+
+func readLinuxCoreTopology() linuxCoreTopology {
+	cpuDir := filepath.Join(sysRoot, "devices", "system", "cpu")
+	online := parseCPUList(readSysfsString(filepath.Join(cpuDir, "online")))
+	if len(online) == 0 {
+		for i := 0; i < runtime.NumCPU(); i++ {
+			online = append(online, i)
+		}
+	}
+
+	atoms := parseCPUList(readSysfsString(filepath.Join(sysRoot, "devices", "cpu_atom", "cpus")))
+	if len(atoms) > 0 {
+		return splitTopology(online, func(cpu int) bool { return containsInt(atoms, cpu) })
+	}
+
+	capacity := make(map[int]int)
+	maxCapacity := 0
+	for _, cpu := range online {
+		c, err := strconv.Atoi(readSysfsString(filepath.Join(cpuDir, fmt.Sprintf("cpu%d", cpu), "cpu_capacity")))
+		if err != nil {
+			continue
+		}
+		capacity[cpu] = c
+		maxCapacity = max(maxCapacity, c)
+	}
+	return splitTopology(online, func(cpu int) bool {
+		c, ok := capacity[cpu]
+		return ok && c < maxCapacity
+	})
+}
+
+func splitTopology(cpus []int, isEfficiency func(int) bool) linuxCoreTopology {
+	var t linuxCoreTopology
+	for _, cpu := range cpus {
+		if isEfficiency(cpu) {
+			t.eCPUs = append(t.eCPUs, cpu)
+		} else {
+			t.pCPUs = append(t.pCPUs, cpu)
+		}
+	}
+	return t
+}
+
+// parseCPUList expands the kernel cpulist format ("0-3,8,10-11").
+func parseCPUList(s string) []int {
+	var cpus []int
+	for _, part := range strings.Split(strings.TrimSpace(s), ",") {
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(lo)
+		if err != nil {
+			continue
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(hi); err != nil {
+				continue
+			}
+		}
+		for cpu := start; cpu <= end; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus
+}
+
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func readSysfsString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func readSysfsInt(path string) (int64, bool) {
+	v, err := strconv.ParseInt(readSysfsString(path), 10, 64)
+	return v, err == nil
+}
+
+// cpuBusyPercents converts two /proc/stat snapshots into per-CPU busy %.
+func cpuBusyPercents(before, after []CPUUsage) []float64 {
+	busy := make([]float64, len(after))
+	for i := range after {
+		if i >= len(before) {
+			break
+		}
+		active := (after[i].User - before[i].User) + (after[i].System - before[i].System) + (after[i].Nice - before[i].Nice)
+		total := active + (after[i].Idle - before[i].Idle)
+		if total > 0 {
+			busy[i] = active / total * 100
+		}
+	}
+	return busy
+}
+
+func averageAt(values []float64, idx []int) float64 {
+	sum, n := 0.0, 0
+	for _, i := range idx {
+		if i < len(values) {
+			sum += values[i]
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+func averageCPUFreqMHz(cpus []int) int32 {
+	var sum, n int64
+	for _, cpu := range cpus {
+		khz, ok := readSysfsInt(filepath.Join(sysRoot, "devices", "system", "cpu", fmt.Sprintf("cpu%d", cpu), "cpufreq", "scaling_cur_freq"))
+		if ok {
+			sum += khz
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return int32(sum / n / 1000)
+}
+
+// raplZone is one powercap energy counter such as package-0, core or dram.
+type raplZone struct {
+	name     string
+	path     string
+	maxRange uint64
+}
+
+func discoverRAPLZones(powercapDir string) []raplZone {
+	dirs, _ := filepath.Glob(filepath.Join(powercapDir, "intel-rapl:*"))
+	var zones []raplZone
+	for _, dir := range dirs {
+		name := readSysfsString(filepath.Join(dir, "name"))
+		if name == "" {
+			continue
+		}
+		maxRange, _ := readSysfsInt(filepath.Join(dir, "max_energy_range_uj"))
+		zones = append(zones, raplZone{name: name, path: filepath.Join(dir, "energy_uj"), maxRange: uint64(maxRange)})
+	}
+	return zones
+}
+
+func readRAPLEnergy(zones []raplZone) []int64 {
+	energy := make([]int64, len(zones))
+	for i, z := range zones {
+		v, ok := readSysfsInt(z.path)
+		if !ok {
+			v = -1
+		}
+		energy[i] = v
+	}
+	return energy
+}
+
+// applyRAPLPower maps RAPL domains onto SocMetrics: core → CPU, uncore
+// (integrated graphics) → GPU, dram → DRAM and psys → whole system.
+func applyRAPLPower(m *SocMetrics, zones []raplZone, before, after []int64, seconds float64) {
+	if seconds <= 0 {
+		return
+	}
+	var pkg float64
+	for i, z := range zones {
+		if before[i] < 0 || after[i] < 0 {
+			continue
+		}
+		delta := uint64(after[i] - before[i])
+		if after[i] < before[i] {
+			delta = z.maxRange - uint64(before[i]) + uint64(after[i])
+		}
+		watts := float64(delta) / 1e6 / seconds
+		switch {
+		case strings.HasPrefix(z.name, "package"):
+			pkg += watts
+		case z.name == "core":
+			m.CPUPower += watts
+		case z.name == "uncore":
+			m.GPUPower += watts
+		case z.name == "dram":
+			m.DRAMPower += watts
+		case z.name == "psys":
+			m.SystemPower += watts
+		}
+	}
+	if m.CPUPower == 0 && pkg > m.GPUPower {
+		m.CPUPower = pkg - m.GPUPower
+	}
+	m.TotalPower = m.CPUPower + m.GPUPower + m.DRAMPower
+	if pkg > m.CPUPower+m.GPUPower {
+		m.TotalPower = pkg + m.DRAMPower
+	}
+}
+
+// readDRMGPU reports busy % and current clock for the first DRM card that
+// exposes them (amdgpu gpu_busy_percent/pp_dpm_sclk, i915 gt_act_freq_mhz).
+func readDRMGPU(drmDir string) (float64, int32) {
+	cards, _ := filepath.Glob(filepath.Join(drmDir, "card[0-9]*"))
+	sort.Strings(cards)
+	for _, card := range cards {
+		if strings.Contains(filepath.Base(card), "-") {
+			continue
+		}
+		busy, hasBusy := readSysfsInt(filepath.Join(card, "device", "gpu_busy_percent"))
+		freq, hasFreq := readSysfsInt(filepath.Join(card, "gt_act_freq_mhz"))
+		if !hasFreq {
+			freq, hasFreq = parseDPMClock(readSysfsString(filepath.Join(card, "device", "pp_dpm_sclk")))
+		}
+		if hasBusy || hasFreq {
+			return float64(busy), int32(freq)
+		}
+	}
+	return 0, 0
+}
+
+// parseDPMClock returns the active level from an amdgpu pp_dpm_* table,
+// e.g. "1: 1800Mhz *".
+func parseDPMClock(table string) (int64, bool) {
+	for _, line := range strings.Split(table, "\n") {
+		if !strings.HasSuffix(strings.TrimSpace(line), "*") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		mhz, err := strconv.ParseInt(strings.TrimSuffix(strings.ToLower(fields[1]), "mhz"), 10, 64)
+		return mhz, err == nil
+	}
+	return 0, false
+}
+
+// hwmonReading is one pass over /sys/class/hwmon.
+type hwmonReading struct {
+	temps   []TempSensor
+	fans    []FanInfo
+	cpuTemp float32
+	gpuTemp float32
+	cpuMax  float64
+	cpuCrit float64
+}
+
+// hwmonSensorGroup maps a hwmon driver to the SMC-style key prefix that
+// sensorGroupName understands, so Linux sensors land in the same groups.
+func hwmonSensorGroup(driver, label string) string {
+	switch driver {
+	case "coretemp", "k10temp", "zenpower", "cpu_thermal", "cpu-thermal":
+		if strings.HasPrefix(label, "Package") || label == "Tctl" || label == "Tdie" {
+			return "TP"
+		}
+		return "Tc"
+	case "amdgpu", "radeon", "nouveau", "i915", "xe":
+		return "Tg"
+	case "nvme":
+		return "Nv"
+	case "jc42", "spd5118", "ddr":
+		return "Tm"
+	case "acpitz":
+		return "TB"
+	}
+	if strings.HasPrefix(driver, "iwlwifi") {
+		return "Tw"
+	}
+	return "X"
+}
+
+func readHwmon(hwmonDir string) hwmonReading {
+	var r hwmonReading
+	var hottestCore float32
+	dirs, _ := filepath.Glob(filepath.Join(hwmonDir, "hwmon*"))
+	sort.Strings(dirs)
+	counters := make(map[string]int)
+	for _, dir := range dirs {
+		driver := readSysfsString(filepath.Join(dir, "name"))
+		inputs, _ := filepath.Glob(filepath.Join(dir, "temp*_input"))
+		sort.Strings(inputs)
+		for _, input := range inputs {
+			milli, ok := readSysfsInt(input)
+			if !ok {
+				continue
+			}
+			prefix := strings.TrimSuffix(input, "_input")
+			label := readSysfsString(prefix + "_label")
+			if label == "" {
+				label = strings.TrimPrefix(filepath.Base(prefix), "temp")
+				label = driver + " " + label
+			}
+			celsius := float64(milli) / 1000
+			group := hwmonSensorGroup(driver, label)
+			key := fmt.Sprintf("%s%02d", group, counters[group])
+			counters[group]++
+			r.temps = append(r.temps, TempSensor{Key: key, Name: label, Value: celsius})
+
+			switch group {
+			case "TP":
+				r.cpuTemp = float32(celsius)
+				if v, ok := readSysfsInt(prefix + "_max"); ok {
+					r.cpuMax = float64(v) / 1000
+				}
+				if v, ok := readSysfsInt(prefix + "_crit"); ok {
+					r.cpuCrit = float64(v) / 1000
+				}
+			case "Tc":
+				if float32(celsius) > hottestCore {
+					hottestCore = float32(celsius)
+				}
+			case "Tg":
+				if r.gpuTemp == 0 {
+					r.gpuTemp = float32(celsius)
+				}
+			}
+		}
+		r.fans = append(r.fans, readHwmonFans(dir, len(r.fans))...)
+	}
+	if r.cpuTemp == 0 {
+		r.cpuTemp = hottestCore
+	}
+	return r
+}
+
+func readHwmonFans(dir string, firstID int) []FanInfo {
+	inputs, _ := filepath.Glob(filepath.Join(dir, "fan*_input"))
+	sort.Strings(inputs)
+	var fans []FanInfo
+	for _, input := range inputs {
+		rpm, ok := readSysfsInt(input)
+		if !ok {
+			continue
+		}
+		prefix := strings.TrimSuffix(input, "_input")
+		n := strings.TrimPrefix(filepath.Base(prefix), "fan")
+		name := readSysfsString(prefix + "_label")
+		if name == "" {
+			name = "Fan " + n
+		}
+		minRPM, _ := readSysfsInt(prefix + "_min")
+		maxRPM, _ := readSysfsInt(prefix + "_max")
+		target, _ := readSysfsInt(prefix + "_target")
+		mode := 0
+		if enable, ok := readSysfsInt(filepath.Join(dir, "pwm"+n+"_enable")); ok && enable == 1 {
+			mode = 1
+		}
+		fans = append(fans, FanInfo{
+			ID:        firstID + len(fans),
+			Name:      name,
+			ActualRPM: int(rpm),
+			MinRPM:    int(minRPM),
+			MaxRPM:    int(maxRPM),
+			TargetRPM: int(target),
+			Mode:      mode,
+		})
+	}
+	return fans
+}
+
+// thermalLevel approximates macOS thermal pressure from the CPU package
+// temperature and the driver-reported max/crit trip points.
+func (r hwmonReading) thermalLevel() thermalStateLevel {
+	t := float64(r.cpuTemp)
+	switch {
+	case r.cpuCrit > 0 && t >= r.cpuCrit:
+		return thermalStateCritical
+	case r.cpuMax > 0 && t >= r.cpuMax:
+		return thermalStateSerious
+	case r.cpuMax > 0 && t >= r.cpuMax-10:
+		return thermalStateFair
+	default:
+		return thermalStateNominal
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// serve.go - `mactop serve`: headless collectors exposed as JSON over HTTP
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	infoPath     = "/api/v1/info"
+	snapshotPath = "/api/v1/snapshot"
+	streamPath   = "/api/v1/stream"
+	// streamKeepAlive is how often an idle stream gets a comment line so
+	// proxies and load balancers don't close it between slow samples.
+	streamKeepAlive = 15 * time.Second
+)
+
+// snapshotHub holds the most recent sample and fans every new one out to the
+// connected stream clients.
+type snapshotHub struct {
+	info   []byte
+	mu     sync.Mutex
+	latest hubEvent
+	subs   map[chan hubEvent]struct{}
+}
+
+// hubEvent is one sample in both encodings served: summary is the
+// HeadlessOutput document, detail the RecordedSample a remote TUI draws from.
+type hubEvent struct {
+	seq     uint64
+	summary []byte
+	detail  []byte
+}
+
+// payload picks the encoding requested with ?detail=1.
+func (ev hubEvent) payload(r *http.Request) []byte {
+	if r.URL.Query().Get("detail") == "1" {
+		return ev.detail
+	}
+	return ev.summary
+}
+
+// newSnapshotHub returns a hub that serves info, the JSON RecordingHeader
+// describing this machine, at /api/v1/info.
+func newSnapshotHub(info []byte) *snapshotHub {
+	return &snapshotHub{info: info, subs: make(map[chan hubEvent]struct{})}
+}
+
+// publish stores data as the latest snapshot and queues it for every
+// subscriber. A client that has fallen a full buffer behind misses samples
+// rather than stalling collection.
+func (h *snapshotHub) publish(summary, detail []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ev := hubEvent{seq: h.latest.seq + 1, summary: summary, detail: detail}
+	h.latest = ev
+	for ch := range h.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// subscribe returns a channel that receives the current snapshot, if any,
+// followed by every published one, and a func that ends the subscription.
+func (h *snapshotHub) subscribe() (<-chan hubEvent, func()) {
+	ch := make(chan hubEvent, 8)
+	h.mu.Lock()
+	if h.latest.seq > 0 {
+		ch <- h.latest
+	}
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.subs, ch)
+		h.mu.Unlock()
+	}
+}
+
+func (h *snapshotHub) snapshot() hubEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.latest
+}
+
+func (h *snapshotHub) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+infoPath, h.handleInfo)
+	mux.HandleFunc("GET "+snapshotPath, h.handleSnapshot)
+	mux.HandleFunc("GET "+streamPath, h.handleStream)
+	return mux
+}
+
+func (h *snapshotHub) handleInfo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(h.info)
+}
+
+func (h *snapshotHub) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	ev := h.snapshot()
+	if ev.seq == 0 {
+		http.Error(w, "no sample collected yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(ev.payload(r))
+}
+
+// handleStream serves each sample as a Server-Sent Event whose data is the
+// same JSON document /api/v1/snapshot returns for the same query.
+func (h *snapshotHub) handleStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	events, cancel := h.subscribe()
+	defer cancel()
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case ev := <-events:
+			if _, err := fmt.Fprintf(w, "id: %d\ndata: %s\n\n", ev.seq, ev.payload(r)); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// runServe runs the headless collectors on the update interval and serves
+// the latest snapshot and a live stream on serveListen until interrupted.
+func runServe() {
+	ln, err := net.Listen("tcp", serveListen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to listen on %s: %v\n", serveListen, err)
+		os.Exit(1)
+	}
+
+	if err := initSocMetrics(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to initialize metrics: %v\n", err)
+		os.Exit(1)
+	}
+	defer cleanupSocMetrics()
+
+	StartDisplayFPSCounter()
+	defer StopDisplayFPSCounter()
+
+	startHeadlessPrometheus()
+
+	info, err := json.Marshal(newRecordingHeader())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: encode system info: %v\n", err)
+		os.Exit(1)
+	}
+	hub := newSnapshotHub(info)
+	srv := &http.Server{Handler: hub.handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "Error: serve: %v\n", err)
+			os.Exit(1)
+		}
+	}()
+	fmt.Fprintf(os.Stderr, "mactop serving %s and %s on %s\n", snapshotPath, streamPath, ln.Addr())
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	tbInfo := performHeadlessWarmup()
+	sysInfo := getSOCInfo()
+	publishNext := func() {
+		if err := publishHeadlessSample(hub, tbInfo, sysInfo); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: encode snapshot: %v\n", err)
+		}
+	}
+	publishNext()
+
+	ticker := time.NewTicker(time.Duration(updateInterval) * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sigChan:
+			// Streams never finish on their own, so there is nothing to drain.
+			srv.Close()
+			return
+		case <-ticker.C:
+			publishNext()
+		}
+	}
+}
+
+// publishHeadlessSample takes and publishes one sample, then hands both
+// encodings to the hub.
+func publishHeadlessSample(hub *snapshotHub, tbInfo *ThunderboltOutput, sysInfo SystemInfo) error {
+	sample := takeHeadlessSample()
+	output := buildHeadlessOutput(sample, tbInfo, sysInfo)
+	publishSample(output, sample, false)
+
+	summary, err := json.Marshal(output)
+	if err != nil {
+		return err
+	}
+	detail, err := json.Marshal(newRecordedSample(output, sample))
+	if err != nil {
+		return err
+	}
+	hub.publish(summary, detail)
+	return nil
+}
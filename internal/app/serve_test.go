@@ -0,0 +1,151 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitSubcommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantCmd  string
+		wantArgs []string
+	}{
+		{"No subcommand", []string{"--headless", "--count", "1"}, "", []string{"--headless", "--count", "1"}},
+		{"Leading", []string{"serve", "--listen", ":9000"}, "serve", []string{"--listen", ":9000"}},
+		{"After flags", []string{"-i", "500", "serve"}, "serve", []string{"-i", "500"}},
+		{"After terminator", []string{"--", "serve"}, "", []string{"--", "serve"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, args := splitSubcommand(tt.args)
+			if cmd != tt.wantCmd || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("splitSubcommand(%q) = %q, %q, want %q, %q", tt.args, cmd, args, tt.wantCmd, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestSnapshotHandler(t *testing.T) {
+	hub := newSnapshotHub(nil)
+	srv := httptest.NewServer(hub.handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + snapshotPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("before first sample status = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+
+	hub.publish([]byte(`{"cpu_usage":12.5}`), []byte(`{"cpu_usage":12.5,"t":1}`))
+	resp, err = http.Get(srv.URL + snapshotPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != `{"cpu_usage":12.5}` {
+		t.Errorf("snapshot = %d %s, want 200 with the published sample", resp.StatusCode, body)
+	}
+
+	resp, err = http.Get(srv.URL + snapshotPath + "?detail=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != `{"cpu_usage":12.5,"t":1}` {
+		t.Errorf("detailed snapshot = %s, want the detailed encoding", body)
+	}
+}
+
+func TestStreamHandler(t *testing.T) {
+	hub := newSnapshotHub(nil)
+	hub.publish([]byte(`{"n":1}`), nil)
+	srv := httptest.NewServer(hub.handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + streamPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	r := bufio.NewReader(resp.Body)
+	readData := func() string {
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatalf("reading stream: %v", err)
+			}
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				return strings.TrimSpace(data)
+			}
+		}
+	}
+
+	if got := readData(); got != `{"n":1}` {
+		t.Errorf("first event = %s, want the current snapshot", got)
+	}
+	hub.publish([]byte(`{"n":2}`), nil)
+	if got := readData(); got != `{"n":2}` {
+		t.Errorf("second event = %s, want the newly published sample", got)
+	}
+}
+
+func TestRemoteBackendFollowsStream(t *testing.T) {
+	info, _ := json.Marshal(RecordingHeader{Format: recordingFormat, Version: recordingVersion, SystemInfo: SystemInfo{Name: "Apple M4 Pro", CoreCount: 14}})
+	hub := newSnapshotHub(info)
+	publish := func(cpuPower float64) {
+		s := testSample(0, cpuPower)
+		detail, _ := json.Marshal(s)
+		hub.publish([]byte(`{}`), detail)
+	}
+	publish(1)
+	srv := httptest.NewServer(hub.handler())
+	defer srv.Close()
+
+	prev := activeBackend
+	t.Cleanup(func() { activeBackend = prev })
+	if err := startRemote(srv.URL); err != nil {
+		t.Fatalf("startRemote() error = %v", err)
+	}
+	b := activeBackend.(*remoteBackend)
+	defer b.Close()
+
+	if got := b.SystemInfo().Name; got != "Apple M4 Pro" {
+		t.Errorf("SystemInfo().Name = %q, want %q", got, "Apple M4 Pro")
+	}
+	if got := b.SampleSoc(0).CPUPower; got != 1 {
+		t.Errorf("first SampleSoc().CPUPower = %v, want 1", got)
+	}
+
+	// Drop the stream; the backend reconnects and picks up new samples.
+	srv.CloseClientConnections()
+	publish(2)
+	got := make(chan float64, 1)
+	go func() { got <- b.SampleSoc(0).CPUPower }()
+	select {
+	case v := <-got:
+		if v != 2 {
+			t.Errorf("SampleSoc().CPUPower after reconnect = %v, want 2", v)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no sample received after reconnect")
+	}
+}
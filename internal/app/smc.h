@@ -1,3 +1,5 @@
+//go:build darwin
+
 // smc.h
 #ifndef SMC_H
 #define SMC_H
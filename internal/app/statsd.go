@@ -0,0 +1,188 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// statsd.go - --statsd: fire-and-forget UDP gauges for a local StatsD or DogStatsD agent
+package app
+
+import (
+	"bytes"
+	"math"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// statsdMaxPacket keeps datagrams under a typical 1500-byte MTU.
+const statsdMaxPacket = 1432
+
+var statsdUnsafe = regexp.MustCompile(`[^A-Za-z0-9_.\-]+`)
+
+// statsdClient batches gauges into as few datagrams as fit under the MTU.
+// With tags it speaks DogStatsD and moves per-core, per-fan and similar
+// dimensions into tags; without, they become part of the metric name.
+type statsdClient struct {
+	conn   net.Conn
+	prefix string
+	tags   []string
+	buf    bytes.Buffer
+}
+
+var activeStatsd *statsdClient
+
+// setupStatsd opens the UDP socket when --statsd is set.
+func setupStatsd() {
+	if statsdAddr == "" {
+		return
+	}
+	c, err := newStatsdClient(statsdAddr, statsdPrefix, statsdTags)
+	if err != nil {
+		stderrLogger.Fatalf("invalid --statsd: %v", err)
+	}
+	activeStatsd = c
+}
+
+func newStatsdClient(addr, prefix, tags string) (*statsdClient, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &statsdClient{conn: conn, prefix: strings.Trim(prefix, ".")}
+	for t := range strings.SplitSeq(tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			c.tags = append(c.tags, t)
+		}
+	}
+	return c, nil
+}
+
+// gauge queues one gauge. dims are dimension key/value pairs such as
+// "core", "3".
+func (c *statsdClient) gauge(name string, v float64, dims ...string) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return
+	}
+	var line strings.Builder
+	if c.prefix != "" {
+		line.WriteString(c.prefix)
+		line.WriteByte('.')
+	}
+	dogstatsd := len(c.tags) > 0
+	line.WriteString(name)
+	if !dogstatsd {
+		for i := 1; i < len(dims); i += 2 {
+			line.WriteByte('.')
+			line.WriteString(statsdUnsafe.ReplaceAllString(dims[i], "_"))
+		}
+	}
+	line.WriteByte(':')
+	line.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	line.WriteString("|g")
+	if dogstatsd {
+		tags := append([]string(nil), c.tags...)
+		for i := 0; i+1 < len(dims); i += 2 {
+			tags = append(tags, dims[i]+":"+statsdUnsafe.ReplaceAllString(dims[i+1], "_"))
+		}
+		line.WriteString("|#")
+		line.WriteString(strings.Join(tags, ","))
+	}
+
+	if c.buf.Len() > 0 && c.buf.Len()+1+line.Len() > statsdMaxPacket {
+		c.flush()
+	}
+	if c.buf.Len() > 0 {
+		c.buf.WriteByte('\n')
+	}
+	c.buf.WriteString(line.String())
+}
+
+func (c *statsdClient) flush() {
+	if c.buf.Len() == 0 {
+		return
+	}
+	// Fire and forget: nobody listening is not an error worth reporting.
+	_, _ = c.conn.Write(c.buf.Bytes())
+	c.buf.Reset()
+}
+
+// exportStatsd sends a sample to the active client, if any. The gauges
+// mirror CPUMetrics, GPUMetrics, MemoryMetrics and NetDiskMetrics.
+func exportStatsd(output HeadlessOutput, s headlessSample) {
+	c := activeStatsd
+	if c == nil {
+		return
+	}
+	statsdCPUGauges(c, output, s)
+	statsdGPUGauges(c, output)
+	statsdMemoryNetDiskGauges(c, output)
+	c.flush()
+}
+
+func statsdCPUGauges(c *statsdClient, o HeadlessOutput, s headlessSample) {
+	m := o.SocMetrics
+	c.gauge("cpu.usage", o.CPUUsage)
+	for _, cl := range []struct {
+		name  string
+		usage []float64
+	}{{"e", o.ECPUUsage}, {"p", o.PCPUUsage}, {"s", o.SCPUUsage}} {
+		if len(cl.usage) == 2 {
+			c.gauge("cpu.cluster.freq_mhz", cl.usage[0], "cluster", cl.name)
+			c.gauge("cpu.cluster.active", cl.usage[1], "cluster", cl.name)
+		}
+	}
+	for i, u := range o.CoreUsages {
+		c.gauge("cpu.core.usage", u, "core", strconv.Itoa(i))
+	}
+	// TotalPower is the whole machine's draw and SystemPower what is left
+	// after the SoC package.
+	for _, p := range []struct {
+		component string
+		watts     float64
+	}{
+		{"cpu", m.CPUPower}, {"gpu", m.GPUPower}, {"ane", m.ANEPower}, {"dram", m.DRAMPower},
+		{"gpu_sram", m.GPUSRAMPower}, {"system", m.SystemPower},
+		{"package", m.TotalPower - m.SystemPower}, {"total", m.TotalPower},
+	} {
+		c.gauge("power.watts", p.watts, "component", p.component)
+	}
+	c.gauge("cpu.temp", float64(m.CPUTemp))
+	throttled := 0.0
+	if thermalStateThrottled(s.thermal) {
+		throttled = 1
+	}
+	c.gauge("cpu.throttled", throttled)
+	c.gauge("dram.bandwidth_gbs", m.DRAMReadBW, "direction", "read")
+	c.gauge("dram.bandwidth_gbs", m.DRAMWriteBW, "direction", "write")
+	c.gauge("dram.bandwidth_gbs", m.DRAMBWCombined, "direction", "combined")
+	for _, f := range s.soc.Fans {
+		c.gauge("fan.rpm", float64(f.ActualRPM), "fan", f.Name)
+	}
+	for _, t := range s.soc.TempSensors {
+		c.gauge("temp_sensor.celsius", t.Value, "sensor", t.Key)
+	}
+}
+
+func statsdGPUGauges(c *statsdClient, o HeadlessOutput) {
+	m := o.SocMetrics
+	c.gauge("gpu.freq_mhz", float64(o.GPUMetrics.FreqMHz))
+	c.gauge("gpu.active", o.GPUMetrics.ActivePercent)
+	c.gauge("gpu.power", m.GPUPower+m.GPUSRAMPower)
+	c.gauge("gpu.temp", float64(m.GPUTemp))
+}
+
+func statsdMemoryNetDiskGauges(c *statsdClient, o HeadlessOutput) {
+	mem := o.Memory
+	c.gauge("memory.total", float64(mem.Total))
+	c.gauge("memory.used", float64(mem.Used))
+	c.gauge("memory.available", float64(mem.Available))
+	c.gauge("memory.swap_total", float64(mem.SwapTotal))
+	c.gauge("memory.swap_used", float64(mem.SwapUsed))
+
+	nd := o.NetDisk
+	c.gauge("net.bytes_per_sec", nd.InBytesPerSec, "direction", "in")
+	c.gauge("net.bytes_per_sec", nd.OutBytesPerSec, "direction", "out")
+	c.gauge("net.packets_per_sec", nd.InPacketsPerSec, "direction", "in")
+	c.gauge("net.packets_per_sec", nd.OutPacketsPerSec, "direction", "out")
+	c.gauge("disk.kbytes_per_sec", nd.ReadKBytesPerSec, "direction", "read")
+	c.gauge("disk.kbytes_per_sec", nd.WriteKBytesPerSec, "direction", "write")
+	c.gauge("disk.ops_per_sec", nd.ReadOpsPerSec, "direction", "read")
+	c.gauge("disk.ops_per_sec", nd.WriteOpsPerSec, "direction", "write")
+}
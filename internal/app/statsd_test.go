@@ -0,0 +1,83 @@
+package app
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatsdGauges(t *testing.T) {
+	tests := []struct {
+		name  string
+		tags  string
+		wants []string
+	}{
+		{"Plain", "", []string{
+			"mactop.cpu.usage:12.5|g",
+			"mactop.cpu.core.usage.1:20|g",
+			"mactop.power.watts.package:23.5|g",
+			"mactop.power.watts.total:30|g",
+			"mactop.fan.rpm.Left_Fan:2400|g",
+		}},
+		{"DogStatsD", "env:prod", []string{
+			"mactop.cpu.usage:12.5|g|#env:prod",
+			"mactop.cpu.core.usage:20|g|#env:prod,core:1",
+			"mactop.power.watts:23.5|g|#env:prod,component:package",
+			"mactop.power.watts:30|g|#env:prod,component:total",
+			"mactop.fan.rpm:2400|g|#env:prod,fan:Left_Fan",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pc.Close()
+
+			c, err := newStatsdClient(pc.LocalAddr().String(), "mactop.", tt.tags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			prev := activeStatsd
+			activeStatsd = c
+			t.Cleanup(func() { activeStatsd = prev })
+
+			output := HeadlessOutput{CPUUsage: 12.5, CoreUsages: make([]float64, 64)}
+			output.CoreUsages[1] = 20
+			output.SocMetrics.TotalPower, output.SocMetrics.SystemPower = 30, 6.5
+			exportStatsd(output, headlessSample{soc: SocMetrics{Fans: []FanInfo{{Name: "Left Fan", ActualRPM: 2400}}}})
+
+			var got []string
+			buf := make([]byte, 64*1024)
+			for {
+				pc.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+				n, _, err := pc.ReadFrom(buf)
+				if err != nil {
+					break
+				}
+				if n > statsdMaxPacket {
+					t.Errorf("datagram of %d bytes, want at most %d", n, statsdMaxPacket)
+				}
+				got = append(got, strings.Split(string(buf[:n]), "\n")...)
+			}
+			if len(got) == 0 {
+				t.Fatal("no datagrams received")
+			}
+			for _, want := range tt.wants {
+				found := false
+				for _, line := range got {
+					if line == want {
+						found = true
+						break
+					}
+				}
+				if !found {
+					t.Errorf("missing %q", want)
+				}
+			}
+		})
+	}
+}
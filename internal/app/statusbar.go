@@ -0,0 +1,260 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// statusbar.go - i3bar, waybar, tmux and sketchybar output for tiling window managers and terminal multiplexers
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strings"
+)
+
+// statusLevel is how close a block is to its limit. The thresholds and their
+// colors match the TUI: usage bars turn to the warning color at 40% and the
+// critical one at 60%, temperatures at 70°C and 90°C.
+type statusLevel int
+
+const (
+	statusNormal statusLevel = iota
+	statusWarning
+	statusCritical
+)
+
+var statusLevelNames = []string{"normal", "warning", "critical"}
+
+// statusBlock is one item on the bar.
+type statusBlock struct {
+	name  string
+	text  string
+	level statusLevel
+}
+
+// statusPalette holds the colors the bar formats use, resolved once from the
+// theme at startup.
+type statusPalette struct {
+	blocks   map[string]string // Per-block theme color
+	warning  string
+	critical string
+}
+
+var activeStatusPalette statusPalette
+
+// statusNamedHex gives the named theme colors without a hex value in
+// themeHexMap a hex equivalent, since i3bar only accepts #RRGGBB.
+var statusNamedHex = map[string]string{
+	"green":   "#00FF00",
+	"red":     "#FF0000",
+	"blue":    "#0000FF",
+	"skyblue": "#87CEEB",
+	"magenta": "#FF00FF",
+	"yellow":  "#FFFF00",
+	"gold":    "#FFD700",
+	"silver":  "#C0C0C0",
+	"white":   "#FFFFFF",
+	"lime":    "#00FF00",
+	"orange":  "#FFA500",
+	"violet":  "#EE82EE",
+	"pink":    "#FFC0CB",
+}
+
+func isStatusBarFormat(format string) bool {
+	return format == "i3bar" || format == "waybar" || format == "tmux" || format == "sketchybar"
+}
+
+// setupStatusPalette resolves the foreground the TUI would use (--foreground,
+// then theme.json, then the saved theme) and the per-component colors from
+// theme.json.
+func setupStatusPalette() {
+	activeStatusPalette = newStatusPalette(cliFgColor, loadThemeFile(), currentConfig.Theme)
+}
+
+func newStatusPalette(cliColor string, theme *CustomThemeConfig, saved string) statusPalette {
+	fg := statusHex(saved)
+	if theme != nil && IsHexColor(theme.Foreground) {
+		fg = statusHex(theme.Foreground)
+	}
+	if cliColor != "" {
+		fg = statusHex(cliColor)
+	}
+	warning, critical := thresholdColors(theme)
+	p := statusPalette{
+		blocks:   map[string]string{"cpu": fg, "gpu": fg, "mem": fg, "power": fg, "temp": fg},
+		warning:  statusHex(warning),
+		critical: statusHex(critical),
+	}
+	if theme != nil {
+		for name, c := range map[string]string{"cpu": theme.CPU, "gpu": theme.GPU, "mem": theme.Memory, "power": theme.Power} {
+			if IsHexColor(c) {
+				p.blocks[name] = statusHex(c)
+			}
+		}
+	}
+	return p
+}
+
+// statusHex turns a theme name or hex color into #RRGGBB, falling back to
+// green like the TUI.
+func statusHex(name string) string {
+	if IsHexColor(name) {
+		hex := strings.TrimPrefix(name, "#")
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		return "#" + strings.ToUpper(hex)
+	}
+	if IsCatppuccinTheme(name) {
+		return strings.ToUpper(GetCatppuccinHex(name, "Primary"))
+	}
+	if hex, ok := themeHexMap[name]; ok && IsHexColor(hex) {
+		return hex
+	}
+	if hex, ok := statusNamedHex[name]; ok {
+		return hex
+	}
+	return statusNamedHex["green"]
+}
+
+func (p statusPalette) color(b statusBlock) string {
+	switch b.level {
+	case statusCritical:
+		return p.critical
+	case statusWarning:
+		return p.warning
+	}
+	return p.blocks[b.name]
+}
+
+func usageLevel(percent float64) statusLevel {
+	switch {
+	case percent >= 60:
+		return statusCritical
+	case percent >= 40:
+		return statusWarning
+	}
+	return statusNormal
+}
+
+func tempLevel(celsius float64) statusLevel {
+	switch {
+	case celsius > 90:
+		return statusCritical
+	case celsius > 70:
+		return statusWarning
+	}
+	return statusNormal
+}
+
+// statusBlocks picks what goes on the bar: CPU, GPU and memory usage, the
+// whole-machine power and the hotter of the CPU and GPU.
+func statusBlocks(output HeadlessOutput) []statusBlock {
+	var memPercent float64
+	if output.Memory.Total > 0 {
+		memPercent = float64(output.Memory.Used) / float64(output.Memory.Total) * 100
+	}
+	temp := math.Max(float64(output.SocMetrics.CPUTemp), float64(output.SocMetrics.GPUTemp))
+	return []statusBlock{
+		{"cpu", fmt.Sprintf("CPU %.0f%%", output.CPUUsage), usageLevel(output.CPUUsage)},
+		{"gpu", fmt.Sprintf("GPU %.0f%%", output.GPUUsage), usageLevel(output.GPUUsage)},
+		{"mem", fmt.Sprintf("MEM %.0f%%", memPercent), usageLevel(memPercent)},
+		{"power", fmt.Sprintf("%.1fW", output.SocMetrics.TotalPower), statusNormal},
+		{"temp", formatTemp(temp), tempLevel(temp)},
+	}
+}
+
+func worstStatusLevel(blocks []statusBlock) statusLevel {
+	worst := statusNormal
+	for _, b := range blocks {
+		if b.level > worst {
+			worst = b.level
+		}
+	}
+	return worst
+}
+
+// i3barBlock is one entry of an i3bar status line.
+type i3barBlock struct {
+	Name     string `json:"name"`
+	FullText string `json:"full_text"`
+	Color    string `json:"color"`
+}
+
+// waybarOutput is what a waybar custom module with "return-type": "json"
+// reads, one object per line.
+type waybarOutput struct {
+	Text       string `json:"text"`
+	Tooltip    string `json:"tooltip"`
+	Class      string `json:"class"`
+	Percentage int    `json:"percentage"`
+}
+
+// writeStatusBar writes one sample in the given bar format. i3bar lines are
+// elements of the array opened by printHeadlessStart.
+func writeStatusBar(w io.Writer, format string, p statusPalette, output HeadlessOutput) error {
+	blocks := statusBlocks(output)
+	var line []byte
+	var err error
+	switch format {
+	case "i3bar":
+		items := make([]i3barBlock, len(blocks))
+		for i, b := range blocks {
+			items[i] = i3barBlock{Name: "mactop_" + b.name, FullText: b.text, Color: p.color(b)}
+		}
+		line, err = json.Marshal(items)
+	case "waybar":
+		line, err = json.Marshal(waybarOutput{
+			Text:       waybarText(blocks, p),
+			Tooltip:    waybarTooltip(output),
+			Class:      statusLevelNames[worstStatusLevel(blocks)],
+			Percentage: int(math.Round(output.CPUUsage)),
+		})
+	case "tmux":
+		parts := make([]string, len(blocks))
+		for i, b := range blocks {
+			parts[i] = fmt.Sprintf("#[fg=%s]%s", p.color(b), b.text)
+		}
+		line = []byte(strings.Join(parts, " ") + "#[default]")
+	case "sketchybar":
+		line = []byte(sketchybarArgs(blocks, p))
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(line))
+	return err
+}
+
+// waybarText colors each block with Pango markup, which waybar renders in
+// the module text.
+func waybarText(blocks []statusBlock, p statusPalette) string {
+	parts := make([]string, len(blocks))
+	for i, b := range blocks {
+		parts[i] = fmt.Sprintf(`<span color="%s">%s</span>`, p.color(b), html.EscapeString(b.text))
+	}
+	return strings.Join(parts, " ")
+}
+
+// sketchybarArgs is a sketchybar command line that sets the label and label
+// color of a mactop_<block> item per block, quoted for the shell that runs
+// it. sketchybar takes colors as 0xAARRGGBB.
+func sketchybarArgs(blocks []statusBlock, p statusPalette) string {
+	parts := make([]string, len(blocks))
+	for i, b := range blocks {
+		label := "'" + strings.ReplaceAll(b.text, "'", `'\''`) + "'"
+		parts[i] = fmt.Sprintf("--set mactop_%s label=%s label.color=0xff%s", b.name, label, strings.TrimPrefix(p.color(b), "#"))
+	}
+	return strings.Join(parts, " ")
+}
+
+func waybarTooltip(output HeadlessOutput) string {
+	m := output.SocMetrics
+	lines := []string{
+		output.SystemInfo.Name,
+		fmt.Sprintf("CPU %.1f%%  GPU %.1f%%", output.CPUUsage, output.GPUUsage),
+		fmt.Sprintf("Memory %s / %s", formatBytes(float64(output.Memory.Used), "auto"), formatBytes(float64(output.Memory.Total), "auto")),
+		fmt.Sprintf("Power %.1fW (CPU %.1fW, GPU %.1fW, ANE %.1fW)", m.TotalPower, m.CPUPower, m.GPUPower+m.GPUSRAMPower, m.ANEPower),
+		fmt.Sprintf("CPU %s  GPU %s  %s", formatTemp(float64(m.CPUTemp)), formatTemp(float64(m.GPUTemp)), output.ThermalState),
+	}
+	return html.EscapeString(strings.Join(lines, "\n"))
+}
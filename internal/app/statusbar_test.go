@@ -0,0 +1,132 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func statusTestOutput() HeadlessOutput {
+	output := HeadlessOutput{CPUUsage: 72, GPUUsage: 45}
+	output.Memory.Used = 4 << 30
+	output.Memory.Total = 16 << 30
+	output.SocMetrics.TotalPower = 12.34
+	output.SocMetrics.CPUTemp = 55
+	output.SocMetrics.GPUTemp = 50
+	return output
+}
+
+func TestStatusHex(t *testing.T) {
+	cases := map[string]string{
+		"#9580ff":  "#9580FF",
+		"abc":      "#AABBCC",
+		"coral":    "#FF7F50",
+		"skyblue":  "#87CEEB",
+		"1977":     "#00FF00",
+		"mocha":    "#FAB387",
+		"":         "#00FF00",
+		"no-theme": "#00FF00",
+	}
+	for in, want := range cases {
+		if got := statusHex(in); got != want {
+			t.Errorf("statusHex(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNewStatusPalettePriority(t *testing.T) {
+	theme := &CustomThemeConfig{Foreground: "#111111", GPU: "#222222"}
+	p := newStatusPalette("", theme, "coral")
+	if p.blocks["cpu"] != "#111111" || p.blocks["gpu"] != "#222222" {
+		t.Errorf("theme.json colors not applied: %v", p.blocks)
+	}
+	p = newStatusPalette("teal", theme, "coral")
+	if p.blocks["cpu"] != "#008080" || p.blocks["gpu"] != "#222222" {
+		t.Errorf("--foreground should override the theme.json foreground only: %v", p.blocks)
+	}
+	p = newStatusPalette("", nil, "coral")
+	if p.blocks["power"] != "#FF7F50" {
+		t.Errorf("saved theme not applied: %v", p.blocks)
+	}
+}
+
+func TestWriteStatusBarI3bar(t *testing.T) {
+	p := newStatusPalette("", nil, "coral")
+	var buf bytes.Buffer
+	if err := writeStatusBar(&buf, "i3bar", p, statusTestOutput()); err != nil {
+		t.Fatal(err)
+	}
+	var blocks []i3barBlock
+	if err := json.Unmarshal(buf.Bytes(), &blocks); err != nil {
+		t.Fatalf("not a JSON array of blocks: %v\n%s", err, buf.String())
+	}
+	want := []i3barBlock{
+		{"mactop_cpu", "CPU 72%", "#FF0000"},
+		{"mactop_gpu", "GPU 45%", "#FFFF00"},
+		{"mactop_mem", "MEM 25%", "#FF7F50"},
+		{"mactop_power", "12.3W", "#FF7F50"},
+	}
+	for i, w := range want {
+		if blocks[i] != w {
+			t.Errorf("block %d = %+v, want %+v", i, blocks[i], w)
+		}
+	}
+	if len(blocks) != 5 || blocks[4].Name != "mactop_temp" {
+		t.Errorf("expected a trailing temperature block, got %+v", blocks)
+	}
+}
+
+func TestWriteStatusBarWaybar(t *testing.T) {
+	p := newStatusPalette("", nil, "coral")
+	var buf bytes.Buffer
+	if err := writeStatusBar(&buf, "waybar", p, statusTestOutput()); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(buf.String(), "\n") != 1 {
+		t.Errorf("waybar output should be one line, got %q", buf.String())
+	}
+	var out waybarOutput
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Class != "critical" || out.Percentage != 72 {
+		t.Errorf("class/percentage = %q/%d, want critical/72", out.Class, out.Percentage)
+	}
+	if !strings.HasPrefix(out.Text, `<span color="#FF0000">CPU 72%</span> <span color="#FFFF00">GPU 45%</span>`) {
+		t.Errorf("unexpected text %q", out.Text)
+	}
+}
+
+func TestWriteStatusBarTmux(t *testing.T) {
+	p := newStatusPalette("", nil, "coral")
+	output := statusTestOutput()
+	output.CPUUsage = 10
+	var buf bytes.Buffer
+	if err := writeStatusBar(&buf, "tmux", p, output); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	if !strings.HasPrefix(got, "#[fg=#FF7F50]CPU 10% #[fg=#FFFF00]GPU 45% ") || !strings.HasSuffix(got, "#[default]\n") {
+		t.Errorf("unexpected tmux line %q", got)
+	}
+}
+
+func TestWriteStatusBarSketchybar(t *testing.T) {
+	p := newStatusPalette("", nil, "coral")
+	var buf bytes.Buffer
+	if err := writeStatusBar(&buf, "sketchybar", p, statusTestOutput()); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	if !strings.HasPrefix(got, "--set mactop_cpu label='CPU 72%' label.color=0xffFF0000 --set mactop_gpu label='GPU 45%' label.color=0xffFFFF00 ") {
+		t.Errorf("unexpected sketchybar line %q", got)
+	}
+}
+
+func TestNewStatusPaletteThresholdColors(t *testing.T) {
+	p := newStatusPalette("", &CustomThemeConfig{Warning: "#FFB86C", Critical: "not-a-color"}, "coral")
+	if p.warning != "#FFB86C" || p.critical != "#FF0000" {
+		t.Errorf("warning/critical = %s/%s, want the theme warning and the default red", p.warning, p.critical)
+	}
+}
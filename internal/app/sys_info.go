@@ -1,18 +1,8 @@
 package app
 
-/*
-#include <sys/types.h>
-#include <sys/sysctl.h>
-#include <stdlib.h>
-*/
-import "C"
-
 import (
-	"fmt"
-	"strconv"
 	"strings"
 	"sync"
-	"unsafe"
 
 	"github.com/metaspartan/mactop/v2/internal/i18n"
 )
@@ -31,6 +21,9 @@ type VolumeInfo struct {
 }
 
 func getVolumes() []VolumeInfo {
+	if src, ok := activeBackend.(snapshotSource); ok {
+		return src.Volumes()
+	}
 	var volumes []VolumeInfo
 	partitions, err := GetNativePartitions(false)
 	if err != nil {
@@ -73,7 +66,7 @@ func getVolumes() []VolumeInfo {
 		seen[p.Device] = true
 		var name string
 		if p.Mountpoint == "/" {
-			name = "Mac HD"
+			name = rootVolumeName
 		} else {
 			name = strings.TrimPrefix(p.Mountpoint, "/Volumes/")
 		}
@@ -93,166 +86,11 @@ func getVolumes() []VolumeInfo {
 
 func getSOCInfo() SystemInfo {
 	socInfoOnce.Do(func() {
-		cachedSOCInfoResult = computeSOCInfo()
+		cachedSOCInfoResult = activeBackend.SystemInfo()
 	})
 	return cachedSOCInfoResult
 }
 
-func computeSOCInfo() SystemInfo {
-	cpuInfoDict := getCPUInfo()
-
-	// Use authoritative core counts from BuildCoreLabels which matches the gauge
-	// and accurately cross-references IORegistry with sysctl perflevels.
-	_, eCount, pCount, sCount, _ := BuildCoreLabels()
-
-	// Fallback: if BuildCoreLabels failed (IORegistry unavailable), use sysctl directly
-	if eCount == 0 && pCount == 0 && sCount == 0 {
-		coreTiers := getPerfLevelCores()
-		eCount = coreTiers["E"]
-		pCount = coreTiers["P"]
-		sCount = coreTiers["S"]
-	}
-
-	coreCount, _ := strconv.Atoi(cpuInfoDict["machdep.cpu.core_count"])
-	gpuCoreCountStr := getGPUCores()
-	gpuCoreCount, _ := strconv.Atoi(gpuCoreCountStr)
-	if gpuCoreCount == 0 && gpuCoreCountStr != "?" {
-	}
-
-	return SystemInfo{
-		Name:         cpuInfoDict["machdep.cpu.brand_string"],
-		CoreCount:    coreCount,
-		ECoreCount:   eCount,
-		PCoreCount:   pCount,
-		SCoreCount:   sCount,
-		GPUCoreCount: gpuCoreCount,
-	}
-}
-
-// sysctlStringByName reads a sysctl string value directly via the C API,
-// avoiding the overhead of spawning an external process.
-func sysctlStringByName(name string) (string, error) {
-	cName := C.CString(name)
-	defer C.free(unsafe.Pointer(cName))
-
-	var size C.size_t
-	if C.sysctlbyname(cName, nil, &size, nil, 0) != 0 {
-		return "", fmt.Errorf("sysctl size query failed for %s", name)
-	}
-	buf := C.malloc(size)
-	defer C.free(buf)
-	if C.sysctlbyname(cName, buf, &size, nil, 0) != 0 {
-		return "", fmt.Errorf("sysctl value query failed for %s", name)
-	}
-	return C.GoString((*C.char)(buf)), nil
-}
-
-// sysctlIntByName reads a sysctl integer value directly via the C API.
-func sysctlIntByName(name string) (int, error) {
-	cName := C.CString(name)
-	defer C.free(unsafe.Pointer(cName))
-
-	var val C.int
-	size := C.size_t(unsafe.Sizeof(val))
-	if C.sysctlbyname(cName, unsafe.Pointer(&val), &size, nil, 0) != 0 {
-		return 0, fmt.Errorf("sysctl int query failed for %s", name)
-	}
-	return int(val), nil
-}
-
-func getCPUInfo() map[string]string {
-	cpuInfoDict := make(map[string]string)
-
-	brand, err := sysctlStringByName("machdep.cpu.brand_string")
-	if err != nil {
-		stderrLogger.Fatalf("failed to get CPU brand string: %v", err)
-	}
-	cpuInfoDict["machdep.cpu.brand_string"] = brand
-
-	coreCount, err := sysctlIntByName("machdep.cpu.core_count")
-	if err != nil {
-		stderrLogger.Fatalf("failed to get CPU core count: %v", err)
-	}
-	cpuInfoDict["machdep.cpu.core_count"] = strconv.Itoa(coreCount)
-
-	return cpuInfoDict
-}
-
-// getPerfLevelCores dynamically queries sysctl hw.perflevel* to discover
-// core types and counts. Returns a map: "E" -> count, "P" -> count, "S" -> count.
-// Works across all M-series chips without hardcoding perflevel indices.
-func getPerfLevelCores() map[string]int {
-	result := map[string]int{"E": 0, "P": 0, "S": 0}
-
-	// Get number of performance levels via direct sysctl (no subprocess)
-	nperflevels, err := sysctlIntByName("hw.nperflevels")
-	if err != nil || nperflevels == 0 {
-		return getPerfLevelCoresLegacy()
-	}
-
-	// Query each perflevel for its name and core count via direct sysctl
-	for i := 0; i < nperflevels; i++ {
-		name, err := sysctlStringByName(fmt.Sprintf("hw.perflevel%d.name", i))
-		if err != nil {
-			continue
-		}
-		count, err := sysctlIntByName(fmt.Sprintf("hw.perflevel%d.logicalcpu", i))
-		if err != nil {
-			continue
-		}
-
-		// Map perflevel names to core type letters
-		switch {
-		case strings.HasPrefix(name, "Super"):
-			result["S"] += count
-		case strings.HasPrefix(name, "Performance"):
-			result["P"] += count
-		case strings.HasPrefix(name, "Efficiency"):
-			result["E"] += count
-		default:
-			// Unknown tier — treat as P-core for safety
-			result["P"] += count
-		}
-	}
-
-	return result
-}
-
-// getPerfLevelCoresLegacy is the fallback for systems without hw.nperflevels
-func getPerfLevelCoresLegacy() map[string]int {
-	result := map[string]int{"E": 0, "P": 0, "S": 0}
-
-	pVal, err := sysctlIntByName("hw.perflevel0.logicalcpu")
-	if err == nil {
-		result["P"] = pVal
-	}
-	eVal, err := sysctlIntByName("hw.perflevel1.logicalcpu")
-	if err == nil {
-		result["E"] = eVal
-	}
-	return result
-}
-
-func getGPUCores() string {
-	count := GetGPUCoreCountFast()
-	if count > 0 {
-		return strconv.Itoa(count)
-	}
-
-	data, err := GetGlobalProfilerData()
-	if err != nil {
-		stderrLogger.Printf("failed to get global profiler data: %v", err)
-		return "?"
-	}
-
-	for _, display := range data.DisplayItems {
-		if display.Cores != "" {
-			return display.Cores
-		}
-	}
-	return "?"
-}
-
 type thermalStateLevel int
 
 const (
@@ -263,31 +101,6 @@ const (
 	thermalStateCritical thermalStateLevel = 3
 )
 
-func getThermalStateLevel() thermalStateLevel {
-	name := C.CString("machdep.xcpm.cpu_thermal_level")
-	defer C.free(unsafe.Pointer(name))
-
-	var val int32
-	size := C.size_t(unsafe.Sizeof(val))
-
-	if C.sysctlbyname(name, unsafe.Pointer(&val), &size, nil, 0) != 0 {
-		return thermalStateNominal
-	}
-
-	switch val {
-	case 0:
-		return thermalStateNominal
-	case 1:
-		return thermalStateFair
-	case 2:
-		return thermalStateSerious
-	case 3:
-		return thermalStateCritical
-	default:
-		return thermalStateUnknown
-	}
-}
-
 func thermalStateString(level thermalStateLevel) string {
 	switch level {
 	case thermalStateNominal:
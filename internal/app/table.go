@@ -0,0 +1,166 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// table.go - --format table: one fixed-width vmstat-style line per sample
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// tableColumn is one column of --format table. When the terminal is too
+// narrow for every column, those with the highest priority number are
+// dropped first.
+type tableColumn struct {
+	header   string
+	width    int
+	priority int
+	value    func(o HeadlessOutput) string
+}
+
+// tableHeaderFallback is how often the header repeats when stdout isn't a
+// terminal, as vmstat does for a 24-line screen.
+const tableHeaderFallback = 22
+
+var tableColumns = []tableColumn{
+	{"TIME", 8, 0, func(o HeadlessOutput) string {
+		if t, err := time.Parse(time.RFC3339, o.Timestamp); err == nil {
+			return t.Format("15:04:05")
+		}
+		return o.Timestamp
+	}},
+	{"CPU%", 5, 0, func(o HeadlessOutput) string { return tablePercent(o.CPUUsage) }},
+	{"E%", 5, 3, func(o HeadlessOutput) string { return tablePercent(safeFloat64At(o.ECPUUsage, 1)) }},
+	{"P%", 5, 3, func(o HeadlessOutput) string { return tablePercent(safeFloat64At(o.PCPUUsage, 1)) }},
+	{"S%", 5, 3, func(o HeadlessOutput) string { return tablePercent(safeFloat64At(o.SCPUUsage, 1)) }},
+	{"GPU%", 5, 0, func(o HeadlessOutput) string { return tablePercent(o.GPUUsage) }},
+	{"GPUMHz", 6, 2, func(o HeadlessOutput) string { return fmt.Sprintf("%d", o.GPUMetrics.FreqMHz) }},
+	{"PKG_W", 6, 1, func(o HeadlessOutput) string {
+		return fmt.Sprintf("%.1f", o.SocMetrics.TotalPower-o.SocMetrics.SystemPower)
+	}},
+	{"CPU_T", 5, 1, func(o HeadlessOutput) string { return formatTemp(float64(o.SocMetrics.CPUTemp)) }},
+	{"MEM", 8, 1, func(o HeadlessOutput) string { return formatBytes(float64(o.Memory.Used), "auto") }},
+	{"SWAP", 8, 2, func(o HeadlessOutput) string { return formatBytes(float64(o.Memory.SwapUsed), "auto") }},
+	{"NET_IN", 9, 2, func(o HeadlessOutput) string { return formatBytes(o.NetDisk.InBytesPerSec, networkUnit) }},
+	{"NET_OUT", 9, 2, func(o HeadlessOutput) string { return formatBytes(o.NetDisk.OutBytesPerSec, networkUnit) }},
+	{"DISK_R", 9, 3, func(o HeadlessOutput) string { return formatBytes(o.NetDisk.ReadKBytesPerSec*1024, diskUnit) }},
+	{"DISK_W", 9, 3, func(o HeadlessOutput) string { return formatBytes(o.NetDisk.WriteKBytesPerSec*1024, diskUnit) }},
+}
+
+// tableWriter prints samples under a header that it repeats every so many
+// lines. The columns are fitted to the terminal each time the header is
+// printed, so a resized window is picked up.
+type tableWriter struct {
+	every   int // 0 to follow the terminal height
+	sCores  bool
+	size    func() (width, height int, ok bool)
+	columns []tableColumn
+	left    int // Lines until the next header
+}
+
+var activeTable *tableWriter
+
+func setupHeadlessTable() {
+	if tableHeaderEvery < 0 {
+		stderrLogger.Fatalf("invalid --header-every: %d", tableHeaderEvery)
+	}
+	activeTable = &tableWriter{
+		every:  tableHeaderEvery,
+		sCores: getSOCInfo().SCoreCount > 0,
+		size:   stdoutSize,
+	}
+	if activeOutput != nil {
+		// Written to a file: keep every column and the fixed header interval.
+		activeTable.size = func() (int, int, bool) { return 0, 0, false }
+	}
+}
+
+func stdoutSize() (int, int, bool) {
+	fd := int(os.Stdout.Fd())
+	if !term.IsTerminal(fd) {
+		return 0, 0, false
+	}
+	w, h, err := term.GetSize(fd)
+	return w, h, err == nil
+}
+
+// tableLayout keeps the most important columns that fit in width, in their
+// usual order. A width of 0 keeps them all.
+func tableLayout(width int, sCores bool) []tableColumn {
+	var candidates []tableColumn
+	for _, c := range tableColumns {
+		if c.header == "S%" && !sCores {
+			continue
+		}
+		candidates = append(candidates, c)
+	}
+	if width <= 0 {
+		return candidates
+	}
+	byPriority := slices.Clone(candidates)
+	slices.SortStableFunc(byPriority, func(a, b tableColumn) int { return a.priority - b.priority })
+	keep := make(map[string]bool)
+	used := -1 // No separator before the first column
+	for _, c := range byPriority {
+		if used+1+c.width > width {
+			break
+		}
+		used += 1 + c.width
+		keep[c.header] = true
+	}
+	var columns []tableColumn
+	for _, c := range candidates {
+		if keep[c.header] {
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+// write prints one sample, preceded by the header when it is due.
+func (t *tableWriter) write(w io.Writer, output HeadlessOutput) error {
+	if t.left <= 0 {
+		width, height, ok := t.size()
+		t.columns = tableLayout(width, t.sCores)
+		t.left = t.every
+		if t.left == 0 {
+			t.left = tableHeaderFallback
+			if ok && height > 2 {
+				t.left = height - 1
+			}
+		}
+		headers := make([]string, len(t.columns))
+		for i, c := range t.columns {
+			headers[i] = tablePad(c.header, c.width)
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(headers, " ")); err != nil {
+			return err
+		}
+	}
+	cells := make([]string, len(t.columns))
+	for i, c := range t.columns {
+		cells[i] = tablePad(c.value(output), c.width)
+	}
+	t.left--
+	_, err := fmt.Fprintln(w, strings.Join(cells, " "))
+	return err
+}
+
+func tablePercent(v float64) string {
+	return fmt.Sprintf("%.1f", v)
+}
+
+// tablePad right-aligns s in width runes; ° is two bytes, so %*s would
+// misalign temperatures.
+func tablePad(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return strings.Repeat(" ", width-n) + s
+	}
+	return s
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func tableHeaders(columns []tableColumn) []string {
+	var headers []string
+	for _, c := range columns {
+		headers = append(headers, c.header)
+	}
+	return headers
+}
+
+func TestTableLayout(t *testing.T) {
+	all := tableHeaders(tableLayout(0, true))
+	if len(all) != len(tableColumns) {
+		t.Fatalf("width 0 should keep every column, got %v", all)
+	}
+	if got := tableHeaders(tableLayout(0, false)); strings.Contains(strings.Join(got, " "), "S%") {
+		t.Errorf("S%% should be left out without S-cores: %v", got)
+	}
+
+	// TIME, CPU%, GPU% take 20 columns; the next priority adds PKG_W, CPU_T and MEM.
+	got := strings.Join(tableHeaders(tableLayout(42, false)), " ")
+	if want := "TIME CPU% GPU% PKG_W CPU_T MEM"; got != want {
+		t.Errorf("width 42: got %q, want %q", got, want)
+	}
+	if got := strings.Join(tableHeaders(tableLayout(20, false)), " "); got != "TIME CPU% GPU%" {
+		t.Errorf("width 20: got %q", got)
+	}
+}
+
+func TestTableWriterHeaderRepeat(t *testing.T) {
+	tw := &tableWriter{every: 2, size: func() (int, int, bool) { return 0, 0, false }}
+	output := HeadlessOutput{Timestamp: "2026-01-02T15:04:05Z", CPUUsage: 12.34}
+	output.SocMetrics.CPUTemp = 55.4
+	var buf bytes.Buffer
+	for range 3 {
+		if err := tw.write(&buf, output); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected header, 2 rows, header, 1 row; got %d lines:\n%s", len(lines), buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "    TIME  CPU%") || lines[3] != lines[0] {
+		t.Errorf("unexpected headers %q / %q", lines[0], lines[3])
+	}
+	if !strings.HasPrefix(lines[1], "15:04:05  12.3") {
+		t.Errorf("unexpected row %q", lines[1])
+	}
+	// Every row lines up with the header, even with the two-byte °.
+	header := []rune(lines[0])
+	row := []rune(lines[1])
+	if len(header) != len(row) {
+		t.Errorf("row is %d runes wide, header %d:\n%s\n%s", len(row), len(header), lines[0], lines[1])
+	}
+}
+
+func TestTableWriterFollowsTerminalHeight(t *testing.T) {
+	tw := &tableWriter{size: func() (int, int, bool) { return 0, 4, true }}
+	var buf bytes.Buffer
+	for range 4 {
+		tw.write(&buf, HeadlessOutput{})
+	}
+	if n := strings.Count(buf.String(), "TIME"); n != 2 {
+		t.Errorf("a 4-line terminal should get a header every 3 rows, got %d headers:\n%s", n, buf.String())
+	}
+}
+
+func TestTableUnits(t *testing.T) {
+	defer func(n, d string) { networkUnit, diskUnit = n, d }(networkUnit, diskUnit)
+	networkUnit, diskUnit = "kb", "mb"
+	output := HeadlessOutput{}
+	output.NetDisk.InBytesPerSec = 2048
+	output.NetDisk.ReadKBytesPerSec = 3072
+	tw := &tableWriter{size: func() (int, int, bool) { return 0, 0, false }}
+	var buf bytes.Buffer
+	tw.write(&buf, output)
+	row := strings.Split(buf.String(), "\n")[1]
+	if !strings.Contains(row, "2.0KB") || !strings.Contains(row, "3.0MB") {
+		t.Errorf("units not applied: %q", row)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// template.go - --format template: renders each headless sample through a Go text/template
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"text/template"
+)
+
+var activeTemplate *template.Template
+
+// templateFuncs are the helpers available to --template on top of the
+// text/template builtins. Unit helpers follow --unit-network and
+// --unit-temp like the TUI does.
+var templateFuncs = template.FuncMap{
+	"bytes": func(v any, unit ...string) string {
+		return formatBytes(templateFloat(v), templateUnit(unit, networkUnit))
+	},
+	"diskBytes": func(v any, unit ...string) string {
+		return formatBytesDecimal(templateFloat(v), templateUnit(unit, diskUnit))
+	},
+	"temp":     func(v any) string { return formatTemp(templateFloat(v)) },
+	"watts":    func(v any) string { return fmt.Sprintf("%.1fW", templateFloat(v)) },
+	"percent":  func(v any) string { return fmt.Sprintf("%.1f%%", templateFloat(v)) },
+	"energy":   func(v any) string { return formatEnergy(templateFloat(v)) },
+	"duration": func(v any) string { return formatTime(templateFloat(v)) },
+	"add":      func(a, b any) float64 { return templateFloat(a) + templateFloat(b) },
+	"sub":      func(a, b any) float64 { return templateFloat(a) - templateFloat(b) },
+	"mul":      func(a, b any) float64 { return templateFloat(a) * templateFloat(b) },
+	"div": func(a, b any) float64 {
+		if d := templateFloat(b); d != 0 {
+			return templateFloat(a) / d
+		}
+		return 0
+	},
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// setupHeadlessTemplate parses --template or --template-file before
+// sampling starts.
+func setupHeadlessTemplate() {
+	text := headlessTemplate
+	if templateFile != "" {
+		if text != "" {
+			stderrLogger.Fatalf("invalid --template-file: --template is also set")
+		}
+		data, err := os.ReadFile(templateFile)
+		if err != nil {
+			stderrLogger.Fatalf("invalid --template-file: %v", err)
+		}
+		text = string(data)
+	}
+	if text == "" {
+		stderrLogger.Fatalf("--format template needs --template or --template-file")
+	}
+	t, err := newHeadlessTemplate(text)
+	if err != nil {
+		stderrLogger.Fatalf("invalid --template: %v", err)
+	}
+	activeTemplate = t
+}
+
+func newHeadlessTemplate(text string) (*template.Template, error) {
+	return template.New("mactop").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// writeTemplateSample renders one sample, ending it with a newline when the
+// template doesn't, so every sample is one line for status bars. data is
+// the HeadlessOutput, or what --fields or --aggregate made of it.
+func writeTemplateSample(w io.Writer, t *template.Template, data any) error {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return err
+	}
+	if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func templateUnit(given []string, fallback string) string {
+	if len(given) > 0 {
+		return given[0]
+	}
+	return fallback
+}
+
+// templateFloat accepts any of the numeric types in HeadlessOutput.
+func templateFloat(v any) float64 {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	}
+	return 0
+}
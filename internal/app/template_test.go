@@ -0,0 +1,59 @@
+package app
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteTemplateSample(t *testing.T) {
+	output := HeadlessOutput{CPUUsage: 12.345, GPUUsage: 50}
+	output.SocMetrics.TotalPower = 7.25
+	output.SocMetrics.SystemPower = 2.25
+	output.Memory.Used = 8 * 1024 * 1024 * 1024
+	output.Energy.SystemJoules = 4500
+	output.Processes = []HeadlessProcess{{Command: "ollama"}}
+
+	cases := []struct {
+		name, tmpl, want string
+	}{
+		{"printf", `{{printf "%.1f" .CPUUsage}}% {{.SocMetrics.TotalPower}}W`, "12.3% 7.25W\n"},
+		{"units", `{{percent .GPUUsage}} {{watts .SocMetrics.TotalPower}} {{bytes .Memory.Used "gb"}} {{energy .Energy.SystemJoules}}`, "50.0% 7.2W 8.0GB 4.5kJ\n"},
+		{"arithmetic", `{{printf "%.2f" (sub .SocMetrics.TotalPower .SocMetrics.SystemPower)}}`, "5.00\n"},
+		{"index", `{{(index .Processes 0).Command}}`, "ollama\n"},
+		{"own newline", "{{.GPUUsage}}\n", "50\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl, err := newHeadlessTemplate(tc.tmpl)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			var buf bytes.Buffer
+			if err := writeTemplateSample(&buf, tmpl, output); err != nil {
+				t.Fatalf("execute: %v", err)
+			}
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHeadlessTemplateErrors(t *testing.T) {
+	if _, err := newHeadlessTemplate("{{.CPUUsage"); err == nil {
+		t.Error("expected a parse error for an unclosed action")
+	}
+	tmpl, err := newHeadlessTemplate("{{.NoSuchField}}")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	var buf bytes.Buffer
+	err = writeTemplateSample(&buf, tmpl, HeadlessOutput{})
+	if err == nil || !strings.Contains(err.Error(), "NoSuchField") {
+		t.Errorf("expected an error naming the missing field, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("a failed sample should write nothing, got %q", buf.String())
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// textfile.go - --prometheus-textfile: rewrites a .prom file for node_exporter's textfile collector
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// promTextfile is the file rewritten every interval. The temp file is
+// created next to it and renamed over it, so node_exporter never reads a
+// half-written file; its name ends in random digits rather than .prom, so
+// the collector ignores it in the meantime.
+type promTextfile struct {
+	path     string
+	gatherer prometheus.Gatherer
+	failing  bool
+}
+
+var activeTextfile *promTextfile
+
+// setupPrometheusTextfile checks --prometheus-textfile before sampling starts.
+func setupPrometheusTextfile() {
+	if promTextfilePath == "" {
+		return
+	}
+	t, err := newPromTextfile(promTextfilePath, promRegistry)
+	if err != nil {
+		stderrLogger.Fatalf("invalid --prometheus-textfile: %v", err)
+	}
+	activeTextfile = t
+}
+
+func newPromTextfile(path string, g prometheus.Gatherer) (*promTextfile, error) {
+	if !strings.HasSuffix(path, ".prom") {
+		return nil, fmt.Errorf("%s: the textfile collector only reads files ending in .prom", path)
+	}
+	dir := filepath.Dir(path)
+	if fi, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if !fi.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	return &promTextfile{path: path, gatherer: g}, nil
+}
+
+// writePrometheusTextfile rewrites the textfile, if any, with the registry's
+// current contents.
+func writePrometheusTextfile() {
+	t := activeTextfile
+	if t == nil {
+		return
+	}
+	logExportTransition(&t.failing, prometheus.WriteToTextfile(t.path, t.gatherer), "prometheus-textfile", t.path)
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPromTextfileRewritesAtomically(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := newPromTextfile(filepath.Join(dir, "mactop.txt"), prometheus.NewRegistry()); err == nil {
+		t.Error("newPromTextfile() without a .prom suffix: want an error")
+	}
+	if _, err := newPromTextfile(filepath.Join(dir, "missing", "mactop.prom"), prometheus.NewRegistry()); err == nil {
+		t.Error("newPromTextfile() in a missing directory: want an error")
+	}
+
+	g := prometheus.NewGauge(prometheus.GaugeOpts{Name: "mactop_cpu_usage_percent", Help: "CPU"})
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(g)
+	path := filepath.Join(dir, "mactop.prom")
+	tf, err := newPromTextfile(path, reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := activeTextfile
+	activeTextfile = tf
+	t.Cleanup(func() { activeTextfile = prev })
+
+	for _, v := range []float64{12.5, 42} {
+		g.Set(v)
+		writePrometheusTextfile()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "mactop_cpu_usage_percent 42\n") {
+		t.Errorf("textfile = %q, want the latest value", data)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only mactop.prom (temp files left behind?)", len(entries))
+	}
+	if tf.failing {
+		t.Error("failing = true after successful writes")
+	}
+}
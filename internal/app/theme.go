@@ -118,8 +118,25 @@ var (
 	SecondaryTextColor ui.Color = 245
 	IsLightMode        bool     = false
 	CurrentBgColor     ui.Color = ui.ColorClear
+	// WarningColor and CriticalColor mark values past a threshold: yellow
+	// and red unless theme.json sets warning or critical.
+	WarningColor  = "yellow"
+	CriticalColor = "red"
 )
 
+// thresholdColors returns the warning and critical colors of a theme file,
+// yellow and red for the ones it doesn't set.
+func thresholdColors(theme *CustomThemeConfig) (warning, critical string) {
+	warning, critical = "yellow", "red"
+	if theme != nil && IsHexColor(theme.Warning) {
+		warning = theme.Warning
+	}
+	if theme != nil && IsHexColor(theme.Critical) {
+		critical = theme.Critical
+	}
+	return warning, critical
+}
+
 // Catppuccin theme names
 var catppuccinThemes = []string{"frappe", "macchiato", "mocha"}
 
@@ -747,6 +764,7 @@ func applyCustomThemeFile() (bool, bool) {
 	if theme == nil {
 		return false, false
 	}
+	WarningColor, CriticalColor = thresholdColors(theme)
 
 	appliedFg := false
 	appliedBg := false
@@ -406,7 +406,12 @@ func assignUSBDevicesToBuses(output *ThunderboltOutput, infoItems []ThunderboltB
 }
 
 func GetThunderboltDescription() string {
-	formatted, err := GetFormattedThunderboltInfo()
+	return describeThunderbolt(GetFormattedThunderboltInfo())
+}
+
+// describeThunderbolt renders buses and their devices as a tree for the
+// Thunderbolt panel.
+func describeThunderbolt(formatted *ThunderboltOutput, err error) string {
 	if err != nil {
 		return "Error loading Thunderbolt info."
 	}
@@ -62,8 +62,11 @@ type GPUMetrics struct {
 }
 
 type ProcessMetrics struct {
-	PID                                      int
+	PID, PPID                                int
 	CPU, LastTime, Memory, GPU               float64 // GPU is ms/s of GPU time
+	Watts, Joules                            float64 // Estimated share of CPU and GPU power, and energy so far
+	PCoreCPU                                 float64 // Part of CPU that ran on P-cores, when CoreSplit
+	CoreSplit                                bool
 	VSZ, RSS                                 int64
 	User, TTY, State, Started, Time, Command string
 	LastUpdated                              time.Time
@@ -127,7 +130,7 @@ func NewCPUCoreWidget(modelInfo SystemInfo) *CPUCoreWidget {
 	modelName := modelInfo.Name
 
 	// Use dynamic core topology detection from IORegistry
-	labels, eCount, pCount, sCount, cpuIndexMap := BuildCoreLabels()
+	labels, eCount, pCount, sCount, cpuIndexMap := getCoreLabels()
 
 	if len(labels) == 0 {
 		// Fallback to sysctl-based counts (old behavior)
@@ -254,6 +257,7 @@ func (w *CPUCoreWidget) drawCore(buf *ui.Buffer, x, y, barWidth, index int, usag
 
 	buf.SetString("[", ui.NewStyle(BracketColor, CurrentBgColor), image.Pt(x+labelWidth, y))
 
+	warningColor, criticalColor := GetThemeColor(WarningColor), GetThemeColor(CriticalColor)
 	for bx := 0; bx < innerBarWidth; bx++ {
 		char := " "
 		var color ui.Color
@@ -261,9 +265,9 @@ func (w *CPUCoreWidget) drawCore(buf *ui.Buffer, x, y, barWidth, index int, usag
 			char = "❚"
 			switch {
 			case usage >= 60:
-				color = ui.ColorRed
+				color = criticalColor
 			case usage >= 40:
-				color = ui.ColorYellow
+				color = warningColor
 			case usage >= 30:
 				color = ui.ColorSkyBlue
 			default:
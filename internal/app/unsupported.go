@@ -0,0 +1,104 @@
+//go:build !darwin
+
+// Copyright (c) 2024-2026 Carsen Klock under MIT License
+// unsupported.go - No-op stand-ins for macOS-only features (SMC fan control,
+// IOReport dumps, CGDisplayStream FPS, IOKit profiler, menu bar and overlay)
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+var errMacOSOnly = errors.New("only available on macOS")
+
+func SetFanForceTest(enabled bool) error {
+	return errMacOSOnly
+}
+
+func SetFanMode(fanIndex, mode int) error {
+	return errMacOSOnly
+}
+
+func SetFanTarget(fanIndex, rpm int) error {
+	return errMacOSOnly
+}
+
+func ResetFansToAuto() error {
+	return errMacOSOnly
+}
+
+func DumpIOReportDebug() {
+	fmt.Println("IOReport diagnostics are", errMacOSOnly)
+}
+
+func DebugIOReport() {
+	DumpIOReportDebug()
+}
+
+func DumpAllSMCTemps() {
+	fmt.Println("SMC diagnostics are", errMacOSOnly)
+}
+
+func StartDisplayFPSCounter() bool {
+	return false
+}
+
+func StopDisplayFPSCounter() {}
+
+func GetDisplayFPSMetrics() DisplayFPSMetrics {
+	return DisplayFPSMetrics{}
+}
+
+func DumpDisplayFPSDiagnostics() {
+	fmt.Println("Display FPS diagnostics are", errMacOSOnly)
+}
+
+func GetThunderboltSwitchesIOKit() []ThunderboltSwitchInfo {
+	return nil
+}
+
+func GetUSBDevicesIOKit() []USBDeviceInfo {
+	return nil
+}
+
+func GetStorageDevicesIOKit() []StorageDeviceInfo {
+	return nil
+}
+
+// Worker state referenced by shutdownWorkers; never populated off macOS.
+var (
+	overlayMetricsEncoder *json.Encoder
+	overlayWorkerCmd      *exec.Cmd
+	overlayWorkerStdin    io.WriteCloser
+	overlayMu             sync.Mutex
+
+	menubarMetricsEncoder *json.Encoder
+	menubarWorkerCmd      *exec.Cmd
+	menubarWorkerStdin    io.WriteCloser
+	menubarMu             sync.Mutex
+)
+
+func startMenuBarProcess() error { return fmt.Errorf("menu bar %w", errMacOSOnly) }
+func startOverlayProcess() error { return fmt.Errorf("overlay %w", errMacOSOnly) }
+
+func startMenuBarWorker() {
+	fmt.Fprintln(os.Stderr, "menu bar worker is", errMacOSOnly)
+	os.Exit(1)
+}
+
+func startOverlayWorker() {
+	fmt.Fprintln(os.Stderr, "overlay worker is", errMacOSOnly)
+	os.Exit(1)
+}
+
+func pushMenuBarMetricsToWorker(sm SocMetrics, cpuMetrics CPUMetrics, gpuMetrics GPUMetrics, netDisk NetDiskMetrics, sysInfo SystemInfo, maxFP32TFLOPs float64, cpuPercent float64, thermalState string, rdmaStatus string) {
+}
+
+func pushOverlayMetrics(sm SocMetrics, cpuMetrics CPUMetrics, gpuMetrics GPUMetrics, netDisk NetDiskMetrics, sysInfo SystemInfo, maxFP32TFLOPs float64, cpuPercent float64, thermalState string, rdmaStatus string) {
+}
@@ -3,9 +3,7 @@ package app
 import (
 	"fmt"
 	"math"
-	"os"
 	"strings"
-	"syscall"
 
 	ui "github.com/metaspartan/gotui/v5"
 )
@@ -35,10 +33,6 @@ func GetCachedTerminalDimensions() (int, int) {
 	return w, h
 }
 
-func StderrToLogfile(logfile *os.File) {
-	syscall.Dup2(int(logfile.Fd()), 2)
-}
-
 func parseTimeString(timeStr string) float64 {
 	var days, hours, minutes, seconds int
 
@@ -118,6 +112,17 @@ func formatResMemorySize(kb int64) string {
 	}
 }
 
+func formatEnergy(joules float64) string {
+	switch {
+	case joules >= 1e6:
+		return fmt.Sprintf("%.1fMJ", joules/1e6)
+	case joules >= 1e3:
+		return fmt.Sprintf("%.1fkJ", joules/1e3)
+	default:
+		return fmt.Sprintf("%.0fJ", joules)
+	}
+}
+
 func truncateWithEllipsis(s string, maxLen int) string {
 	if maxLen <= 3 {
 		return "..."